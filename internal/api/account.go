@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+type STPParams struct {
+	STPMode string `json:"stp_mode"`
+}
+
+// HandleSetSTPMode sets the self-trade prevention mode the caller's orders
+// get when they do not name one.
+func (s *Server) HandleSetSTPMode(w http.ResponseWriter, r *http.Request) {
+	params := STPParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.store.SetSTPMode(r.Context(), userID, store.STPMode(params.STPMode)); err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if errors.Is(err, store.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to set stp mode", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(params); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+type BatchOrderParams struct {
+	Orders       []TradeParams `json:"orders"`
+	AllOrNothing bool          `json:"all_or_nothing"`
+}
+
+type BatchCancelParams struct {
+	OrderIDs     []string `json:"order_ids"`
+	AllOrNothing bool     `json:"all_or_nothing"`
+}
+
+// BatchResult is the outcome of one item in a batch, in request order.
+// Exactly one of Order and Error is set.
+type BatchResult struct {
+	Order *store.Order `json:"order,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// HandleCreateOrders places up to store.MaxBatchSize orders in one transaction.
+func (s *Server) HandleCreateOrders(w http.ResponseWriter, r *http.Request) {
+	params := BatchOrderParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	orders := make([]store.Order, len(params.Orders))
+	for i, p := range params.Orders {
+		orders[i] = store.Order{
+			UserID:          userID,
+			Symbol:          p.Symbol,
+			Price:           p.Price,
+			Quantity:        p.Quantity,
+			Side:            p.Side,
+			Type:            store.OrderType(p.Type),
+			StopPrice:       p.StopPrice,
+			TrailingOffset:  p.TrailingOffset,
+			TrailingPercent: p.TrailingPercent,
+			ClientOrderID:   p.ClientOrderID,
+			STPMode:         store.STPMode(p.STPMode),
+			DisplayQuantity: p.DisplayQuantity,
+		}
+	}
+
+	created, errs, err := s.store.CreateOrders(r.Context(), orders, params.AllOrNothing)
+
+	s.writeBatch(w, r, created, errs, err, "Failed to place order batch")
+}
+
+// HandleCancelOrders cancels up to store.MaxBatchSize of the caller's orders
+// in one transaction, with the same per-item results as HandleCreateOrders.
+func (s *Server) HandleCancelOrders(w http.ResponseWriter, r *http.Request) {
+	params := BatchCancelParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	cancelled, errs, err := s.store.CancelOrders(r.Context(), params.OrderIDs, userID, params.AllOrNothing)
+
+	s.writeBatch(w, r, cancelled, errs, err, "Failed to cancel order batch")
+}
+
+// writeBatch publishes what a batch changed and reports each item's result.
+func (s *Server) writeBatch(w http.ResponseWriter, r *http.Request, orders []*store.Order, errs []error, err error, failure string) {
+	status := http.StatusOK
+
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrBatchRejected):
+			status = http.StatusBadRequest
+		case errors.Is(err, store.ErrValidation):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		default:
+			slog.Error(failure, "error", err)
+			http.Error(w, "Internal System Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	results := make([]BatchResult, len(errs))
+	symbols := map[string]bool{}
+
+	for i := range results {
+		if errs[i] != nil {
+			results[i].Error = errs[i].Error()
+			continue
+		}
+
+		if status != http.StatusOK {
+			results[i].Error = store.ErrBatchRejected.Error()
+			continue
+		}
+
+		results[i].Order = orders[i]
+		s.publishDepth(orders[i])
+		s.publishOrder(orders[i])
+		symbols[orders[i].Symbol] = true
+	}
+
+	if s.matcher != nil {
+		for symbol := range symbols {
+			s.matcher.Notify(symbol)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(map[string]any{"results": results}); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
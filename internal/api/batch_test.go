@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestBatchOrderAPI(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+
+	user := createTestUser(t, storage)
+
+	send := func(handler http.HandlerFunc, method string, body any) (int, []BatchResult) {
+		b, _ := json.Marshal(body)
+		request := httptest.NewRequest(method, "/orders/batch", bytes.NewBuffer(b))
+		request = request.WithContext(context.WithValue(request.Context(), UserIDKey, user.ID))
+
+		response := httptest.NewRecorder()
+		handler(response, request)
+
+		var decoded struct {
+			Results []BatchResult `json:"results"`
+		}
+		json.NewDecoder(response.Body).Decode(&decoded)
+
+		return response.Code, decoded.Results
+	}
+
+	quotes := BatchOrderParams{Orders: []TradeParams{
+		{Symbol: "BAT-USD", Side: "BUY", Price: 99, Quantity: 1},
+		{Symbol: "BAT-USD", Side: "SELL", Price: 0, Quantity: 1},
+	}}
+
+	t.Run("Returns a result per order", func(t *testing.T) {
+		code, results := send(s.HandleCreateOrders, "POST", quotes)
+
+		if code != http.StatusOK || len(results) != 2 {
+			t.Fatalf("Expected 200 with 2 results, got %d %+v", code, results)
+		}
+		if results[0].Order == nil || results[1].Error == "" {
+			t.Errorf("Expected the first placed and the second rejected, got %+v", results)
+		}
+
+		code, results = send(s.HandleCancelOrders, "DELETE", BatchCancelParams{OrderIDs: []string{results[0].Order.ID}})
+
+		if code != http.StatusOK || results[0].Order == nil || results[0].Order.Status != "CANCELLED" {
+			t.Errorf("Expected the order cancelled, got %d %+v", code, results)
+		}
+	})
+
+	t.Run("Returns 400 when an all-or-nothing batch fails", func(t *testing.T) {
+		quotes.AllOrNothing = true
+
+		code, results := send(s.HandleCreateOrders, "POST", quotes)
+
+		if code != http.StatusBadRequest || len(results) != 2 || results[0].Order != nil || results[0].Error == "" {
+			t.Errorf("Expected 400 with nothing placed, got %d %+v", code, results)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+var fillCSVHeader = []string{
+	"trade_id", "order_id", "symbol", "side", "price", "quantity",
+	"fee", "fee_asset", "liquidity", "timestamp",
+}
+
+func (s *Server) HandleGetFills(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()
+
+	limit, err := parseLimitParam(params, 100, 1000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startTime, err := parseTimeParam(params, "start")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	endTime, err := parseTimeParam(params, "end")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := store.FillQuery{
+		UserID:    userID,
+		Symbol:    params.Get("symbol"),
+		OrderID:   params.Get("order_id"),
+		StartTime: startTime,
+		EndTime:   endTime,
+		Cursor:    params.Get("cursor"),
+		Limit:     limit,
+	}
+
+	if params.Get("format") == "csv" {
+		s.writeFillsCSV(w, r, query)
+		return
+	}
+
+	fills, nextCursor, err := s.store.GetUserFills(r.Context(), query)
+
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		slog.Error("Failed to get fills", "error", err)
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"fills":      fills,
+		"nextCursor": nextCursor,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// writeFillsCSV streams every fill matching the query, walking the cursor
+// until the history is exhausted, so a tax export is never truncated.
+func (s *Server) writeFillsCSV(w http.ResponseWriter, r *http.Request, query store.FillQuery) {
+	query.Limit = 1000
+
+	fills, nextCursor, err := s.store.GetUserFills(r.Context(), query)
+
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		slog.Error("Failed to get fills", "error", err)
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="fills.csv"`)
+
+	writer := csv.NewWriter(w)
+	writer.Write(fillCSVHeader)
+
+	for {
+		for _, f := range fills {
+			writer.Write([]string{
+				f.TradeID,
+				f.OrderID,
+				f.Symbol,
+				f.Side,
+				strconv.FormatFloat(f.Price, 'f', -1, 64),
+				strconv.Itoa(f.Quantity),
+				strconv.FormatFloat(f.Fee, 'f', -1, 64),
+				f.FeeAsset,
+				f.Liquidity,
+				f.Timestamp.UTC().Format(time.RFC3339Nano),
+			})
+		}
+
+		if nextCursor == "" {
+			break
+		}
+
+		query.Cursor = nextCursor
+
+		fills, nextCursor, err = s.store.GetUserFills(r.Context(), query)
+		if err != nil {
+			// Headers are already sent; all we can do is stop the stream short.
+			slog.Error("Failed to get fills page for CSV export", "error", err)
+			break
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		slog.Error("failed to write csv", "error", err)
+	}
+}
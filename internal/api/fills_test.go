@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestHandleGetFills(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+	ctx := context.Background()
+
+	user := createTestUser(t, storage)
+
+	var bidID, askID string
+
+	err := tx.QueryRow(ctx, `
+		INSERT INTO orders (user_id, symbol, side, price, quantity, status)
+		VALUES ($1, 'BTC-USD', 'BUY', 50000, 1, 'PENDING') RETURNING id
+	`, user.ID).Scan(&bidID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO orders (symbol, side, price, quantity, status)
+		VALUES ('BTC-USD', 'SELL', 50000, 1, 'PENDING') RETURNING id
+	`).Scan(&askID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := storage.CreateTrade(ctx, 50000, 1, bidID, askID); err != nil {
+		t.Fatal(err)
+	}
+
+	withUser := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), UserIDKey, user.ID))
+	}
+
+	t.Run("Returns 200 and the caller's fills", func(t *testing.T) {
+		req := withUser(httptest.NewRequest("GET", "/fills?symbol=BTC-USD", nil))
+		rec := httptest.NewRecorder()
+
+		s.HandleGetFills(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d. Body: %s", rec.Code, rec.Body.String())
+		}
+
+		var response struct {
+			Fills      []store.Fill `json:"fills"`
+			NextCursor string       `json:"nextCursor"`
+		}
+
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal("Failed to decode JSON", err)
+		}
+
+		if len(response.Fills) != 1 {
+			t.Fatalf("Expected 1 fill, got %d", len(response.Fills))
+		}
+		if response.Fills[0].OrderID != bidID {
+			t.Errorf("Expected fill for order %s, got %s", bidID, response.Fills[0].OrderID)
+		}
+		if response.NextCursor != "" {
+			t.Errorf("Expected no next cursor, got %q", response.NextCursor)
+		}
+	})
+
+	t.Run("Exports CSV", func(t *testing.T) {
+		req := withUser(httptest.NewRequest("GET", "/fills?format=csv", nil))
+		rec := httptest.NewRecorder()
+
+		s.HandleGetFills(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d", rec.Code)
+		}
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+			t.Errorf("Expected text/csv, got %s", ct)
+		}
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatal("Failed to parse CSV", err)
+		}
+
+		if len(records) != 2 {
+			t.Fatalf("Expected header + 1 row, got %d rows", len(records))
+		}
+		if records[1][1] != bidID {
+			t.Errorf("Expected order id %s in CSV, got %s", bidID, records[1][1])
+		}
+	})
+
+	t.Run("Returns 400 for an invalid start time", func(t *testing.T) {
+		req := withUser(httptest.NewRequest("GET", "/fills?start=yesterday", nil))
+		rec := httptest.NewRecorder()
+
+		s.HandleGetFills(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Returns 401 without a user", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/fills", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetFills(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 Unauthorized, got %d", rec.Code)
+		}
+	})
+}
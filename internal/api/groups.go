@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// OrderGroupParams places an OCO, whose side is its exits', or a bracket, whose side is its entry's.
+type OrderGroupParams struct {
+	Type            string  `json:"type"`
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	Quantity        int     `json:"quantity"`
+	EntryPrice      float64 `json:"entry_price"`
+	TakeProfitPrice float64 `json:"take_profit_price"`
+	StopPrice       float64 `json:"stop_price"`
+	// StopLimitPrice is where the stop-loss rests once triggered. It
+	// defaults to StopPrice.
+	StopLimitPrice float64 `json:"stop_limit_price"`
+	STPMode        string  `json:"stp_mode"`
+}
+
+func (s *Server) HandleCreateOrderGroup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	params := OrderGroupParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	group, err := s.store.CreateOrderGroup(r.Context(), store.OrderGroupRequest{
+		UserID:          userID,
+		Symbol:          params.Symbol,
+		Type:            store.GroupType(params.Type),
+		Side:            params.Side,
+		Quantity:        params.Quantity,
+		EntryPrice:      params.EntryPrice,
+		TakeProfitPrice: params.TakeProfitPrice,
+		StopPrice:       params.StopPrice,
+		StopLimitPrice:  params.StopLimitPrice,
+		STPMode:         store.STPMode(params.STPMode),
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		slog.Error("Failed to create order group", "error", err, "symbol", params.Symbol)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range group.Orders {
+		order := &group.Orders[i]
+
+		if order.Status == "PENDING" {
+			s.publishDepth(order)
+		}
+		s.publishOrder(order)
+	}
+
+	if s.matcher != nil {
+		s.matcher.Notify(group.Symbol)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(group); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (s *Server) HandleGetOrderGroup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	group, err := s.store.GetOrderGroup(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrOrderGroupNotFound) {
+			http.Error(w, "Order group not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to get order group", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(group); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// HandleCancelOrderGroup cancels every leg of one of the caller's groups
+// that is still live.
+func (s *Server) HandleCancelOrderGroup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	group, err := s.store.CancelOrderGroup(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrOrderGroupNotFound) || errors.Is(err, store.ErrOrderNotFound) {
+			http.Error(w, "Order group not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to cancel order group", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.publishDepthChange(group.Symbol, group.UpdateID, group.Depth)
+	s.publishGroupCancels(group, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(group); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// publishGroupCancels tells owners about the legs cancelled along with skipOrderID.
+func (s *Server) publishGroupCancels(group *store.OrderGroup, skipOrderID string) {
+	for i := range group.Orders {
+		order := &group.Orders[i]
+
+		if order.ID == skipOrderID || order.Status != "CANCELLED" {
+			continue
+		}
+
+		s.publishOrder(order)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOrderGroupAPI(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+
+	user := createTestUser(t, storage)
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDKey, user.ID)))
+		})
+	})
+	router.Post("/order-groups", s.HandleCreateOrderGroup)
+	router.Get("/order-groups/{id}", s.HandleGetOrderGroup)
+	router.Delete("/order-groups/{id}", s.HandleCancelOrderGroup)
+	router.Delete("/orders/{id}", s.HandleCancelOrder)
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewBuffer(b)))
+		return rec
+	}
+
+	params := OrderGroupParams{
+		Type: "OCO", Symbol: "BTC-USD", Side: "SELL", Quantity: 1,
+		TakeProfitPrice: 110, StopPrice: 90,
+	}
+
+	t.Run("Returns 400 for a stop above the take-profit", func(t *testing.T) {
+		bad := params
+		bad.StopPrice = 120
+
+		if rec := do("POST", "/order-groups", bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
+	var group store.OrderGroup
+
+	t.Run("Returns 201 and both legs on create", func(t *testing.T) {
+		rec := do("POST", "/order-groups", params)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected 201 Created, got %d. Body: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&group); err != nil {
+			t.Fatal(err)
+		}
+		if len(group.Orders) != 2 || group.Orders[1].Type != store.StopLimit || group.Orders[1].Status != "WAITING" {
+			t.Errorf("Expected a take-profit and a waiting stop, got %+v", group.Orders)
+		}
+	})
+
+	t.Run("Cancelling one leg cancels the other", func(t *testing.T) {
+		if rec := do("DELETE", "/orders/"+group.Orders[1].ID, nil); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d. Body: %s", rec.Code, rec.Body.String())
+		}
+
+		rec := do("GET", "/order-groups/"+group.ID, nil)
+
+		var got store.OrderGroup
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != "CANCELLED" || got.Orders[0].Status != "CANCELLED" {
+			t.Errorf("Expected the group and its take-profit to be cancelled, got %+v", got)
+		}
+	})
+
+	t.Run("Returns 404 once nothing is left to cancel", func(t *testing.T) {
+		if rec := do("DELETE", "/order-groups/"+group.ID, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+		if rec := do("GET", "/order-groups/not-a-group", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+	})
+}
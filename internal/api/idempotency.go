@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+// IdempotencyKeyHeader lets a client retry a mutating request safely: the
+// first response under a key is stored and replayed to every retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotentBody bounds the request bodies the middleware will buffer.
+// Larger ones are refused rather than hashed in part.
+const maxIdempotentBody = 1 << 20
+
+// recordingWriter passes a response through while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware replays responses by Idempotency-Key; it must run after AuthMiddleware.
+func (s *Server) IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			key = ""
+		}
+
+		userID, ok := r.Context().Value(UserIDKey).(string)
+
+		if key == "" || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.New()
+		io.WriteString(sum, r.Method+" "+r.URL.RequestURI()+"\n")
+		sum.Write(body)
+		requestHash := hex.EncodeToString(sum.Sum(nil))
+
+		stored, err := s.store.BeginIdempotentRequest(r.Context(), userID, key, requestHash)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrValidation):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, store.ErrIdempotencyKeyReused):
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			case errors.Is(err, store.ErrIdempotencyInProgress):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				slog.Error("Failed to claim idempotency key", "error", err)
+				http.Error(w, "Internal System Error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if stored != nil {
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.StatusCode)
+			w.Write(stored.Body)
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		// The outcome is saved even if the client has gone, since that is
+		// exactly when it will retry.
+		ctx := context.WithoutCancel(r.Context())
+
+		if rw.status >= http.StatusInternalServerError {
+			if err := s.store.ReleaseIdempotencyKey(ctx, userID, key); err != nil {
+				slog.Error("Failed to release idempotency key", "error", err)
+			}
+			return
+		}
+
+		resp := store.IdempotentResponse{
+			StatusCode:  rw.status,
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        rw.body.Bytes(),
+		}
+
+		if err := s.store.CompleteIdempotentRequest(ctx, userID, key, resp); err != nil {
+			slog.Error("Failed to store idempotent response", "error", err)
+		}
+	})
+}
+
+// PruneIdempotencyKeys deletes expired idempotency keys every interval until
+// ctx is done.
+func (s *Server) PruneIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.store.DeleteExpiredIdempotencyKeys(ctx)
+			if err != nil {
+				slog.Error("Failed to delete expired idempotency keys", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("Deleted expired idempotency keys", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+
+	user := createTestUser(t, storage)
+
+	calls := 0
+	status := http.StatusAccepted
+
+	handler := s.IdempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d}`, calls)
+	}))
+
+	send := func(method, key, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/trade", strings.NewReader(body))
+		request = request.WithContext(context.WithValue(request.Context(), UserIDKey, user.ID))
+		if key != "" {
+			request.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	t.Run("Replays the first response to a retry", func(t *testing.T) {
+		first := send("POST", "retry-1", `{"side":"BUY"}`)
+		second := send("POST", "retry-1", `{"side":"BUY"}`)
+
+		if calls != 1 {
+			t.Errorf("Expected the handler to run once, ran %d times", calls)
+		}
+		if second.Code != first.Code || second.Body.String() != first.Body.String() {
+			t.Errorf("Expected %d %s, got %d %s", first.Code, first.Body, second.Code, second.Body)
+		}
+		if second.Header().Get("Idempotent-Replayed") != "true" || second.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected replay headers: %v", second.Header())
+		}
+	})
+
+	t.Run("Rejects a different body under the same key", func(t *testing.T) {
+		if response := send("POST", "retry-1", `{"side":"SELL"}`); response.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected 422, got %d", response.Code)
+		}
+	})
+
+	t.Run("Does not keep server errors", func(t *testing.T) {
+		calls, status = 0, http.StatusInternalServerError
+		send("POST", "retry-2", `{}`)
+
+		status = http.StatusAccepted
+		if response := send("POST", "retry-2", `{}`); response.Code != http.StatusAccepted || calls != 2 {
+			t.Errorf("Expected the retry to run again, got %d after %d calls", response.Code, calls)
+		}
+	})
+
+	t.Run("Ignores reads and requests without a key", func(t *testing.T) {
+		calls = 0
+		send("GET", "read-1", "")
+		send("GET", "read-1", "")
+		send("POST", "", `{}`)
+		send("POST", "", `{}`)
+
+		if calls != 4 {
+			t.Errorf("Expected every request to reach the handler, got %d", calls)
+		}
+	})
+}
+
+func TestIdempotencyMiddleware_BodyTooLarge(t *testing.T) {
+	s := NewServer(nil)
+
+	handler := s.IdempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the handler not to run")
+	}))
+
+	request := httptest.NewRequest("POST", "/trade", strings.NewReader(strings.Repeat("x", maxIdempotentBody+1)))
+	request = request.WithContext(context.WithValue(request.Context(), UserIDKey, "user-1"))
+	request.Header.Set(IdempotencyKeyHeader, "big-1")
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	if response.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", response.Code)
+	}
+}
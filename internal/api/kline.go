@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -45,9 +46,44 @@ func (s *Server) HandleGetKlines(w http.ResponseWriter, r *http.Request) {
 		limit = 1000
 	}
 
-	klines, err := s.store.GetKlines(r.Context(), symbol, protectedInterval, limit)
+	startTime, err := parseTimeParam(params, "startTime")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	endTime, err := parseTimeParam(params, "endTime")
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !startTime.IsZero() && !endTime.IsZero() && !endTime.After(startTime) {
+		http.Error(w, "endTime must be after startTime", http.StatusBadRequest)
+		return
+	}
+
+	order := params.Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		http.Error(w, "order must be asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	klines, err := s.store.GetKlineRange(r.Context(), store.KlineQuery{
+		Symbol:    symbol,
+		Interval:  protectedInterval,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Limit:     limit,
+		Ascending: order == "asc",
+	})
+
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		slog.Error("failed to fetch klines", "error", err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -39,6 +39,36 @@ func TestHandleGetKlines(t *testing.T) {
 			target:     "/kline?symbol=BTC-USD&interval=2m", // 2m is not supported
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name:       "Weekly Interval",
+			target:     "/kline?symbol=BTC-USD&interval=1w&order=asc",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Time Range",
+			target:     "/kline?symbol=BTC-USD&interval=1h&startTime=1704067200000&endTime=1704153600000",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Legacy Day Interval",
+			target:     "/kline?symbol=BTC-USD&interval=1+day",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid Start Time",
+			target:     "/kline?symbol=BTC-USD&interval=1m&startTime=yesterday",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "End Before Start",
+			target:     "/kline?symbol=BTC-USD&interval=1m&startTime=1704153600000&endTime=1704067200000",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid Order",
+			target:     "/kline?symbol=BTC-USD&interval=1m&order=sideways",
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Invalid Limit (Non-numeric)",
 			target:     "/kline?symbol=BTC-USD&interval=1m&limit=abc",
@@ -52,3 +52,24 @@ func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// AdminMiddleware lets only admins through. It must run after
+// AuthMiddleware.
+func (s *Server) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value(UserIDKey).(string)
+
+		admin, err := s.store.IsAdmin(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "failed to fetch user", http.StatusInternalServerError)
+			return
+		}
+
+		if !admin {
+			http.Error(w, "Forbidden: admin only", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
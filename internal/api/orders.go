@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+	"github.com/go-chi/chi/v5"
 )
 
 type TradeParams struct {
@@ -14,8 +18,47 @@ type TradeParams struct {
 	Price    float64 `json:"price"`
 	Quantity int     `json:"quantity"`
 	Side     string  `json:"side"`
+	// Type is LIMIT (the default), STOP_LIMIT or TRAILING_STOP.
+	Type            string  `json:"type"`
+	StopPrice       float64 `json:"stop_price"`
+	TrailingOffset  float64 `json:"trailing_offset"`
+	TrailingPercent float64 `json:"trailing_percent"`
+	// ClientOrderID optionally names the order. Reusing one returns 409
+	// with the existing order, so a timed-out submission can be retried.
+	ClientOrderID string `json:"client_order_id"`
+	// STPMode overrides the account's self-trade prevention mode.
+	STPMode string `json:"stp_mode"`
+	// DisplayQuantity, if set, places an iceberg that shows only this much
+	// of Quantity on the book at a time.
+	DisplayQuantity int `json:"display_quantity"`
+	// ResponseType is ack (the default), result or full.
+	ResponseType string `json:"response_type"`
 }
 
+// Order response types: ack on acceptance, result after matching, full with fills.
+const (
+	ResponseAck    = "ack"
+	ResponseResult = "result"
+	ResponseFull   = "full"
+)
+
+// OrderResponse is an order after matching. AvgPrice is zero until the
+// order has been filled at all.
+type OrderResponse struct {
+	*store.Order
+	AvgPrice float64 `json:"avg_price"`
+}
+
+// FullOrderResponse adds the order's fills, oldest first.
+type FullOrderResponse struct {
+	OrderResponse
+	Fills []store.Fill `json:"fills"`
+}
+
+// matchWaitTimeout bounds how long a waiting order request is held. The
+// order is returned as it stands if matching takes longer.
+const matchWaitTimeout = 2 * time.Second
+
 func (s *Server) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	params := TradeParams{}
 
@@ -31,15 +74,29 @@ func (s *Server) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	switch params.ResponseType {
+	case "", ResponseAck, ResponseResult, ResponseFull:
+	default:
+		http.Error(w, "response_type must be ack, result or full", http.StatusBadRequest)
+		return
+	}
+
 	order := store.Order{
-		UserID:   userID,
-		Symbol:   params.Symbol,
-		Price:    params.Price,
-		Quantity: params.Quantity,
-		Side:     params.Side,
+		UserID:          userID,
+		Symbol:          params.Symbol,
+		Price:           params.Price,
+		Quantity:        params.Quantity,
+		Side:            params.Side,
+		Type:            store.OrderType(params.Type),
+		StopPrice:       params.StopPrice,
+		TrailingOffset:  params.TrailingOffset,
+		TrailingPercent: params.TrailingPercent,
+		ClientOrderID:   params.ClientOrderID,
+		STPMode:         store.STPMode(params.STPMode),
+		DisplayQuantity: params.DisplayQuantity,
 	}
 
-	id, err := s.store.CreateOrder(r.Context(), order)
+	created, err := s.store.CreateOrder(r.Context(), order)
 
 	if err != nil {
 		if errors.Is(err, store.ErrValidation) {
@@ -47,15 +104,269 @@ func (s *Server) CreateOrder(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, store.ErrDuplicateClientOrderID) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(created)
+			return
+		}
+
 		slog.Error("Failed to create order", "error", err, "symbol", params.Symbol)
 		http.Error(w, "Internal System Error", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"trade_id": id})
+	// A stop stays off the book until it triggers.
+	if created.Status == "PENDING" {
+		s.publishDepth(created)
+	}
+	s.publishOrder(created)
+
+	if s.matcher != nil {
+		s.matcher.Notify(created.Symbol)
+	}
+
+	if params.ResponseType == "" || params.ResponseType == ResponseAck {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"trade_id": created.ID, "order_id": created.ID})
+		return
+	}
+
+	if s.matcher != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), matchWaitTimeout)
+		err := s.matcher.Await(ctx, created.Symbol)
+		cancel()
+
+		if err != nil {
+			slog.Warn("Timed out waiting for match", "error", err, "order_id", created.ID)
+		}
+	}
+
+	response, err := s.orderResponse(r.Context(), created.ID, params.ResponseType)
+	if err != nil {
+		slog.Error("Failed to fetch order after matching", "error", err, "order_id", created.ID)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// orderResponse reads an order back with its average fill price, and its
+// fills for the full response type.
+func (s *Server) orderResponse(ctx context.Context, orderID, responseType string) (any, error) {
+	order, err := s.store.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	fills, err := s.store.GetOrderFills(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := OrderResponse{Order: order}
+
+	notional, filled := 0.0, 0
+	for _, f := range fills {
+		notional += f.Price * float64(f.Quantity)
+		filled += f.Quantity
+	}
+
+	if filled > 0 {
+		response.AvgPrice = notional / float64(filled)
+	}
+
+	if responseType == ResponseFull {
+		return FullOrderResponse{OrderResponse: response, Fills: fills}, nil
+	}
+
+	return response, nil
+}
+
+// AmendParams changes a resting order. Omitted fields are left as they are;
+// quantity is the new open quantity.
+type AmendParams struct {
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+}
+
+// HandleAmendOrder changes the price or quantity of one of the caller's resting orders.
+func (s *Server) HandleAmendOrder(w http.ResponseWriter, r *http.Request) {
+	params := AmendParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	amendment := store.OrderAmendment{Price: params.Price, Quantity: params.Quantity}
+
+	_, after, err := s.store.AmendOrder(r.Context(), chi.URLParam(r, "id"), userID, amendment)
+
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if errors.Is(err, store.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to amend order", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	if after.UpdateID != 0 {
+		s.publishDepth(after)
+		s.publishOrder(after)
+
+		if s.matcher != nil {
+			s.matcher.Notify(after.Symbol)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(after); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
 }
 
+// HandleGetOrderByClientID returns one of the caller's orders by the client
+// order ID it was placed with.
+func (s *Server) HandleGetOrderByClientID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	order, err := s.store.GetOrderByClientID(r.Context(), userID, chi.URLParam(r, "clientOrderID"))
+
+	if err != nil {
+		if errors.Is(err, store.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to get order", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (s *Server) HandleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	s.cancelOrder(w, r, chi.URLParam(r, "id"))
+}
+
+// HandleCancelOrderByClientID cancels one of the caller's orders by its
+// client order ID.
+func (s *Server) HandleCancelOrderByClientID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	order, err := s.store.GetOrderByClientID(r.Context(), userID, chi.URLParam(r, "clientOrderID"))
+
+	if err != nil {
+		if errors.Is(err, store.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to get order", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.cancelOrder(w, r, order.ID)
+}
+
+func (s *Server) cancelOrder(w http.ResponseWriter, r *http.Request, orderID string) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	cancelled, err := s.store.CancelOrder(r.Context(), orderID, userID)
+
+	if err != nil {
+		if errors.Is(err, store.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to cancel order", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.publishDepth(cancelled)
+	s.publishOrder(cancelled)
+
+	if cancelled.GroupID != "" {
+		group, err := s.store.GetOrderGroup(r.Context(), cancelled.GroupID, userID)
+		if err != nil {
+			slog.Error("Failed to get order group", "error", err, "group_id", cancelled.GroupID)
+		} else {
+			s.publishGroupCancels(group, cancelled.ID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cancelled); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// publishDepth publishes the levels an order's change touched.
+func (s *Server) publishDepth(order *store.Order) {
+	s.publishDepthChange(order.Symbol, order.UpdateID, order.Depth)
+}
+
+func (s *Server) publishDepthChange(symbol string, updateID int64, depth *store.DepthChange) {
+	if s.hub == nil || depth == nil {
+		return
+	}
+
+	s.hub.PublishDepth(stream.NewDepthUpdate(symbol, updateID, depth))
+}
+
+// publishOrder tells the owner's order stream that an order was placed or
+// cancelled.
+func (s *Server) publishOrder(order *store.Order) {
+	if s.hub == nil {
+		return
+	}
+
+	s.hub.Publish(stream.Event{Type: stream.TypeOrder, Stream: stream.Name(stream.ChannelOrders, order.UserID), Data: order})
+}
+
+// HandleGetOrderBook serves an L2 book by default, or individual orders with level=3.
 func (s *Server) HandleGetOrderBook(w http.ResponseWriter, r *http.Request) {
 	params := r.URL.Query()
 
@@ -66,9 +377,31 @@ func (s *Server) HandleGetOrderBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orderBook, err := s.store.GetOrderBook(r.Context(), symbol)
+	q, err := parseBookQuery(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Symbol = symbol
+
+	var orderBook any
+
+	switch params.Get("level") {
+	case "", "2":
+		orderBook, err = s.store.GetOrderBookDepth(r.Context(), q)
+	case "3":
+		orderBook, err = s.store.GetOrderBookL3(r.Context(), q)
+	default:
+		http.Error(w, "invalid level parameter", http.StatusBadRequest)
+		return
+	}
 
 	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		slog.Error("Failed to get order book", "error", err)
 		http.Error(w, "Server Error", http.StatusInternalServerError)
 		return
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Nevnet99/trade-engine/internal/store"
 	"github.com/Nevnet99/trade-engine/internal/testutils"
+	"github.com/go-chi/chi/v5"
 )
 
 func createTestUser(t *testing.T, storage *store.Storage) *store.User {
@@ -56,6 +57,26 @@ func TestCreateOrderAPI(t *testing.T) {
 			body:           map[string]interface{}{"symbol": "BTC", "price": 100, "quantity": 0, "side": "BUY"},
 			expectedStatus: 400,
 		},
+		{
+			name:           "Iceberg",
+			body:           map[string]interface{}{"symbol": "BTC-USD", "price": 100, "quantity": 10, "display_quantity": 2, "side": "BUY"},
+			expectedStatus: 202,
+		},
+		{
+			name:           "Logic Error (Display Quantity Not Below Quantity)",
+			body:           map[string]interface{}{"symbol": "BTC-USD", "price": 100, "quantity": 10, "display_quantity": 10, "side": "BUY"},
+			expectedStatus: 400,
+		},
+		{
+			name:           "Trailing Stop",
+			body:           map[string]interface{}{"symbol": "BTC-USD", "quantity": 1, "type": "TRAILING_STOP", "trailing_percent": 5, "side": "SELL"},
+			expectedStatus: 202,
+		},
+		{
+			name:           "Logic Error (Trailing Stop With Price)",
+			body:           map[string]interface{}{"symbol": "BTC-USD", "price": 100, "quantity": 1, "type": "TRAILING_STOP", "trailing_offset": 5, "side": "SELL"},
+			expectedStatus: 400,
+		},
 	}
 
 	for _, tt := range tests {
@@ -84,6 +105,208 @@ func TestCreateOrderAPI(t *testing.T) {
 	}
 }
 
+type fakeMatcher struct {
+	notified []string
+	awaited  []string
+}
+
+func (f *fakeMatcher) Notify(symbol string) {
+	f.notified = append(f.notified, symbol)
+}
+
+func (f *fakeMatcher) Await(ctx context.Context, symbol string) error {
+	f.awaited = append(f.awaited, symbol)
+	return nil
+}
+
+func TestCreateOrderAPI_Matcher(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	matcher := &fakeMatcher{}
+	server := NewServer(storage, WithMatcher(matcher))
+
+	user := createTestUser(t, storage)
+
+	place := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		request := httptest.NewRequest("POST", "/trade", bytes.NewBuffer(b))
+		request = request.WithContext(context.WithValue(request.Context(), UserIDKey, user.ID))
+
+		response := httptest.NewRecorder()
+		server.CreateOrder(response, request)
+		return response
+	}
+
+	t.Run("Wakes the engine without waiting", func(t *testing.T) {
+		response := place(map[string]interface{}{"symbol": "WAIT-USD", "price": 100, "quantity": 1, "side": "BUY"})
+
+		if response.Code != http.StatusAccepted {
+			t.Fatalf("Expected 202 Accepted, got %d", response.Code)
+		}
+		if len(matcher.notified) != 1 || matcher.notified[0] != "WAIT-USD" || len(matcher.awaited) != 0 {
+			t.Errorf("Expected a notification only, got %v / %v", matcher.notified, matcher.awaited)
+		}
+	})
+
+	t.Run("Waits for the match and returns the order", func(t *testing.T) {
+		response := place(map[string]interface{}{"symbol": "WAIT-USD", "price": 100, "quantity": 1, "side": "SELL", "response_type": "result"})
+
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d. Body: %s", response.Code, response.Body.String())
+		}
+		if len(matcher.awaited) != 1 {
+			t.Errorf("Expected the handler to await the engine, got %v", matcher.awaited)
+		}
+
+		var order store.Order
+		if err := json.NewDecoder(response.Body).Decode(&order); err != nil {
+			t.Fatal(err)
+		}
+		if order.ID == "" || order.Side != "SELL" || order.Status != "PENDING" {
+			t.Errorf("Expected the placed order, got %+v", order)
+		}
+	})
+}
+
+func TestCreateOrderAPI_ResponseTypes(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	server := NewServer(storage, WithMatcher(&fakeMatcher{}))
+	ctx := context.Background()
+
+	user := createTestUser(t, storage)
+
+	resting, err := storage.CreateOrder(ctx, store.Order{UserID: user.ID, Symbol: "RESP-USD", Side: "SELL", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	place := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		request := httptest.NewRequest("POST", "/trade", bytes.NewBuffer(b))
+		request = request.WithContext(context.WithValue(request.Context(), UserIDKey, user.ID))
+
+		response := httptest.NewRecorder()
+		server.CreateOrder(response, request)
+		return response
+	}
+
+	t.Run("Rejects an unknown response type", func(t *testing.T) {
+		response := place(map[string]interface{}{"symbol": "RESP-USD", "price": 100, "quantity": 1, "side": "BUY", "response_type": "verbose"})
+
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", response.Code)
+		}
+	})
+
+	t.Run("ack returns the order ID", func(t *testing.T) {
+		response := place(map[string]interface{}{"symbol": "ACK-USD", "price": 100, "quantity": 1, "side": "BUY", "response_type": "ack"})
+
+		var body map[string]string
+		json.NewDecoder(response.Body).Decode(&body)
+
+		if response.Code != http.StatusAccepted || body["order_id"] == "" {
+			t.Errorf("Expected 202 with an order_id, got %d %v", response.Code, body)
+		}
+	})
+
+	t.Run("full returns the fills and average price", func(t *testing.T) {
+		// Stand in for the engine: the order is placed, then matched
+		// against the resting sell before the handler reads it back.
+		server.matcher = matchFunc(func(ctx context.Context, symbol string) {
+			bid, _ := storage.GetBestBuyOrder(ctx, symbol)
+			if bid == nil {
+				t.Fatal("Expected the new order on the book")
+			}
+			if _, err := storage.CreateTrade(ctx, 100, 1, bid.ID, resting.ID); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		response := place(map[string]interface{}{"symbol": "RESP-USD", "price": 100, "quantity": 2, "side": "BUY", "response_type": "full"})
+
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d. Body: %s", response.Code, response.Body.String())
+		}
+
+		var body FullOrderResponse
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.FilledQuantity != 1 || body.Quantity != 1 || body.AvgPrice != 100 {
+			t.Errorf("Expected a half-filled order at 100, got %+v", body.OrderResponse.Order)
+		}
+		if len(body.Fills) != 1 || body.Fills[0].OrderID != body.ID {
+			t.Errorf("Expected one fill for the order, got %+v", body.Fills)
+		}
+	})
+}
+
+// matchFunc is a Matcher that runs fn when awaited.
+type matchFunc func(ctx context.Context, symbol string)
+
+func (f matchFunc) Notify(symbol string) {}
+
+func (f matchFunc) Await(ctx context.Context, symbol string) error {
+	f(ctx, symbol)
+	return nil
+}
+
+func TestHandleCancelOrder(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+	ctx := context.Background()
+
+	user := createTestUser(t, storage)
+
+	order, err := storage.CreateOrder(ctx, store.Order{UserID: user.ID, Symbol: "BTC-USD", Side: "BUY", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.Delete("/orders/{id}", s.HandleCancelOrder)
+
+	cancel := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/orders/"+id, nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, user.ID))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Returns 200 and the cancelled order", func(t *testing.T) {
+		rec := cancel(order.ID)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d. Body: %s", rec.Code, rec.Body.String())
+		}
+
+		var cancelled store.Order
+		if err := json.NewDecoder(rec.Body).Decode(&cancelled); err != nil {
+			t.Fatal(err)
+		}
+
+		if cancelled.ID != order.ID || cancelled.Status != "CANCELLED" {
+			t.Errorf("Unexpected response: %+v", cancelled)
+		}
+	})
+
+	t.Run("Returns 404 once the order is no longer resting", func(t *testing.T) {
+		if rec := cancel(order.ID); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Returns 404 for a malformed ID", func(t *testing.T) {
+		if rec := cancel("not-an-id"); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+	})
+}
+
 func TestHandleGetOrderBook(t *testing.T) {
 
 	tx := testutils.SetupTestDB(t)
@@ -156,3 +379,118 @@ func TestHandleGetOrderBook(t *testing.T) {
 		}
 	})
 }
+
+func TestClientOrderIDAPI(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+
+	user := createTestUser(t, storage)
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDKey, user.ID)))
+		})
+	})
+	router.Post("/trade", s.CreateOrder)
+	router.Get("/orders/client/{clientOrderID}", s.HandleGetOrderByClientID)
+	router.Delete("/orders/client/{clientOrderID}", s.HandleCancelOrderByClientID)
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewBuffer(b)))
+		return rec
+	}
+
+	params := map[string]interface{}{"symbol": "CID-USD", "price": 100, "quantity": 1, "side": "BUY", "client_order_id": "bot-42"}
+
+	first := do("POST", "/trade", params)
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202 Accepted, got %d. Body: %s", first.Code, first.Body.String())
+	}
+
+	var ack map[string]string
+	json.NewDecoder(first.Body).Decode(&ack)
+
+	t.Run("Returns 409 and the existing order on reuse", func(t *testing.T) {
+		rec := do("POST", "/trade", params)
+
+		var existing store.Order
+		json.NewDecoder(rec.Body).Decode(&existing)
+
+		if rec.Code != http.StatusConflict || existing.ID != ack["order_id"] {
+			t.Errorf("Expected 409 with order %s, got %d %+v", ack["order_id"], rec.Code, existing)
+		}
+	})
+
+	t.Run("Finds and cancels by client order ID", func(t *testing.T) {
+		if rec := do("GET", "/orders/client/bot-42", nil); rec.Code != http.StatusOK {
+			t.Errorf("Expected 200 OK, got %d", rec.Code)
+		}
+
+		rec := do("DELETE", "/orders/client/bot-42", nil)
+
+		var cancelled store.Order
+		json.NewDecoder(rec.Body).Decode(&cancelled)
+
+		if rec.Code != http.StatusOK || cancelled.Status != "CANCELLED" || cancelled.ClientOrderID != "bot-42" {
+			t.Errorf("Expected the order cancelled, got %d %+v", rec.Code, cancelled)
+		}
+
+		if rec := do("GET", "/orders/client/bot-43", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandleAmendOrder(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+	ctx := context.Background()
+
+	user := createTestUser(t, storage)
+
+	order, err := storage.CreateOrder(ctx, store.Order{UserID: user.ID, Symbol: "AMD-USD", Side: "SELL", Price: 100, Quantity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.Patch("/orders/{id}", s.HandleAmendOrder)
+
+	amend := func(id string, body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		request := httptest.NewRequest("PATCH", "/orders/"+id, bytes.NewBuffer(b))
+		request = request.WithContext(context.WithValue(request.Context(), UserIDKey, user.ID))
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	t.Run("Returns the amended order", func(t *testing.T) {
+		response := amend(order.ID, AmendParams{Price: 101, Quantity: 2})
+
+		var amended store.Order
+		json.NewDecoder(response.Body).Decode(&amended)
+
+		if response.Code != http.StatusOK || amended.Price != 101 || amended.Quantity != 2 {
+			t.Errorf("Expected 200 with 2 @ 101, got %d %+v", response.Code, amended)
+		}
+	})
+
+	t.Run("Returns 400 for an empty amendment", func(t *testing.T) {
+		if response := amend(order.ID, AmendParams{}); response.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", response.Code)
+		}
+	})
+
+	t.Run("Returns 404 for an unknown order", func(t *testing.T) {
+		if response := amend("not-an-id", AmendParams{Quantity: 1}); response.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", response.Code)
+		}
+	})
+}
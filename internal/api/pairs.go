@@ -2,7 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/go-chi/chi/v5"
 )
 
 func (s *Server) HandleGetPairs(w http.ResponseWriter, r *http.Request) {
@@ -18,3 +24,111 @@ func (s *Server) HandleGetPairs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// PairProtectionParams configures a pair's price band and circuit breaker.
+// Omitted or zero fields turn a protection off.
+type PairProtectionParams struct {
+	PriceBandPercent            float64 `json:"price_band_percent"`
+	ReferencePrice              float64 `json:"reference_price"`
+	CircuitBreakerPercent       float64 `json:"circuit_breaker_percent"`
+	CircuitBreakerWindowSeconds int     `json:"circuit_breaker_window_seconds"`
+	CircuitBreakerHaltSeconds   int     `json:"circuit_breaker_halt_seconds"`
+}
+
+// HandleSetPairProtection replaces the price band and circuit breaker of the
+// pair in the path, and returns the pair. It is for admins only.
+func (s *Server) HandleSetPairProtection(w http.ResponseWriter, r *http.Request) {
+	params := PairProtectionParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := s.store.SetPairProtection(r.Context(), chi.URLParam(r, "symbol"), store.PairProtection{
+		PriceBandPercent:            params.PriceBandPercent,
+		ReferencePrice:              params.ReferencePrice,
+		CircuitBreakerPercent:       params.CircuitBreakerPercent,
+		CircuitBreakerWindowSeconds: params.CircuitBreakerWindowSeconds,
+		CircuitBreakerHaltSeconds:   params.CircuitBreakerHaltSeconds,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if errors.Is(err, store.ErrPairNotFound) {
+			http.Error(w, "Trading pair not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to set pair protection", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pair); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// AuctionParams schedules an auction. A DurationSeconds of zero leaves it
+// running until it is ended.
+type AuctionParams struct {
+	DurationSeconds int `json:"duration_seconds"`
+}
+
+// HandleStartAuction puts the pair in the path into a call auction, or moves
+// the end of the one it is in, and returns the pair. It is for admins only.
+func (s *Server) HandleStartAuction(w http.ResponseWriter, r *http.Request) {
+	params := AuctionParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := s.store.StartAuction(r.Context(), chi.URLParam(r, "symbol"), time.Duration(params.DurationSeconds)*time.Second)
+	s.writeAuction(w, r, pair, err)
+}
+
+// HandleEndAuction ends the auction of the pair in the path, so it uncrosses
+// straight away, and returns the pair. It is for admins only.
+func (s *Server) HandleEndAuction(w http.ResponseWriter, r *http.Request) {
+	pair, err := s.store.EndAuction(r.Context(), chi.URLParam(r, "symbol"))
+	if err == nil && s.matcher != nil {
+		s.matcher.Notify(pair.Symbol)
+	}
+
+	s.writeAuction(w, r, pair, err)
+}
+
+func (s *Server) writeAuction(w http.ResponseWriter, r *http.Request, pair *store.TradingPair, err error) {
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if errors.Is(err, store.ErrPairNotFound) {
+			http.Error(w, "Trading pair not found", http.StatusNotFound)
+			return
+		}
+
+		if errors.Is(err, store.ErrTradingPhase) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		slog.Error("Failed to change trading phase", "error", err, "symbol", chi.URLParam(r, "symbol"))
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pair); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
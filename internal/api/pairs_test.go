@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/Nevnet99/trade-engine/internal/store"
 	"github.com/Nevnet99/trade-engine/internal/testutils"
+	"github.com/go-chi/chi/v5"
 )
 
 func TestHandleGetPairs(t *testing.T) {
@@ -54,3 +56,77 @@ func TestHandleGetPairs(t *testing.T) {
 		t.Errorf("Expected symbol SOL-USD, got %s", pairs[0].Symbol)
 	}
 }
+
+func TestHandleSetPairProtection(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+	ctx := context.Background()
+
+	user := createTestUser(t, storage)
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDKey, user.ID)))
+		})
+	})
+	router.Use(s.AdminMiddleware)
+	router.Put("/admin/pairs/{symbol}/protection", s.HandleSetPairProtection)
+
+	put := func(symbol string, params PairProtectionParams) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(params)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("PUT", "/admin/pairs/"+symbol+"/protection", bytes.NewBuffer(b)))
+		return rec
+	}
+
+	params := PairProtectionParams{
+		PriceBandPercent: 10, CircuitBreakerPercent: 5, CircuitBreakerWindowSeconds: 60, CircuitBreakerHaltSeconds: 300,
+	}
+
+	t.Run("Returns 403 for a non-admin", func(t *testing.T) {
+		if rec := put("BTC-USD", params); rec.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 Forbidden, got %d", rec.Code)
+		}
+	})
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET is_admin = true WHERE id = $1`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Returns 400 for a breaker without a halt", func(t *testing.T) {
+		bad := params
+		bad.CircuitBreakerHaltSeconds = 0
+
+		if rec := put("BTC-USD", bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Returns 404 for an unknown pair", func(t *testing.T) {
+		if rec := put("NOPE-USD", params); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Shows the protection in GET /pairs", func(t *testing.T) {
+		if rec := put("BTC-USD", params); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d. Body: %s", rec.Code, rec.Body.String())
+		}
+
+		rec := httptest.NewRecorder()
+		s.HandleGetPairs(rec, httptest.NewRequest("GET", "/pairs", nil))
+
+		var pairs []store.TradingPair
+		if err := json.NewDecoder(rec.Body).Decode(&pairs); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, pair := range pairs {
+			if pair.Symbol == "BTC-USD" && (pair.PriceBandPercent != 10 || pair.CircuitBreakerHaltSeconds != 300) {
+				t.Errorf("Expected BTC-USD's protection, got %+v", pair)
+			}
+		}
+	})
+}
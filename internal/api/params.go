@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+// parseTimeParam accepts either Unix milliseconds or an RFC 3339 timestamp.
+// A missing parameter returns the zero time.
+func parseTimeParam(params url.Values, name string) (time.Time, error) {
+	if !params.Has(name) {
+		return time.Time{}, nil
+	}
+
+	value := params.Get(name)
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s parameter", name)
+	}
+
+	return t.UTC(), nil
+}
+
+// parseLimitParam reads a positive page size, falling back to def when the
+// parameter is absent and capping it at max.
+func parseLimitParam(params url.Values, def int, max int) (int, error) {
+	if !params.Has("limit") {
+		return def, nil
+	}
+
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid limit parameter")
+	}
+
+	if limit > max {
+		limit = max
+	}
+
+	return limit, nil
+}
+
+// parseBookQuery reads the depth and grouping of an order book request. The
+// store decides which depths are allowed.
+func parseBookQuery(params url.Values) (store.BookQuery, error) {
+	q := store.BookQuery{Depth: store.DefaultBookDepth}
+
+	switch depth := params.Get("depth"); depth {
+	case "":
+	case "full":
+		q.Depth = store.FullBookDepth
+	default:
+		n, err := strconv.Atoi(depth)
+		if err != nil || n <= 0 {
+			return q, fmt.Errorf("invalid depth parameter")
+		}
+		q.Depth = n
+	}
+
+	if params.Has("grouping") {
+		grouping, err := strconv.ParseFloat(params.Get("grouping"), 64)
+		if err != nil || grouping <= 0 {
+			return q, fmt.Errorf("invalid grouping parameter")
+		}
+		q.Grouping = grouping
+	}
+
+	return q, nil
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseTimeParam(t *testing.T) {
+	want := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "Missing", query: "", want: time.Time{}},
+		{name: "Unix Milliseconds", query: "start=1704103200000", want: want},
+		{name: "RFC3339", query: "start=2024-01-01T10:00:00Z", want: want},
+		{name: "RFC3339 With Offset", query: "start=2024-01-01T11:00:00%2B01:00", want: want},
+		{name: "Garbage", query: "start=yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _ := url.ParseQuery(tt.query)
+
+			got, err := parseTimeParam(params, "start")
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimitParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{name: "Default", query: "", want: 100},
+		{name: "Explicit", query: "limit=10", want: 10},
+		{name: "Capped", query: "limit=5000", want: 1000},
+		{name: "Zero", query: "limit=0", wantErr: true},
+		{name: "Non-numeric", query: "limit=abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _ := url.ParseQuery(tt.query)
+
+			got, err := parseLimitParam(params, 100, 1000)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBookQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantDepth    int
+		wantGrouping float64
+		wantErr      bool
+	}{
+		{name: "Default", query: "", wantDepth: 20},
+		{name: "Explicit Depth", query: "depth=50", wantDepth: 50},
+		{name: "Full Depth", query: "depth=full", wantDepth: 0},
+		{name: "Grouping", query: "grouping=10", wantDepth: 20, wantGrouping: 10},
+		{name: "Fractional Grouping", query: "grouping=0.5", wantDepth: 20, wantGrouping: 0.5},
+		{name: "Non-numeric Depth", query: "depth=lots", wantErr: true},
+		{name: "Zero Grouping", query: "grouping=0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _ := url.ParseQuery(tt.query)
+
+			got, err := parseBookQuery(params)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if got.Depth != tt.wantDepth || got.Grouping != tt.wantGrouping {
+				t.Errorf("got %+v, want depth %d grouping %v", got, tt.wantDepth, tt.wantGrouping)
+			}
+		})
+	}
+}
@@ -1,13 +1,63 @@
 package api
 
-import "github.com/Nevnet99/trade-engine/internal/store"
+import (
+	"context"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+)
 
 type Server struct {
-	store *store.Storage
+	store   *store.Storage
+	tickers *tickerCache
+	hub     *stream.Hub
+	matcher Matcher
+	origins []string
+}
+
+// Matcher is an in-process matching engine that order intake can wake.
+type Matcher interface {
+	// Notify asks for symbol to be matched soon.
+	Notify(symbol string)
+	// Await returns once everything committed before the call has been
+	// matched.
+	Await(ctx context.Context, symbol string) error
+}
+
+type Option func(*Server)
+
+// WithHub enables the WebSocket endpoint and lets handlers publish market
+// data events.
+func WithHub(h *stream.Hub) Option {
+	return func(s *Server) {
+		s.hub = h
+	}
+}
+
+// WithMatcher wakes an in-process engine as soon as an order is placed, and
+// lets clients wait for the match.
+func WithMatcher(m Matcher) Option {
+	return func(s *Server) {
+		s.matcher = m
+	}
+}
+
+// WithAllowedOrigins lets browser pages on other origins open the WebSocket endpoint.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(s *Server) {
+		s.origins = origins
+	}
 }
 
-func NewServer(store *store.Storage) *Server {
-	return &Server{
-		store: store,
+func NewServer(store *store.Storage, opts ...Option) *Server {
+	s := &Server{
+		store:   store,
+		tickers: newTickerCache(tickerCacheTTL),
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+)
+
+const (
+	sseHeartbeat   = 15 * time.Second
+	sseReplayLimit = 1000
+)
+
+// sseWriter frames events per the EventSource spec. Events without an ID
+// leave the browser's Last-Event-ID untouched.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseWriter{w: w, flusher: flusher}, true
+}
+
+func (s *sseWriter) send(e stream.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+
+	if e.ID != "" {
+		if _, err := fmt.Fprintf(s.w, "id: %s\n", e.ID); err != nil {
+			return err
+		}
+	}
+	if e.Type != "" {
+		if _, err := fmt.Fprintf(s.w, "event: %s\n", e.Type); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseWriter) heartbeat() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+// serveSSE subscribes before replaying so nothing published during the
+// replay is lost, then skips live events the replay already delivered.
+func (s *Server) serveSSE(w http.ResponseWriter, r *http.Request, name string, replay func() ([]stream.Event, error)) {
+	if s.hub == nil {
+		http.Error(w, "Streaming is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	sub := s.hub.Subscribe()
+	defer s.hub.Remove(sub)
+	sub.Subscribe(name)
+
+	missed, err := replay()
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+
+		slog.Error("Failed to replay stream", "error", err, "stream", name)
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	out, ok := newSSEWriter(w)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	replayed := map[string]bool{}
+
+	for _, e := range missed {
+		if e.ID != "" {
+			replayed[e.ID] = true
+		}
+		if err := out.send(e); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	skipping := false
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-sub.Done():
+			return
+
+		case e := <-sub.Events():
+			// An event without an ID belongs to the identified event before it,
+			// so it is skipped along with a replayed one.
+			if e.ID != "" {
+				skipping = replayed[e.ID]
+			}
+			if skipping {
+				continue
+			}
+
+			if err := out.send(e); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if err := out.heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) HandleStreamTrades(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+
+	if symbol == "" {
+		http.Error(w, "No query parameter set", http.StatusBadRequest)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+
+	s.serveSSE(w, r, stream.Name(stream.ChannelTrades, symbol), func() ([]stream.Event, error) {
+		if lastEventID == "" {
+			return nil, nil
+		}
+
+		trades, err := s.store.GetTrades(r.Context(), store.TradeQuery{
+			Symbol: symbol,
+			FromID: lastEventID,
+			Limit:  sseReplayLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		events := []stream.Event{}
+		for i := range trades {
+			// FromID is inclusive and the client already has that trade.
+			if trades[i].ID == lastEventID {
+				continue
+			}
+			events = append(events, stream.Event{
+				ID:     trades[i].ID,
+				Type:   stream.TypeTrade,
+				Stream: stream.Name(stream.ChannelTrades, symbol),
+				Data:   &trades[i],
+			})
+		}
+
+		return events, nil
+	})
+}
+
+// HandleStreamOrders streams the user's order event log, resuming from Last-Event-ID.
+func (s *Server) HandleStreamOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	if s.hub == nil {
+		http.Error(w, "Streaming is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := stream.Name(stream.ChannelOrders, userID)
+
+	sub := s.hub.Subscribe()
+	defer s.hub.Remove(sub)
+	sub.Subscribe(name)
+
+	var after int64
+	var err error
+
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		after, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || after < 0 {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	} else {
+		after, err = s.store.GetLastOrderEventSeq(r.Context(), userID)
+		if err != nil {
+			slog.Error("Failed to fetch order event seq", "error", err, "stream", name)
+			http.Error(w, "Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	missed, err := s.store.GetOrderEvents(r.Context(), store.OrderEventQuery{UserID: userID, AfterSeq: after, Limit: sseReplayLimit})
+	if err != nil {
+		slog.Error("Failed to replay stream", "error", err, "stream", name)
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	out, ok := newSSEWriter(w)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// send writes events and reads on until the log is exhausted.
+	send := func(events []store.OrderEvent) error {
+		for {
+			for _, e := range events {
+				if err := out.send(stream.Event{ID: strconv.FormatInt(e.Seq, 10), Type: e.Type, Stream: name, Data: e.Payload}); err != nil {
+					return err
+				}
+				after = e.Seq
+			}
+
+			if len(events) < sseReplayLimit {
+				return nil
+			}
+
+			var err error
+			events, err = s.store.GetOrderEvents(r.Context(), store.OrderEventQuery{UserID: userID, AfterSeq: after, Limit: sseReplayLimit})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	follow := func() error {
+		events, err := s.store.GetOrderEvents(r.Context(), store.OrderEventQuery{UserID: userID, AfterSeq: after, Limit: sseReplayLimit})
+		if err != nil {
+			slog.Error("Failed to follow stream", "error", err, "stream", name)
+			return err
+		}
+		return send(events)
+	}
+
+	if err := send(missed); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-sub.Done():
+			return
+
+		case <-sub.Events():
+			if err := follow(); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			// Changes the API and engine do not publish, such as a group leg
+			// cancelled by a fill, still arrive within a heartbeat.
+			if err := follow(); err != nil {
+				return
+			}
+			if err := out.heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
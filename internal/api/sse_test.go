@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+// readSSEEvent reads lines up to the next blank line, skipping heartbeats.
+func readSSEEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, ": ")
+		fields[key] = value
+	}
+}
+
+func TestHandleStreamTrades(t *testing.T) {
+	t.Run("Returns 400 if symbol is missing", func(t *testing.T) {
+		s := NewServer(nil, WithHub(stream.NewHub(16)))
+		rec := httptest.NewRecorder()
+
+		s.HandleStreamTrades(rec, httptest.NewRequest("GET", "/stream/trades", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Returns 503 if streaming is disabled", func(t *testing.T) {
+		s := NewServer(nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleStreamTrades(rec, httptest.NewRequest("GET", "/stream/trades?symbol=BTC-USD", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 Service Unavailable, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Streams live trades for the symbol", func(t *testing.T) {
+		hub := stream.NewHub(16)
+		s := NewServer(nil, WithHub(hub))
+
+		ts := httptest.NewServer(http.HandlerFunc(s.HandleStreamTrades))
+		defer ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"?symbol=BTC-USD", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected text/event-stream, got %s", ct)
+		}
+
+		hub.Publish(stream.Event{ID: "t-1", Type: stream.TypeTrade, Stream: "trades:ETH-USD", Data: "ignored"})
+		hub.Publish(stream.Event{ID: "t-2", Type: stream.TypeTrade, Stream: "trades:BTC-USD", Data: "hello"})
+
+		event := readSSEEvent(t, bufio.NewReader(resp.Body))
+
+		if event["id"] != "t-2" || event["event"] != "trade" || event["data"] != `"hello"` {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	})
+}
+
+func TestHandleStreamTrades_Resume(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	user := createTestUser(t, storage)
+
+	var tradeIDs []string
+
+	for i := range 2 {
+		var bidID, askID string
+
+		err := tx.QueryRow(ctx, `
+			INSERT INTO orders (user_id, symbol, side, price, quantity, status)
+			VALUES ($1, 'SSE-USD', 'BUY', 100, 1, 'PENDING') RETURNING id
+		`, user.ID).Scan(&bidID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO orders (user_id, symbol, side, price, quantity, status)
+			VALUES ($1, 'SSE-USD', 'SELL', 100, 1, 'PENDING') RETURNING id
+		`, user.ID).Scan(&askID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trade, err := storage.CreateTrade(ctx, 100, 1, bidID, askID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE trades SET timestamp = $1 WHERE id = $2`,
+			time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC), trade.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tradeIDs = append(tradeIDs, trade.ID)
+	}
+
+	hub := stream.NewHub(16)
+	s := NewServer(storage, WithHub(hub))
+
+	ts := httptest.NewServer(http.HandlerFunc(s.HandleStreamTrades))
+	defer ts.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(reqCtx, "GET", ts.URL+"?symbol=SSE-USD", nil)
+	req.Header.Set("Last-Event-ID", tradeIDs[0])
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	event := readSSEEvent(t, bufio.NewReader(resp.Body))
+
+	if event["id"] != tradeIDs[1] {
+		t.Errorf("Expected replay to resume after %s with %s, got %+v", tradeIDs[0], tradeIDs[1], event)
+	}
+}
+
+func TestHandleStreamOrders_Resume(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	user := createTestUser(t, storage)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO trading_pairs (symbol, base_asset, quote_asset) VALUES ('SSO-USD', 'SSO', 'USD')`); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := storage.CreateOrder(ctx, store.Order{UserID: user.ID, Symbol: "SSO-USD", Side: "BUY", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := storage.CancelOrder(ctx, order.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := stream.NewHub(16)
+	s := NewServer(storage, WithHub(hub))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.HandleStreamOrders(w, r.WithContext(context.WithValue(r.Context(), UserIDKey, user.ID)))
+	}))
+	defer ts.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(reqCtx, "GET", ts.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body := bufio.NewReader(resp.Body)
+
+	event := readSSEEvent(t, body)
+	if event["id"] != "2" || event["event"] != stream.TypeOrder || !strings.Contains(event["data"], `"CANCELLED"`) {
+		t.Errorf("Expected replay to resume with the cancel, got %+v", event)
+	}
+
+	// Live events come from the log too, numbered the same way.
+	if _, err := storage.CreateOrder(ctx, store.Order{UserID: user.ID, Symbol: "SSO-USD", Side: "BUY", Price: 99, Quantity: 1}); err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish(stream.Event{Type: stream.TypeOrder, Stream: stream.Name(stream.ChannelOrders, user.ID), Data: "ignored"})
+
+	event = readSSEEvent(t, body)
+	if event["id"] != "3" || event["event"] != stream.TypeOrder || !strings.Contains(event["data"], `"PENDING"`) {
+		t.Errorf("Expected the new order as event 3, got %+v", event)
+	}
+}
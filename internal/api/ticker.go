@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"golang.org/x/sync/singleflight"
+)
+
+const tickerCacheTTL = 2 * time.Second
+
+type cachedTicker struct {
+	ticker    *store.Ticker
+	expiresAt time.Time
+}
+
+// tickerCache keeps each listed pair's ticker for a short TTL.
+type tickerCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedTicker
+	group   singleflight.Group
+}
+
+func newTickerCache(ttl time.Duration) *tickerCache {
+	return &tickerCache{
+		ttl:     ttl,
+		entries: map[string]cachedTicker{},
+	}
+}
+
+func (c *tickerCache) get(ctx context.Context, storage *store.Storage, symbol string) (*store.Ticker, error) {
+	if ticker, ok := c.cached(symbol, time.Now()); ok {
+		return ticker, nil
+	}
+
+	// The computation is shared, so one caller going away must not fail it
+	// for the others.
+	ctx = context.WithoutCancel(ctx)
+
+	ticker, err, _ := c.group.Do(symbol, func() (any, error) {
+		now := time.Now()
+
+		if ticker, ok := c.cached(symbol, now); ok {
+			return ticker, nil
+		}
+
+		if _, err := storage.GetTradingPair(ctx, symbol); err != nil {
+			return nil, err
+		}
+
+		ticker, err := storage.GetTicker(ctx, symbol, now)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		for key, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		c.entries[symbol] = cachedTicker{ticker: ticker, expiresAt: now.Add(c.ttl)}
+		c.mu.Unlock()
+
+		return ticker, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ticker.(*store.Ticker), nil
+}
+
+func (c *tickerCache) cached(symbol string, now time.Time) (*store.Ticker, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[symbol]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.ticker, true
+}
+
+func (s *Server) HandleGetTicker(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	if params.Has("symbol") {
+		symbol := params.Get("symbol")
+
+		if symbol == "" {
+			http.Error(w, "No query parameter set", http.StatusBadRequest)
+			return
+		}
+
+		ticker, err := s.tickers.get(r.Context(), s.store, symbol)
+		if errors.Is(err, store.ErrPairNotFound) {
+			http.Error(w, "Trading pair not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to get ticker", "error", err, "symbol", symbol)
+			http.Error(w, "Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ticker); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+		return
+	}
+
+	pairs, err := s.store.GetActiveTradingPairs(r.Context())
+	if err != nil {
+		slog.Error("Failed to get trading pairs", "error", err)
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	tickers := []*store.Ticker{}
+
+	for _, pair := range pairs {
+		ticker, err := s.tickers.get(r.Context(), s.store, pair.Symbol)
+		if err != nil {
+			slog.Error("Failed to get ticker", "error", err, "symbol", pair.Symbol)
+			http.Error(w, "Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		tickers = append(tickers, ticker)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tickers); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestHandleGetTicker(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	s := NewServer(store.NewStorage(tx))
+	ctx := context.Background()
+
+	if _, err := tx.Exec(ctx, "DELETE FROM trading_pairs"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO trading_pairs (symbol, base_asset, quote_asset, is_active)
+		VALUES ('BTC-USD', 'BTC', 'USD', true), ('ETH-USD', 'ETH', 'USD', true)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bidID, askID string
+	err = tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('BTC-USD', 'BUY', 50000, 1, 'FILLED') RETURNING id`).Scan(&bidID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('BTC-USD', 'SELL', 50000, 1, 'FILLED') RETURNING id`).Scan(&askID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO trades (bid_order_id, ask_order_id, price, quantity, timestamp)
+		VALUES ($1, $2, 50000, 1, $3)
+	`, bidID, askID, time.Now().UTC().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Returns a single ticker for a symbol", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ticker?symbol=BTC-USD", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetTicker(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d", rec.Code)
+		}
+
+		var ticker store.Ticker
+		if err := json.NewDecoder(rec.Body).Decode(&ticker); err != nil {
+			t.Fatal("Failed to decode JSON", err)
+		}
+
+		if ticker.LastPrice != 50000 || ticker.TradeCount != 1 {
+			t.Errorf("Unexpected ticker: %+v", ticker)
+		}
+	})
+
+	t.Run("Serves repeat requests from the cache", func(t *testing.T) {
+		if _, err := tx.Exec(ctx, `UPDATE trades SET price = 1 WHERE bid_order_id = $1`, bidID); err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest("GET", "/ticker?symbol=BTC-USD", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetTicker(rec, req)
+
+		var ticker store.Ticker
+		if err := json.NewDecoder(rec.Body).Decode(&ticker); err != nil {
+			t.Fatal("Failed to decode JSON", err)
+		}
+
+		if ticker.LastPrice != 50000 {
+			t.Errorf("Expected cached last price 50000, got %v", ticker.LastPrice)
+		}
+	})
+
+	t.Run("Returns every active pair without a symbol", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ticker", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetTicker(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d", rec.Code)
+		}
+
+		var tickers []store.Ticker
+		if err := json.NewDecoder(rec.Body).Decode(&tickers); err != nil {
+			t.Fatal("Failed to decode JSON", err)
+		}
+
+		if len(tickers) != 2 {
+			t.Errorf("Expected 2 tickers, got %d", len(tickers))
+		}
+	})
+
+	t.Run("Returns 400 for an empty symbol", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ticker?symbol=", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetTicker(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Returns 404 for an unknown symbol without caching it", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ticker?symbol=NOPE-USD", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetTicker(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+
+		if _, ok := s.tickers.entries["NOPE-USD"]; ok {
+			t.Error("Expected an unknown symbol to stay out of the cache")
+		}
+	})
+}
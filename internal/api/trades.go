@@ -2,8 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
 )
 
 func (s *Server) HandleGetRecentTrades(w http.ResponseWriter, r *http.Request) {
@@ -16,9 +19,39 @@ func (s *Server) HandleGetRecentTrades(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	recentTrades, err := s.store.GetRecentTrades(r.Context(), symbol)
+	limit, err := parseLimitParam(params, 50, 1000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startTime, err := parseTimeParam(params, "start")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	endTime, err := parseTimeParam(params, "end")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recentTrades, err := s.store.GetTrades(r.Context(), store.TradeQuery{
+		Symbol:    symbol,
+		FromID:    params.Get("from_id"),
+		Before:    params.Get("before"),
+		StartTime: startTime,
+		EndTime:   endTime,
+		Limit:     limit,
+	})
 
 	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		slog.Error("Failed to get recent trades", "error", err)
 		http.Error(w, "Server Error", http.StatusInternalServerError)
 		return
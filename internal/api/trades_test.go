@@ -36,10 +36,12 @@ func TestHandleGetRecentTrades(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = tx.Exec(ctx, `
+	var tradeID string
+	err = tx.QueryRow(ctx, `
 		INSERT INTO trades (bid_order_id, ask_order_id, price, quantity, timestamp)
 		VALUES ($1, $2, 50000, 1, $3)
-	`, bidID, askID, time.Now())
+		RETURNING id
+	`, bidID, askID, time.Now()).Scan(&tradeID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -72,6 +74,48 @@ func TestHandleGetRecentTrades(t *testing.T) {
 		}
 	})
 
+	t.Run("Pages backward with before", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/trades?symbol=BTC-USD&limit=10&before="+tradeID, nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetRecentTrades(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d", rec.Code)
+		}
+
+		var response map[string][]store.Trade
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal("Failed to decode JSON", err)
+		}
+
+		if len(response["recentTrades"]) != 0 {
+			t.Errorf("Expected no trades before the only trade, got %d", len(response["recentTrades"]))
+		}
+	})
+
+	t.Run("Returns 400 for an invalid limit", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/trades?symbol=BTC-USD&limit=-1", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetRecentTrades(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Returns 400 for a malformed cursor", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/trades?symbol=BTC-USD&from_id=latest", nil)
+		rec := httptest.NewRecorder()
+
+		s.HandleGetRecentTrades(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
 	t.Run("Returns 400 if symbol is missing", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/trades", nil)
 		rec := httptest.NewRecorder()
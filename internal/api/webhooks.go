@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookParams struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+func (s *Server) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	params := WebhookParams{}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := s.store.CreateWebhook(r.Context(), userID, params.URL, params.Events)
+	if err != nil {
+		if errors.Is(err, store.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		slog.Error("Failed to create webhook", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(webhook); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (s *Server) HandleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	webhooks, err := s.store.GetWebhooks(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get webhooks", "error", err)
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"webhooks": webhooks,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (s *Server) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.store.DeleteWebhook(r.Context(), chi.URLParam(r, "id"), userID); err != nil {
+		if errors.Is(err, store.ErrWebhookNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to delete webhook", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) HandleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	limit, err := parseLimitParam(r.URL.Query(), 100, 1000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := s.store.GetWebhookDeliveries(r.Context(), chi.URLParam(r, "id"), userID, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrWebhookNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to get webhook deliveries", "error", err)
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"deliveries": deliveries,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (s *Server) HandleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+
+	if !ok {
+		http.Error(w, "Unauthorized: User ID missing", http.StatusUnauthorized)
+		return
+	}
+
+	delivery, err := s.store.RedeliverWebhook(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "deliveryID"), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrDeliveryNotFound) {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+
+		slog.Error("Failed to redeliver webhook", "error", err)
+		http.Error(w, "Internal System Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(delivery); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWebhookAPI(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	s := NewServer(storage)
+
+	user := createTestUser(t, storage)
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDKey, user.ID)))
+		})
+	})
+	router.Post("/webhooks", s.HandleCreateWebhook)
+	router.Get("/webhooks", s.HandleGetWebhooks)
+	router.Delete("/webhooks/{id}", s.HandleDeleteWebhook)
+	router.Get("/webhooks/{id}/deliveries", s.HandleGetWebhookDeliveries)
+	router.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", s.HandleRedeliverWebhook)
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewBuffer(b)))
+		return rec
+	}
+
+	var created store.Webhook
+
+	t.Run("Returns 201 and the secret on create", func(t *testing.T) {
+		rec := do("POST", "/webhooks", WebhookParams{URL: "https://example.com/hook", Events: []string{"fills"}})
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected 201 Created, got %d. Body: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+			t.Fatal(err)
+		}
+		if created.ID == "" || created.Secret == "" {
+			t.Errorf("Expected an ID and secret, got %+v", created)
+		}
+	})
+
+	t.Run("Returns 400 for an unknown event", func(t *testing.T) {
+		rec := do("POST", "/webhooks", WebhookParams{URL: "https://example.com/hook", Events: []string{"gossip"}})
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Lists webhooks and an empty delivery log", func(t *testing.T) {
+		rec := do("GET", "/webhooks", nil)
+
+		var response struct {
+			Webhooks []store.Webhook `json:"webhooks"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Webhooks) != 1 || response.Webhooks[0].Secret != "" {
+			t.Errorf("Expected one webhook without its secret, got %+v", response.Webhooks)
+		}
+
+		if rec := do("GET", "/webhooks/"+created.ID+"/deliveries", nil); rec.Code != http.StatusOK {
+			t.Errorf("Expected 200 OK, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Returns 404 when redelivering an unknown delivery", func(t *testing.T) {
+		rec := do("POST", "/webhooks/"+created.ID+"/deliveries/"+created.ID+"/redeliver", nil)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Deletes a webhook once", func(t *testing.T) {
+		if rec := do("DELETE", "/webhooks/"+created.ID, nil); rec.Code != http.StatusNoContent {
+			t.Errorf("Expected 204 No Content, got %d", rec.Code)
+		}
+		if rec := do("DELETE", "/webhooks/"+created.ID, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 Not Found, got %d", rec.Code)
+		}
+	})
+}
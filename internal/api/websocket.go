@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = (wsPongWait * 9) / 10
+	wsMaxMessage = 4096
+)
+
+// checkOrigin admits clients that send no Origin, pages on the server's own
+// origin and pages on an allowed origin.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host) || slices.Contains(s.origins, origin)
+}
+
+// wsRequest is a client control message, e.g. {"method": "SUBSCRIBE", "params": ["trades:BTC-USD"], "id": 1}.
+type wsRequest struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+	ID     int      `json:"id"`
+}
+
+type wsResponse struct {
+	ID     int      `json:"id"`
+	Result []string `json:"result,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.hub == nil {
+		http.Error(w, "Streaming is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.checkOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an HTTP error response.
+		slog.Error("websocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := s.hub.Subscribe()
+	defer s.hub.Remove(sub)
+
+	requests := make(chan wsRequest)
+	readerDone := make(chan struct{})
+
+	go func() {
+		defer close(readerDone)
+
+		conn.SetReadLimit(wsMaxMessage)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+
+		for {
+			var req wsRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+
+			select {
+			case requests <- req:
+			case <-sub.Done():
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	write := func(v any) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(v)
+	}
+
+	for {
+		select {
+		case <-readerDone:
+			return
+
+		case <-sub.Done():
+			conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "slow consumer"),
+				time.Now().Add(wsWriteWait),
+			)
+			return
+
+		case req := <-requests:
+			if err := s.handleWSRequest(r.Context(), req, sub, write); err != nil {
+				return
+			}
+
+		case event := <-sub.Events():
+			if err := write(event); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleWSRequest applies a SUBSCRIBE/UNSUBSCRIBE and acknowledges it.
+func (s *Server) handleWSRequest(ctx context.Context, req wsRequest, sub *stream.Subscriber, write func(any) error) error {
+	for _, name := range req.Params {
+		if _, _, ok := stream.ParseName(name); !ok {
+			return write(wsResponse{ID: req.ID, Error: fmt.Sprintf("unknown stream: %s", name)})
+		}
+	}
+
+	switch strings.ToUpper(req.Method) {
+	case "SUBSCRIBE":
+		for _, name := range req.Params {
+			_, symbol, _ := stream.ParseName(name)
+
+			_, err := s.store.GetTradingPair(ctx, symbol)
+			if errors.Is(err, store.ErrPairNotFound) {
+				return write(wsResponse{ID: req.ID, Error: fmt.Sprintf("unknown symbol: %s", symbol)})
+			}
+			if err != nil {
+				slog.Error("Failed to get trading pair", "error", err, "symbol", symbol)
+				return write(wsResponse{ID: req.ID, Error: "server error"})
+			}
+		}
+
+		if err := sub.Subscribe(req.Params...); errors.Is(err, stream.ErrTooManyStreams) {
+			return write(wsResponse{ID: req.ID, Error: fmt.Sprintf("cannot subscribe to more than %d streams", stream.MaxStreams)})
+		}
+	case "UNSUBSCRIBE":
+		sub.Unsubscribe(req.Params...)
+		return write(wsResponse{ID: req.ID, Result: req.Params})
+	default:
+		return write(wsResponse{ID: req.ID, Error: fmt.Sprintf("unknown method: %s", req.Method)})
+	}
+
+	if err := write(wsResponse{ID: req.ID, Result: req.Params}); err != nil {
+		return err
+	}
+
+	for _, name := range req.Params {
+		channel, symbol, _ := stream.ParseName(name)
+		if channel != stream.ChannelBook {
+			continue
+		}
+
+		book, err := s.store.GetOrderBook(ctx, symbol)
+		if err != nil {
+			slog.Error("Failed to get order book snapshot", "error", err, "symbol", symbol)
+			continue
+		}
+
+		if err := write(stream.Event{Stream: name, Data: book}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+	"github.com/gorilla/websocket"
+)
+
+func TestHandleWebSocket(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	hub := stream.NewHub(16)
+	s := NewServer(store.NewStorage(tx), WithHub(hub))
+
+	if _, err := tx.Exec(context.Background(), `INSERT INTO trading_pairs (symbol, base_asset, quote_asset) VALUES ('BTC-USD', 'BTC', 'USD') ON CONFLICT DO NOTHING`); err != nil {
+		t.Fatalf("Failed to seed DB: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(s.HandleWebSocket))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	t.Run("Acknowledges a subscription and forwards events", func(t *testing.T) {
+		if err := conn.WriteJSON(wsRequest{Method: "SUBSCRIBE", Params: []string{"trades:BTC-USD"}, ID: 1}); err != nil {
+			t.Fatal(err)
+		}
+
+		var ack wsResponse
+		if err := conn.ReadJSON(&ack); err != nil {
+			t.Fatalf("Failed to read ack: %v", err)
+		}
+
+		if ack.ID != 1 || ack.Error != "" || len(ack.Result) != 1 {
+			t.Fatalf("Unexpected ack: %+v", ack)
+		}
+
+		hub.Publish(stream.Event{Stream: "trades:ETH-USD", Data: "ignored"})
+		hub.Publish(stream.Event{Stream: "trades:BTC-USD", Data: "hello"})
+
+		var event stream.Event
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+
+		if event.Stream != "trades:BTC-USD" || event.Data != "hello" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	})
+
+	t.Run("Rejects unknown streams", func(t *testing.T) {
+		if err := conn.WriteJSON(wsRequest{Method: "SUBSCRIBE", Params: []string{"gossip:BTC-USD"}, ID: 2}); err != nil {
+			t.Fatal(err)
+		}
+
+		var resp wsResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+
+		if resp.ID != 2 || resp.Error == "" {
+			t.Errorf("Expected an error response, got %+v", resp)
+		}
+	})
+
+	t.Run("Rejects symbols that are not listed", func(t *testing.T) {
+		if err := conn.WriteJSON(wsRequest{Method: "SUBSCRIBE", Params: []string{"trades:NOPE-USD"}, ID: 4}); err != nil {
+			t.Fatal(err)
+		}
+
+		var resp wsResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+
+		if resp.ID != 4 || resp.Error == "" {
+			t.Errorf("Expected an error response, got %+v", resp)
+		}
+	})
+
+	t.Run("Rejects unknown methods", func(t *testing.T) {
+		if err := conn.WriteJSON(wsRequest{Method: "SHOUT", Params: []string{"trades:BTC-USD"}, ID: 3}); err != nil {
+			t.Fatal(err)
+		}
+
+		var resp wsResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+
+		if resp.ID != 3 || resp.Error == "" {
+			t.Errorf("Expected an error response, got %+v", resp)
+		}
+	})
+}
+
+func TestHandleWebSocket_Origin(t *testing.T) {
+	s := NewServer(nil, WithHub(stream.NewHub(16)), WithAllowedOrigins("https://app.example.com"))
+
+	ts := httptest.NewServer(http.HandlerFunc(s.HandleWebSocket))
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"", true},
+		{ts.URL, true},
+		{"https://app.example.com", true},
+		{"https://evil.example.com", false},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+
+		conn, _, err := websocket.DefaultDialer.Dial(url, header)
+		if (err == nil) != tt.ok {
+			t.Errorf("Origin %q: expected ok=%v, got %v", tt.origin, tt.ok, err)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
+
+func TestHandleWebSocket_Disabled(t *testing.T) {
+	s := NewServer(nil)
+
+	req := httptest.NewRequest("GET", "/ws", nil)
+	rec := httptest.NewRecorder()
+
+	s.HandleWebSocket(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 Service Unavailable, got %d", rec.Code)
+	}
+}
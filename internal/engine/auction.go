@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+)
+
+// equilibrium finds the price at which the most of the book would trade.
+func equilibrium(book *store.OrderBook, reference float64) (price float64, volume int) {
+	bestSurplus, bestDistance := 0, 0.0
+
+	for _, side := range [][]store.OrderBookEntry{book.Bids, book.Asks} {
+		for _, level := range side {
+			demand, supply := 0, 0
+			for _, bid := range book.Bids {
+				if bid.Price >= level.Price {
+					demand += bid.Quantity
+				}
+			}
+			for _, ask := range book.Asks {
+				if ask.Price <= level.Price {
+					supply += ask.Quantity
+				}
+			}
+
+			traded := min(demand, supply)
+			if traded == 0 {
+				continue
+			}
+
+			surplus := demand - supply
+			if surplus < 0 {
+				surplus = -surplus
+			}
+			distance := math.Abs(level.Price - reference)
+
+			better := cmp.Or(
+				cmp.Compare(volume, traded),
+				cmp.Compare(surplus, bestSurplus),
+				cmp.Compare(distance, bestDistance),
+				cmp.Compare(level.Price, price),
+			) < 0
+			if volume == 0 || better {
+				price, volume, bestSurplus, bestDistance = level.Price, traded, surplus, distance
+			}
+		}
+	}
+
+	return price, volume
+}
+
+// callAuction publishes the indicative price, or starts the uncross once the auction has ended.
+func (m *MatchingEngine) callAuction(ctx context.Context, pair *store.TradingPair) (bool, error) {
+	book, err := m.store.GetOrderBookDepth(ctx, store.BookQuery{Symbol: pair.Symbol, Depth: store.FullBookDepth})
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch book: %w", err)
+	}
+
+	reference := cmp.Or(pair.LastPrice, pair.ReferencePrice)
+	price, volume := equilibrium(book, reference)
+
+	if pair.HaltedUntil == nil && pair.OpeningAuctionSeconds > 0 {
+		opened, err := m.store.ScheduleOpeningAuction(ctx, pair.Symbol)
+		if err != nil && !errors.Is(err, store.ErrTradingPhase) {
+			return false, err
+		}
+		if err == nil {
+			slog.Info("Opening auction scheduled", "ends_at", opened.HaltedUntil, "symbol", pair.Symbol)
+			pair = opened
+		}
+	}
+
+	if !pair.AuctionEnded {
+		m.publishAuction(pair.Symbol, store.PhaseAuction, pair.HaltedUntil, price, volume)
+		return false, nil
+	}
+
+	auction := store.TradingPhaseChange{Price: price, Volume: volume, Reference: reference}
+
+	if err := m.store.StartUncross(ctx, pair.Symbol, book.LastUpdateID, auction); err != nil {
+		if errors.Is(err, store.ErrStaleMatch) || errors.Is(err, store.ErrTradingPhase) {
+			// An order arrived as the auction ended, or another engine
+			// started the uncross; look again.
+			return true, nil
+		}
+		return false, err
+	}
+
+	slog.Info("Auction ended", "price", price, "volume", volume, "symbol", pair.Symbol)
+
+	if volume == 0 {
+		m.publishAuction(pair.Symbol, store.PhaseContinuous, nil, 0, 0)
+	} else {
+		m.publishAuction(pair.Symbol, store.PhaseUncrossing, nil, price, volume)
+	}
+
+	return true, nil
+}
+
+// auctionPrice returns the price a symbol is being uncrossed at, or zero if
+// it is not uncrossing.
+func (m *MatchingEngine) auctionPrice(ctx context.Context, symbol string) (float64, error) {
+	pair, err := m.store.GetTradingPair(ctx, symbol)
+	if errors.Is(err, store.ErrPairNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if pair.Phase != store.PhaseUncrossing {
+		return 0, nil
+	}
+
+	return pair.AuctionPrice, nil
+}
+
+func (m *MatchingEngine) publishAuction(symbol string, phase store.TradingPhase, endsAt *time.Time, price float64, volume int) {
+	if m.hub == nil {
+		return
+	}
+
+	m.hub.Publish(stream.Event{
+		Stream: stream.Name(stream.ChannelAuction, symbol),
+		Data:   stream.AuctionUpdate{Symbol: symbol, Phase: phase, EndsAt: endsAt, Price: price, Volume: volume},
+	})
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestEquilibrium(t *testing.T) {
+	level := func(price float64, qty int) store.OrderBookEntry {
+		return store.OrderBookEntry{Price: price, Quantity: qty}
+	}
+
+	tests := []struct {
+		name      string
+		bids      []store.OrderBookEntry
+		asks      []store.OrderBookEntry
+		reference float64
+		price     float64
+		volume    int
+	}{
+		{
+			name: "Does not cross",
+			bids: []store.OrderBookEntry{level(99, 5)},
+			asks: []store.OrderBookEntry{level(100, 5)},
+		},
+		{
+			name:   "Maximises volume",
+			bids:   []store.OrderBookEntry{level(102, 3), level(101, 1)},
+			asks:   []store.OrderBookEntry{level(100, 3), level(101, 2)},
+			price:  101,
+			volume: 4,
+		},
+		{
+			name:      "Then minimises the surplus",
+			bids:      []store.OrderBookEntry{level(102, 4), level(101, 2)},
+			asks:      []store.OrderBookEntry{level(100, 4), level(102, 3)},
+			reference: 102,
+			price:     101,
+			volume:    4,
+		},
+		{
+			name:      "Then prefers the price nearest the reference",
+			bids:      []store.OrderBookEntry{level(102, 3), level(101, 2), level(99, 5)},
+			asks:      []store.OrderBookEntry{level(98, 2), level(100, 4), level(103, 1)},
+			reference: 102,
+			price:     101,
+			volume:    5,
+		},
+		{
+			name:      "Then the lowest price",
+			bids:      []store.OrderBookEntry{level(102, 3), level(101, 2), level(99, 5)},
+			asks:      []store.OrderBookEntry{level(98, 2), level(100, 4), level(103, 1)},
+			reference: 100.5,
+			price:     100,
+			volume:    5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, volume := equilibrium(&store.OrderBook{Bids: tt.bids, Asks: tt.asks}, tt.reference)
+
+			if price != tt.price || volume != tt.volume {
+				t.Errorf("Expected %d @ %v, got %d @ %v", tt.volume, tt.price, volume, price)
+			}
+		})
+	}
+}
+
+func TestMatchOrders_Auction(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "auction_buyer", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &store.User{Username: "auction_seller", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// A newly listed pair opens with an auction.
+	if _, err := tx.Exec(ctx, `INSERT INTO trading_pairs (symbol, base_asset, quote_asset) VALUES ('AUC-USD', 'AUC', 'USD')`); err != nil {
+		t.Fatalf("Failed to seed DB: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	place := func(id string, owner *store.User, side string, price float64, qty int, offset time.Duration) {
+		t.Helper()
+
+		_, err := storage.RestoreOrder(ctx, store.Order{
+			ID: id, UserID: owner.ID, Symbol: "AUC-USD", Side: side, Price: price, Quantity: qty,
+			CreatedAt: baseTime.Add(offset),
+		})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+	}
+
+	// Continuously, the first ask at 98 would trade with the first bid at
+	// 102 at the ask's price. The auction trades 5 at 100 instead.
+	place("00000000-0000-4000-8000-0000000000f1", other, "SELL", 98, 2, 0)
+	place("00000000-0000-4000-8000-0000000000f2", u, "BUY", 102, 3, time.Second)
+	place("00000000-0000-4000-8000-0000000000f3", u, "BUY", 101, 2, 2*time.Second)
+	place("00000000-0000-4000-8000-0000000000f4", u, "BUY", 99, 5, 3*time.Second)
+	place("00000000-0000-4000-8000-0000000000f5", other, "SELL", 100, 4, 4*time.Second)
+	place("00000000-0000-4000-8000-0000000000f6", other, "SELL", 103, 1, 5*time.Second)
+
+	engine := New(storage)
+
+	trades := func() []store.Trade {
+		t.Helper()
+
+		trades, err := storage.GetTrades(ctx, store.TradeQuery{Symbol: "AUC-USD", Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trades
+	}
+
+	engine.runMatchingCycle(ctx, "AUC-USD")
+
+	if got := trades(); len(got) != 0 {
+		t.Fatalf("Expected nothing to trade during the auction, got %+v", got)
+	}
+
+	pair, err := storage.GetTradingPair(ctx, "AUC-USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.Phase != store.PhaseAuction || pair.HaltedUntil == nil || pair.OpeningAuctionSeconds != 0 {
+		t.Errorf("Expected the engine to schedule the end of the opening auction, got %+v", pair)
+	}
+
+	if _, err := storage.EndAuction(ctx, "AUC-USD"); err != nil {
+		t.Fatalf("EndAuction failed: %v", err)
+	}
+
+	engine.runMatchingCycle(ctx, "AUC-USD")
+
+	volume := 0
+	for _, trade := range trades() {
+		if trade.Price != 100 {
+			t.Errorf("Expected every trade at the auction price, got %+v", trade)
+		}
+		volume += trade.Quantity
+	}
+	if volume != 5 {
+		t.Errorf("Expected the auction to trade 5, got %d", volume)
+	}
+
+	pair, err = storage.GetTradingPair(ctx, "AUC-USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.Phase != store.PhaseContinuous {
+		t.Errorf("Expected continuous matching to resume, got %s", pair.Phase)
+	}
+}
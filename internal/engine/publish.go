@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+)
+
+// publishTrade emits everything a single trade changes.
+func (m *MatchingEngine) publishTrade(ctx context.Context, trade *store.Trade, candles []store.IntervalCandle, buyOrder, sellOrder *store.Order) {
+	if m.hub == nil {
+		return
+	}
+
+	symbol := trade.Symbol
+
+	m.hub.Publish(stream.Event{ID: trade.ID, Type: stream.TypeTrade, Stream: stream.Name(stream.ChannelTrades, symbol), Data: trade})
+
+	m.publishFill(ctx, trade, buyOrder)
+	m.publishFill(ctx, trade, sellOrder)
+
+	m.publishDepth(symbol, trade.UpdateID, trade.Depth)
+
+	for _, c := range candles {
+		m.hub.Publish(stream.Event{
+			Stream: stream.Name(stream.KlineChannel(c.Interval), symbol),
+			Data:   stream.KlineUpdate{Symbol: symbol, IntervalCandle: c},
+		})
+	}
+}
+
+// publishPrevention publishes the orders and levels a prevented self-trade changed.
+func (m *MatchingEngine) publishPrevention(ctx context.Context, p *store.SelfTradePrevention, buyOrder, sellOrder *store.Order) {
+	if m.hub == nil {
+		return
+	}
+
+	userStream := stream.Name(stream.ChannelOrders, p.UserID)
+
+	m.hub.Publish(stream.Event{Type: stream.TypeSelfTrade, Stream: userStream, Data: p})
+
+	for _, o := range []*store.Order{buyOrder, sellOrder} {
+		updated, err := m.store.GetOrder(ctx, o.ID)
+		if err != nil {
+			slog.Error("Failed to fetch order", "error", err, "order_id", o.ID)
+			return
+		}
+
+		if updated.Status != o.Status || updated.Quantity != o.Quantity {
+			m.hub.Publish(stream.Event{Type: stream.TypeOrder, Stream: userStream, Data: updated})
+		}
+	}
+
+	m.publishDepth(p.Symbol, p.UpdateID, p.Depth)
+}
+
+// publishOrder tells the owner's order stream about an order the engine
+// changed without trading it, such as a stop that triggered.
+func (m *MatchingEngine) publishOrder(order *store.Order) {
+	if m.hub == nil || order.UserID == "" {
+		return
+	}
+
+	m.hub.Publish(stream.Event{Type: stream.TypeOrder, Stream: stream.Name(stream.ChannelOrders, order.UserID), Data: order})
+}
+
+// publishDepth tells depth subscribers about the price levels a book change
+// touched, as the store read them before the change committed.
+func (m *MatchingEngine) publishDepth(symbol string, updateID int64, depth *store.DepthChange) {
+	if m.hub == nil || depth == nil {
+		return
+	}
+
+	m.hub.PublishDepth(stream.NewDepthUpdate(symbol, updateID, depth))
+}
+
+// publishFill sends the order owner their side of the trade followed by the
+// order's new state, so a filled order is reported as FILLED.
+func (m *MatchingEngine) publishFill(ctx context.Context, trade *store.Trade, order *store.Order) {
+	if order.UserID == "" {
+		return
+	}
+
+	fill, err := m.store.GetFill(ctx, trade.ID, order.ID)
+	if err != nil {
+		slog.Error("Failed to fetch fill", "error", err, "trade_id", trade.ID)
+		return
+	}
+
+	updated, err := m.store.GetOrder(ctx, order.ID)
+	if err != nil {
+		slog.Error("Failed to fetch order", "error", err, "order_id", order.ID)
+		return
+	}
+
+	userStream := stream.Name(stream.ChannelOrders, order.UserID)
+
+	m.hub.Publish(stream.Event{ID: fill.Cursor(), Type: stream.TypeFill, Stream: userStream, Data: fill})
+	m.hub.Publish(stream.Event{Type: stream.TypeOrder, Stream: userStream, Data: updated})
+}
+
+// publishSnapshots runs once per matching cycle that traded, rather than per
+// trade, because a full book and a 24h ticker are comparatively expensive.
+func (m *MatchingEngine) publishSnapshots(ctx context.Context, symbol string) {
+	if m.hub == nil {
+		return
+	}
+
+	book, err := m.store.GetOrderBook(ctx, symbol)
+	if err != nil {
+		slog.Error("Failed to fetch order book snapshot", "error", err)
+	} else {
+		m.hub.Publish(stream.Event{Stream: stream.Name(stream.ChannelBook, symbol), Data: book})
+	}
+
+	ticker, err := m.store.GetTicker(ctx, symbol, time.Now())
+	if err != nil {
+		slog.Error("Failed to fetch ticker", "error", err)
+	} else {
+		m.hub.Publish(stream.Event{Stream: stream.Name(stream.ChannelTicker, symbol), Data: ticker})
+	}
+}
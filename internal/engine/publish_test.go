@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestRunMatchingCycle_PublishesMarketData(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	hub := stream.NewHub(64)
+	engine := New(storage, WithHub(hub))
+
+	buyer, err := storage.CreateUser(ctx, &store.User{Username: "pub_buyer", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create buyer: %v", err)
+	}
+	seller, err := storage.CreateUser(ctx, &store.User{Username: "pub_seller", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create seller: %v", err)
+	}
+
+	sub := hub.Subscribe()
+	sub.Subscribe(
+		stream.Name(stream.ChannelOrders, seller.ID),
+		stream.Name(stream.ChannelTrades, "PUB-USD"),
+		stream.Name(stream.ChannelDepth, "PUB-USD"),
+		stream.Name(stream.KlineChannel(store.Minute), "PUB-USD"),
+		stream.Name(stream.ChannelBook, "PUB-USD"),
+	)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO orders (user_id, symbol, side, price, quantity, status) VALUES
+		($1, 'PUB-USD', 'BUY', 100, 2, 'PENDING'),
+		($2, 'PUB-USD', 'SELL', 100, 1, 'PENDING')
+	`, buyer.ID, seller.ID)
+	if err != nil {
+		t.Fatalf("Failed to seed orders: %v", err)
+	}
+
+	engine.runMatchingCycle(ctx, "PUB-USD")
+
+	received := map[string]stream.Event{}
+	private := []stream.Event{}
+
+	for len(sub.Events()) > 0 {
+		e := <-sub.Events()
+		if e.Stream == stream.Name(stream.ChannelOrders, seller.ID) {
+			private = append(private, e)
+			continue
+		}
+		received[e.Stream] = e
+	}
+
+	if len(private) != 2 || private[0].Type != stream.TypeFill || private[1].Type != stream.TypeOrder {
+		t.Fatalf("Expected a fill then an order update for the seller, got %+v", private)
+	}
+	if private[0].ID == "" {
+		t.Error("Expected the fill event to carry a resumable ID")
+	}
+	if status := private[1].Data.(*store.Order).Status; status != "FILLED" {
+		t.Errorf("Expected the sell order to be FILLED, got %s", status)
+	}
+
+	trade, ok := received["trades:PUB-USD"]
+	if !ok {
+		t.Fatal("Expected a trade event")
+	}
+	if trade.Data.(*store.Trade).Quantity != 1 {
+		t.Errorf("Expected trade quantity 1, got %+v", trade.Data)
+	}
+
+	depth, ok := received["depth:PUB-USD"]
+	if !ok {
+		t.Fatal("Expected a depth event")
+	}
+
+	update := depth.Data.(stream.DepthUpdate)
+	if update.Bids[0].Quantity != 1 || update.Asks[0].Quantity != 0 {
+		t.Errorf("Expected bid level 1 and emptied ask level, got %+v", update)
+	}
+
+	book, ok := received["book:PUB-USD"]
+	if !ok {
+		t.Fatal("Expected a book snapshot")
+	}
+	if last := book.Data.(*store.OrderBook).LastUpdateID; update.UpdateID != last {
+		t.Errorf("Expected depth updateId %d to match the snapshot, got %d", last, update.UpdateID)
+	}
+
+	if _, ok := received["kline_1m:PUB-USD"]; !ok {
+		t.Error("Expected a kline event")
+	}
+}
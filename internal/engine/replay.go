@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+var ErrReplayDiverged = errors.New("replay diverged from journal")
+
+// Replay re-applies journaled inputs and re-derives outputs, failing with ErrReplayDiverged on a mismatch.
+func (m *MatchingEngine) Replay(ctx context.Context, events []store.JournalEvent) error {
+	for _, e := range events {
+		if err := m.replayEvent(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MatchingEngine) replayEvent(ctx context.Context, e store.JournalEvent) error {
+	switch e.Type {
+	case store.EventOrderCreated:
+		order, err := e.Order()
+		if err != nil {
+			return err
+		}
+
+		if _, err := m.store.RestoreOrder(ctx, *order); err != nil {
+			return fmt.Errorf("failed to restore order at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventOrderGroupCreated:
+		group, err := e.OrderGroup()
+		if err != nil {
+			return err
+		}
+
+		if err := m.store.RestoreOrderGroup(ctx, *group); err != nil {
+			return fmt.Errorf("failed to restore order group at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventOrderAmended:
+		order, err := e.Order()
+		if err != nil {
+			return err
+		}
+
+		if _, err := m.store.RestoreAmendment(ctx, *order); err != nil {
+			return fmt.Errorf("failed to amend order at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventOrderCancelled:
+		order, err := e.Order()
+		if err != nil {
+			return err
+		}
+
+		if _, err := m.store.CancelOrder(ctx, order.ID, order.UserID); err != nil {
+			return fmt.Errorf("failed to cancel order at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventTradeExecuted:
+		want, err := e.Trade()
+		if err != nil {
+			return err
+		}
+
+		auctionPrice, err := m.auctionPrice(ctx, e.Symbol)
+		if err != nil {
+			return fmt.Errorf("failed to fetch trading phase at seq %d: %w", e.Seq, err)
+		}
+
+		got, _, err := m.step(ctx, e.Symbol, auctionPrice)
+		if err != nil {
+			return fmt.Errorf("failed to match at seq %d: %w", e.Seq, err)
+		}
+
+		if got == nil {
+			return fmt.Errorf("seq %d: expected trade %s but the book did not cross: %w", e.Seq, want.ID, ErrReplayDiverged)
+		}
+
+		if got.BuyerID != want.BuyerID || got.SellerID != want.SellerID || got.Price != want.Price || got.Quantity != want.Quantity {
+			return fmt.Errorf("seq %d: expected %d @ %v between %s and %s, got %d @ %v between %s and %s: %w",
+				e.Seq,
+				want.Quantity, want.Price, want.BuyerID, want.SellerID,
+				got.Quantity, got.Price, got.BuyerID, got.SellerID,
+				ErrReplayDiverged)
+		}
+
+	case store.EventSelfTradePrevented:
+		want, err := e.SelfTradePrevention()
+		if err != nil {
+			return err
+		}
+
+		auctionPrice, err := m.auctionPrice(ctx, e.Symbol)
+		if err != nil {
+			return fmt.Errorf("failed to fetch trading phase at seq %d: %w", e.Seq, err)
+		}
+
+		_, got, err := m.step(ctx, e.Symbol, auctionPrice)
+		if err != nil {
+			return fmt.Errorf("failed to match at seq %d: %w", e.Seq, err)
+		}
+
+		if got == nil || got.BuyOrderID != want.BuyOrderID || got.SellOrderID != want.SellOrderID || got.Mode != want.Mode || got.Quantity != want.Quantity {
+			return fmt.Errorf("seq %d: expected %s self-trade prevention between %s and %s, got %+v: %w",
+				e.Seq, want.Mode, want.BuyOrderID, want.SellOrderID, got, ErrReplayDiverged)
+		}
+
+	case store.EventOrderTriggered:
+		want, err := e.Order()
+		if err != nil {
+			return err
+		}
+
+		got, err := m.store.TriggerStop(ctx, e.Symbol)
+		if err != nil {
+			return fmt.Errorf("failed to trigger stop at seq %d: %w", e.Seq, err)
+		}
+
+		if got == nil || got.ID != want.ID {
+			return fmt.Errorf("seq %d: expected stop %s to trigger, got %+v: %w", e.Seq, want.ID, got, ErrReplayDiverged)
+		}
+
+		if err := m.store.RestorePriority(ctx, want.ID, want.CreatedAt, want.PriorityAt); err != nil {
+			return fmt.Errorf("failed to trigger stop at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventOrderActivated:
+		want, err := e.Order()
+		if err != nil {
+			return err
+		}
+
+		if err := m.store.RestorePriority(ctx, want.ID, want.CreatedAt, want.PriorityAt); err != nil {
+			if errors.Is(err, store.ErrOrderNotFound) {
+				return fmt.Errorf("seq %d: expected order %s to be activated: %w", e.Seq, want.ID, ErrReplayDiverged)
+			}
+			return fmt.Errorf("failed to activate order at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventOrderReplenished:
+		want, err := e.Replenishment()
+		if err != nil {
+			return err
+		}
+
+		got, err := m.store.GetOrder(ctx, want.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch order at seq %d: %w", e.Seq, err)
+		}
+
+		if got.Status != "PENDING" || got.VisibleQuantity != want.VisibleQuantity {
+			return fmt.Errorf("seq %d: expected order %s to show %d, got %s showing %d: %w",
+				e.Seq, want.OrderID, want.VisibleQuantity, got.Status, got.VisibleQuantity, ErrReplayDiverged)
+		}
+
+		if err := m.store.RestorePriority(ctx, want.OrderID, got.CreatedAt, want.PriorityAt); err != nil {
+			return fmt.Errorf("failed to replenish order at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventTradingPhaseChanged:
+		want, err := e.TradingPhaseChange()
+		if err != nil {
+			return err
+		}
+
+		if want.Phase == store.PhaseUncrossing {
+			book, err := m.store.GetOrderBookDepth(ctx, store.BookQuery{Symbol: e.Symbol, Depth: store.FullBookDepth})
+			if err != nil {
+				return fmt.Errorf("failed to fetch book at seq %d: %w", e.Seq, err)
+			}
+
+			if price, volume := equilibrium(book, want.Reference); price != want.Price || volume != want.Volume {
+				return fmt.Errorf("seq %d: expected the auction to uncross %d @ %v, got %d @ %v: %w",
+					e.Seq, want.Volume, want.Price, volume, price, ErrReplayDiverged)
+			}
+		}
+
+		if err := m.store.RestoreTradingPhase(ctx, e.Symbol, *want); err != nil {
+			return fmt.Errorf("failed to change trading phase at seq %d: %w", e.Seq, err)
+		}
+
+	case store.EventOrderStatusChanged:
+		want, err := e.StatusChange()
+		if err != nil {
+			return err
+		}
+
+		got, err := m.store.GetOrder(ctx, want.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch order at seq %d: %w", e.Seq, err)
+		}
+
+		if got.Status != want.Status || got.Quantity != want.Quantity || got.FilledQuantity != want.FilledQuantity {
+			return fmt.Errorf("seq %d: expected order %s %s with %d left, got %s with %d left: %w",
+				e.Seq, want.OrderID, want.Status, want.Quantity, got.Status, got.Quantity, ErrReplayDiverged)
+		}
+
+	default:
+		return fmt.Errorf("unknown journal event type %q at seq %d", e.Type, e.Seq)
+	}
+
+	return nil
+}
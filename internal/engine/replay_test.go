@@ -0,0 +1,208 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestReplay(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "replay_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	v, err := storage.CreateUser(ctx, &store.User{Username: "replay_seller", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	w, err := storage.CreateUser(ctx, &store.User{Username: "replay_buyer", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	var startSeq int64
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(seq), 0) FROM engine_events`).Scan(&startSeq); err != nil {
+		t.Fatal(err)
+	}
+
+	// Orders are restored with explicit times because NOW() is fixed inside
+	// the test transaction, which would leave time priority to chance.
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	placeIceberg := func(owner *store.User, side string, price float64, qty, display int, offset time.Duration) *store.Order {
+		t.Helper()
+
+		var id string
+		if err := tx.QueryRow(ctx, `SELECT gen_random_uuid()::text`).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+
+		o, err := storage.RestoreOrder(ctx, store.Order{
+			ID: id, UserID: owner.ID, Symbol: "RPL-USD", Side: side, Price: price, Quantity: qty,
+			DisplayQuantity: display, CreatedAt: baseTime.Add(offset),
+		})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+		return o
+	}
+
+	place := func(owner *store.User, side string, price float64, qty int, offset time.Duration) *store.Order {
+		t.Helper()
+		return placeIceberg(owner, side, price, qty, 0, offset)
+	}
+
+	// The pair only matters for the auction at the end, which replay must
+	// uncross the same way.
+	if _, err := tx.Exec(ctx, `INSERT INTO trading_pairs (symbol, base_asset, quote_asset, trading_phase) VALUES ('RPL-USD', 'RPL', 'USD', 'CONTINUOUS')`); err != nil {
+		t.Fatal(err)
+	}
+
+	live := New(storage)
+
+	place(u, "BUY", 100, 5, 0)
+	place(v, "SELL", 99, 2, time.Second)
+	leftover := place(v, "SELL", 100, 4, 2*time.Second)
+	live.runMatchingCycle(ctx, "RPL-USD")
+
+	resting := place(u, "BUY", 98, 3, 3*time.Second)
+	if _, _, err := storage.AmendOrder(ctx, resting.ID, u.ID, store.OrderAmendment{Price: 97}); err != nil {
+		t.Fatalf("AmendOrder failed: %v", err)
+	}
+	if _, err := storage.CancelOrder(ctx, leftover.ID, v.ID); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+
+	// u's sell crosses their own bid, which the amend made the newer order,
+	// so self-trade prevention cancels the bid instead of trading.
+	place(u, "SELL", 97, 1, 4*time.Second)
+	live.runMatchingCycle(ctx, "RPL-USD")
+
+	// The iceberg's first slice fills and the next is shown with a new time
+	// priority, which the replay has to give back.
+	placeIceberg(v, "SELL", 96, 5, 2, 5*time.Second)
+	place(w, "BUY", 96, 3, 6*time.Second)
+	live.runMatchingCycle(ctx, "RPL-USD")
+
+	// A trade at 95 triggers the OCO's stop-loss, which cancels its
+	// take-profit.
+	if _, err := storage.CreateOrderGroup(ctx, store.OrderGroupRequest{
+		UserID: u.ID, Symbol: "RPL-USD", Type: store.GroupOCO, Side: "SELL", Quantity: 1,
+		TakeProfitPrice: 120, StopPrice: 95,
+	}); err != nil {
+		t.Fatalf("CreateOrderGroup failed: %v", err)
+	}
+	place(v, "SELL", 95, 1, 7*time.Second)
+	place(w, "BUY", 95, 1, 8*time.Second)
+	live.runMatchingCycle(ctx, "RPL-USD")
+
+	// In an auction w's bids collect against the stop-loss at 95 and the
+	// rest of the iceberg, and uncross at 96 rather than the stop-loss's
+	// price.
+	if _, err := storage.StartAuction(ctx, "RPL-USD", 0); err != nil {
+		t.Fatalf("StartAuction failed: %v", err)
+	}
+	place(w, "BUY", 96, 2, 9*time.Second)
+	place(w, "BUY", 95, 1, 10*time.Second)
+	live.runMatchingCycle(ctx, "RPL-USD")
+	if _, err := storage.EndAuction(ctx, "RPL-USD"); err != nil {
+		t.Fatalf("EndAuction failed: %v", err)
+	}
+	live.runMatchingCycle(ctx, "RPL-USD")
+
+	journal, err := storage.GetJournal(ctx, store.JournalQuery{Symbol: "RPL-USD", AfterSeq: startSeq, Limit: 200})
+	if err != nil {
+		t.Fatalf("GetJournal failed: %v", err)
+	}
+
+	snapshot := func() map[string]store.Order {
+		t.Helper()
+
+		rows, err := tx.Query(ctx, `SELECT id FROM orders WHERE symbol = 'RPL-USD'`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+
+		orders := map[string]store.Order{}
+		for _, id := range ids {
+			o, err := storage.GetOrder(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			orders[id] = *o
+		}
+		return orders
+	}
+
+	// wipe stands in for a fresh database by removing everything the journal
+	// describes.
+	wipe := func() {
+		t.Helper()
+
+		_, err := tx.Exec(ctx, `
+			DELETE FROM trades WHERE bid_order_id IN (SELECT id FROM orders WHERE symbol = 'RPL-USD');
+			DELETE FROM orders WHERE symbol = 'RPL-USD';
+			DELETE FROM order_groups WHERE symbol = 'RPL-USD';
+			DELETE FROM book_sequences WHERE symbol = 'RPL-USD';
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Rebuilds the book", func(t *testing.T) {
+		want := snapshot()
+		wipe()
+
+		if err := New(storage).Replay(ctx, journal); err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+
+		got := snapshot()
+
+		if len(got) != len(want) {
+			t.Fatalf("Expected %d orders, got %d", len(want), len(got))
+		}
+		for id, o := range want {
+			if got[id] != o {
+				t.Errorf("Order %s: expected %+v, got %+v", id, o, got[id])
+			}
+		}
+	})
+
+	t.Run("Reports divergence", func(t *testing.T) {
+		wipe()
+
+		tampered := append([]store.JournalEvent(nil), journal...)
+		for i, e := range tampered {
+			if e.Type == store.EventTradeExecuted {
+				tampered[i].Payload = []byte(`{"id": "x", "buyer_id": "x", "seller_id": "x", "price": 1, "quantity": 1}`)
+				break
+			}
+		}
+
+		err := New(storage).Replay(ctx, tampered)
+
+		if !errors.Is(err, ErrReplayDiverged) {
+			t.Errorf("Expected ErrReplayDiverged, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// DefaultWorkers is how many symbols are matched at once unless WithWorkers
+// says otherwise.
+const DefaultWorkers = 4
+
+// WithWorkers caps how many symbols are matched at the same time.
+func WithWorkers(n int) Option {
+	return func(m *MatchingEngine) {
+		if n > 0 {
+			m.workers = n
+		}
+	}
+}
+
+// symbolWorker owns the matching of one symbol. Wake-ups are coalesced: a
+// worker that is already due to run does not queue a second cycle.
+type symbolWorker struct {
+	symbol string
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	waiters []chan struct{}
+}
+
+func (w *symbolWorker) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Notify asks the worker for symbol to run a matching cycle soon. It does
+// nothing if the symbol has no worker.
+func (m *MatchingEngine) Notify(symbol string) {
+	m.mu.Lock()
+	w, ok := m.symbols[symbol]
+	m.mu.Unlock()
+
+	if ok {
+		w.notify()
+	}
+}
+
+// Await waits until symbol's worker has matched everything committed before the call.
+func (m *MatchingEngine) Await(ctx context.Context, symbol string) error {
+	m.mu.Lock()
+	w, ok := m.symbols[symbol]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	matched := make(chan struct{})
+
+	w.mu.Lock()
+	w.waiters = append(w.waiters, matched)
+	w.mu.Unlock()
+
+	w.notify()
+
+	select {
+	case <-matched:
+		return nil
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// syncWorkers starts a worker for every newly active pair and stops the
+// workers of pairs that are no longer active.
+func (m *MatchingEngine) syncWorkers(ctx context.Context, active []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep := make(map[string]bool, len(active))
+
+	for _, symbol := range active {
+		keep[symbol] = true
+
+		if _, ok := m.symbols[symbol]; ok {
+			continue
+		}
+
+		workerCtx, cancel := context.WithCancel(ctx)
+		w := &symbolWorker{
+			symbol: symbol,
+			wake:   make(chan struct{}, 1),
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		m.symbols[symbol] = w
+
+		slog.Info("Starting matching worker", "symbol", symbol)
+		go m.runWorker(workerCtx, w)
+	}
+
+	for symbol, w := range m.symbols {
+		if !keep[symbol] {
+			slog.Info("Stopping matching worker", "symbol", symbol)
+			w.cancel()
+			delete(m.symbols, symbol)
+		}
+	}
+}
+
+// stopWorkers stops every worker and waits for them to finish their current
+// cycle.
+func (m *MatchingEngine) stopWorkers() {
+	m.mu.Lock()
+	workers := make([]*symbolWorker, 0, len(m.symbols))
+	for symbol, w := range m.symbols {
+		w.cancel()
+		workers = append(workers, w)
+		delete(m.symbols, symbol)
+	}
+	m.mu.Unlock()
+
+	for _, w := range workers {
+		<-w.done
+	}
+}
+
+func (m *MatchingEngine) runWorker(ctx context.Context, w *symbolWorker) {
+	defer close(w.done)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case m.slots <- struct{}{}:
+		}
+
+		w.mu.Lock()
+		waiters := w.waiters
+		w.waiters = nil
+		w.mu.Unlock()
+
+		if err := m.safeCycle(ctx, w.symbol); err != nil {
+			slog.Error("Matching worker recovered from panic", "error", err, "symbol", w.symbol)
+		}
+
+		<-m.slots
+
+		for _, matched := range waiters {
+			close(matched)
+		}
+	}
+}
+
+// safeCycle runs one matching cycle, turning a panic into an error so one
+// bad symbol cannot take the others down with it.
+func (m *MatchingEngine) safeCycle(ctx context.Context, symbol string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	m.cycle(ctx, symbol)
+
+	return nil
+}
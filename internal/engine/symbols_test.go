@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSymbolWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := New(nil, WithWorkers(2))
+
+	ran := make(chan string, 16)
+
+	m.cycle = func(ctx context.Context, symbol string) {
+		ran <- symbol
+
+		if symbol == "BAD-USD" {
+			panic("boom")
+		}
+	}
+
+	wait := func(want string) {
+		t.Helper()
+		select {
+		case got := <-ran:
+			if got != want {
+				t.Fatalf("Expected a cycle for %s, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for a cycle for %s", want)
+		}
+	}
+
+	m.syncWorkers(ctx, []string{"BAD-USD", "ETH-USD"})
+
+	t.Run("A panicking symbol keeps its worker and spares the others", func(t *testing.T) {
+		m.Notify("BAD-USD")
+		wait("BAD-USD")
+
+		m.Notify("ETH-USD")
+		wait("ETH-USD")
+
+		m.Notify("BAD-USD")
+		wait("BAD-USD")
+	})
+
+	t.Run("Follows the active pairs", func(t *testing.T) {
+		m.syncWorkers(ctx, []string{"ETH-USD", "SOL-USD"})
+
+		m.Notify("BAD-USD")
+		m.Notify("SOL-USD")
+		wait("SOL-USD")
+
+		select {
+		case got := <-ran:
+			t.Errorf("Expected no more cycles, got one for %s", got)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Stops every worker", func(t *testing.T) {
+		m.stopWorkers()
+
+		m.Notify("ETH-USD")
+
+		select {
+		case got := <-ran:
+			t.Errorf("Expected no cycles after stopping, got one for %s", got)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestAwait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := New(nil)
+
+	release := make(chan struct{})
+	m.cycle = func(ctx context.Context, symbol string) {
+		<-release
+	}
+
+	m.syncWorkers(ctx, []string{"BTC-USD"})
+	defer m.stopWorkers()
+
+	t.Run("Returns at once for an unknown symbol", func(t *testing.T) {
+		if err := m.Await(ctx, "NOPE-USD"); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+	})
+
+	t.Run("Gives up when the context ends", func(t *testing.T) {
+		short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		if err := m.Await(short, "BTC-USD"); err == nil {
+			t.Error("Expected a timeout while the cycle is blocked")
+		}
+	})
+
+	t.Run("Returns once a full cycle has run", func(t *testing.T) {
+		done := make(chan error)
+		go func() { done <- m.Await(ctx, "BTC-USD") }()
+
+		// Let the cycle that started before Await finish, then the one it
+		// asked for.
+		close(release)
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Expected nil, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for Await")
+		}
+	})
+}
@@ -2,22 +2,72 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
 )
 
 type MatchingEngine struct {
-	store *store.Storage
+	store   *store.Storage
+	hub     *stream.Hub
+	workers int
+	// sweepInterval is how often every symbol is matched regardless of
+	// notifications.
+	sweepInterval time.Duration
+
+	mu      sync.Mutex
+	symbols map[string]*symbolWorker
+	slots   chan struct{}
+	// cycle is what a symbol worker runs when woken; tests replace it.
+	cycle func(ctx context.Context, symbol string)
 }
 
-func New(s *store.Storage) *MatchingEngine {
-	return &MatchingEngine{
-		store: s,
+type Option func(*MatchingEngine)
+
+// WithHub makes the engine publish market data events as it matches.
+func WithHub(h *stream.Hub) Option {
+	return func(m *MatchingEngine) {
+		m.hub = h
+	}
+}
+
+// DefaultSweepInterval is how often the engine matches every symbol when
+// nothing has woken it.
+const DefaultSweepInterval = 5 * time.Second
+
+// WithSweepInterval sets how often every symbol is matched as a fallback to
+// order notifications.
+func WithSweepInterval(d time.Duration) Option {
+	return func(m *MatchingEngine) {
+		if d > 0 {
+			m.sweepInterval = d
+		}
 	}
 }
 
+func New(s *store.Storage, opts ...Option) *MatchingEngine {
+	m := &MatchingEngine{
+		store:         s,
+		workers:       DefaultWorkers,
+		sweepInterval: DefaultSweepInterval,
+		symbols:       map[string]*symbolWorker{},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.slots = make(chan struct{}, m.workers)
+	m.cycle = m.runMatchingCycle
+
+	return m
+}
+
 func min(a int, b int) int {
 	if a < b {
 		return a
@@ -26,77 +76,232 @@ func min(a int, b int) int {
 	}
 }
 
+// ProcessMatches keeps one worker per active trading pair, woken as orders arrive.
 func (m *MatchingEngine) ProcessMatches(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := time.NewTicker(m.sweepInterval)
 	defer ticker.Stop()
 
-	slog.Info("Matching Engine Worker Started")
+	slog.Info("Matching Engine Worker Started", "workers", m.workers, "sweep", m.sweepInterval)
+
+	go m.listen(ctx)
+
+	m.sweep(ctx)
 
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Info("Matching Engine shutting down...")
+			m.stopWorkers()
 			return
 		case <-ticker.C:
-			tradingPairs, err := m.store.GetActiveTradingPairs(ctx)
+			m.sweep(ctx)
+		}
+	}
+}
 
-			if err != nil {
-				slog.Error("failed to get active trading pairs", "error", err)
-				continue
-			}
+// sweep syncs the workers with the active trading pairs and wakes them all.
+func (m *MatchingEngine) sweep(ctx context.Context) {
+	tradingPairs, err := m.store.GetActiveTradingPairs(ctx)
 
-			for _, pair := range tradingPairs {
-				m.runMatchingCycle(ctx, pair.Symbol)
-			}
+	if err != nil {
+		slog.Error("failed to get active trading pairs", "error", err)
+		return
+	}
 
-		}
+	symbols := make([]string, 0, len(tradingPairs))
+	for _, pair := range tradingPairs {
+		symbols = append(symbols, pair.Symbol)
 	}
-}
 
-func (m *MatchingEngine) runMatchingCycle(ctx context.Context, symbol string) {
+	m.syncWorkers(ctx, symbols)
+
+	for _, symbol := range symbols {
+		m.Notify(symbol)
+	}
+}
 
+// listen wakes workers for orders placed by other processes, reconnecting
+// after a failure. The sweep covers anything missed in between.
+func (m *MatchingEngine) listen(ctx context.Context) {
 	for {
-		buyOrder, err := m.store.GetBestBuyOrder(ctx, symbol)
-		if err != nil {
-			slog.Error("Failed to fetch best buy order", "error", err)
+		err := m.store.ListenOrders(ctx, m.Notify)
+
+		if errors.Is(err, store.ErrListenUnsupported) {
+			slog.Warn("Order notifications unavailable, relying on the sweep")
 			return
 		}
 
-		if buyOrder == nil {
+		if ctx.Err() != nil {
 			return
 		}
 
-		sellOrder, err := m.store.GetBestSellOrder(ctx, symbol)
-		if err != nil {
-			slog.Error("Failed to fetch best sell order", "error", err)
+		slog.Error("Lost order notifications, reconnecting", "error", err)
+
+		select {
+		case <-ctx.Done():
 			return
+		case <-time.After(time.Second):
 		}
+	}
+}
 
-		if sellOrder == nil {
-			return
+func (m *MatchingEngine) runMatchingCycle(ctx context.Context, symbol string) {
+	if changes := m.matchOrders(ctx, symbol); changes > 0 {
+		m.publishSnapshots(ctx, symbol)
+	}
+}
+
+// matchOrders crosses the book until it no longer overlaps and returns how many changes it made.
+func (m *MatchingEngine) matchOrders(ctx context.Context, symbol string) int {
+	changes := 0
+
+	for {
+		pair, err := m.store.GetTradingPair(ctx, symbol)
+		if err != nil && !errors.Is(err, store.ErrPairNotFound) {
+			slog.Error("Failed to fetch trading phase", "error", err, "symbol", symbol)
+			return changes
 		}
 
-		if buyOrder.Price < sellOrder.Price {
-			return
+		var auctionPrice float64
+
+		if pair != nil {
+			switch pair.Phase {
+			case store.PhaseAuction:
+				ended, err := m.callAuction(ctx, pair)
+				if err != nil {
+					slog.Error("Failed to run auction", "error", err, "symbol", symbol)
+					return changes
+				}
+
+				if !ended {
+					return changes
+				}
+
+				changes++
+				continue
+			case store.PhaseUncrossing:
+				auctionPrice = pair.AuctionPrice
+			}
 		}
 
-		tradeQuantity := min(buyOrder.Quantity, sellOrder.Quantity)
-		if tradeQuantity <= 0 {
-			slog.Info("Order filled or empty, skipping match")
-			return
+		trade, prevented, err := m.step(ctx, symbol, auctionPrice)
+		if errors.Is(err, store.ErrStaleMatch) {
+			// Another engine or a cancel got there first; re-read the book.
+			slog.Info("Stale match, retrying", "symbol", symbol)
+			continue
 		}
 
-		tradePrice := buyOrder.Price
-		if sellOrder.CreatedAt.Before(buyOrder.CreatedAt) {
-			tradePrice = sellOrder.Price
+		if err != nil {
+			slog.Error("Failed to match orders", "error", err, "symbol", symbol)
+			return changes
 		}
 
-		slog.Info("Match Found", "qty", tradeQuantity, "price", tradePrice)
+		if trade == nil && prevented == nil && auctionPrice > 0 {
+			// Nothing more crosses at the auction price, so the uncross is
+			// over. Another engine may have finished it already.
+			if err := m.store.FinishUncross(ctx, symbol); err != nil && !errors.Is(err, store.ErrTradingPhase) {
+				slog.Error("Failed to finish uncross", "error", err, "symbol", symbol)
+				return changes
+			}
+
+			slog.Info("Auction uncrossed, continuous matching resumed", "price", auctionPrice, "symbol", symbol)
+
+			m.publishAuction(symbol, store.PhaseContinuous, nil, 0, 0)
+
+			changes++
+			continue
+		}
+
+		if trade == nil && prevented == nil {
+			// Only once the book has settled does a triggered stop join it,
+			// at which point it may cross again.
+			triggered, err := m.store.TriggerStop(ctx, symbol)
+			if errors.Is(err, store.ErrStaleMatch) {
+				continue
+			}
+
+			if err != nil {
+				slog.Error("Failed to trigger stops", "error", err, "symbol", symbol)
+				return changes
+			}
+
+			if triggered == nil {
+				return changes
+			}
+
+			slog.Info("Stop triggered", "order_id", triggered.ID, "stop_price", triggered.StopPrice, "symbol", symbol)
+
+			m.publishDepth(symbol, triggered.UpdateID, triggered.Depth)
+			m.publishOrder(triggered)
+		}
+
+		changes++
+	}
+}
+
+// step matches the best bid against the best ask once, at auctionPrice if it is non-zero.
+func (m *MatchingEngine) step(ctx context.Context, symbol string, auctionPrice float64) (*store.Trade, *store.SelfTradePrevention, error) {
+	buyOrder, err := m.store.GetBestBuyOrder(ctx, symbol)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch best buy order: %w", err)
+	}
 
-		err = m.store.CreateTrade(ctx, tradePrice, tradeQuantity, buyOrder.ID, sellOrder.ID)
+	if buyOrder == nil {
+		return nil, nil, nil
+	}
+
+	sellOrder, err := m.store.GetBestSellOrder(ctx, symbol)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch best sell order: %w", err)
+	}
+
+	if sellOrder == nil {
+		return nil, nil, nil
+	}
+
+	if buyOrder.Price < sellOrder.Price {
+		return nil, nil, nil
+	}
+
+	if auctionPrice > 0 && (buyOrder.Price < auctionPrice || sellOrder.Price > auctionPrice) {
+		return nil, nil, nil
+	}
+
+	tradeQuantity := min(buyOrder.Shown(), sellOrder.Shown())
+	if tradeQuantity <= 0 {
+		slog.Info("Order filled or empty, skipping match")
+		return nil, nil, nil
+	}
+
+	if buyOrder.UserID != "" && buyOrder.UserID == sellOrder.UserID {
+		prevented, err := m.store.PreventSelfTrade(ctx, buyOrder, sellOrder)
 		if err != nil {
-			slog.Error("Failed to execute trade", "error", err)
-			return
+			return nil, nil, fmt.Errorf("failed to prevent self-trade: %w", err)
 		}
+
+		slog.Info("Self-trade prevented", "mode", prevented.Mode, "user_id", prevented.UserID, "symbol", symbol)
+
+		m.publishPrevention(ctx, prevented, buyOrder, sellOrder)
+
+		return nil, prevented, nil
+	}
+
+	tradePrice := buyOrder.Price
+	if sellOrder.CreatedAt.Before(buyOrder.CreatedAt) {
+		tradePrice = sellOrder.Price
 	}
+	if auctionPrice > 0 {
+		tradePrice = auctionPrice
+	}
+
+	slog.Info("Match Found", "qty", tradeQuantity, "price", tradePrice)
+
+	trade, err := m.store.CreateTrade(ctx, tradePrice, tradeQuantity, buyOrder.ID, sellOrder.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute trade: %w", err)
+	}
+
+	m.publishTrade(ctx, trade, trade.Candles, buyOrder, sellOrder)
+
+	return trade, nil, nil
 }
@@ -2,7 +2,10 @@ package engine
 
 import (
 	"context"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Nevnet99/trade-engine/internal/store"
 	"github.com/Nevnet99/trade-engine/internal/testutils"
@@ -20,6 +23,13 @@ func TestProcessMatches_MultiFill(t *testing.T) {
 		t.Fatalf("Failed to create test user: %v", err)
 	}
 
+	// The sellers are someone else, or self-trade prevention would stop
+	// the whale trading with them.
+	seller, err := storage.CreateUser(ctx, &store.User{Username: "engine_seller", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
 	_, err = tx.Exec(ctx, `
     INSERT INTO trading_pairs (symbol, base_asset, quote_asset, is_active) 
     VALUES ('BTC-USD', 'BTC', 'USD', true)
@@ -34,18 +44,19 @@ func TestProcessMatches_MultiFill(t *testing.T) {
 		Symbol: "BTC-USD", Side: "BUY", Price: 50000, Quantity: 10,
 	}
 
-	whaleID, err := storage.CreateOrder(ctx, whaleOrder)
+	whale, err := storage.CreateOrder(ctx, whaleOrder)
 	if err != nil {
 		t.Fatalf("Failed to create whale order: %v", err)
 	}
+	whaleID := whale.ID
 
 	sellerA := store.Order{
-		UserID: u.ID,
+		UserID: seller.ID,
 		Symbol: "BTC-USD", Side: "SELL", Price: 49000, Quantity: 4,
 	}
 
 	sellerB := store.Order{
-		UserID: u.ID,
+		UserID: seller.ID,
 		Symbol: "BTC-USD", Side: "SELL", Price: 49500, Quantity: 4,
 	}
 
@@ -69,4 +80,380 @@ func TestProcessMatches_MultiFill(t *testing.T) {
 	if remainingQty != 2 {
 		t.Errorf("Expected Whale Quantity 2, got %d", remainingQty)
 	}
+
+	candles, err := storage.GetKlines(ctx, "BTC-USD", store.Minute, 1)
+	if err != nil {
+		t.Fatalf("Failed to fetch candles: %v", err)
+	}
+
+	if len(candles) != 1 || candles[0].Volume < 8 {
+		t.Errorf("Expected the engine to record both fills in the latest candle, got %+v", candles)
+	}
+}
+
+func TestMatchOrders_ConcurrentEngines(t *testing.T) {
+	pool := testutils.SetupTestPool(t)
+	storage := store.NewStorageFromPool(pool)
+	ctx := context.Background()
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	symbol := "RACE-" + suffix
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "race_" + suffix, PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	buyer, err := storage.CreateUser(ctx, &store.User{Username: "race_buyer_" + suffix, PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		users := []string{u.ID, buyer.ID}
+
+		for _, c := range []struct {
+			query string
+			args  []any
+		}{
+			{`DELETE FROM outbox_deliveries WHERE message_id IN (SELECT id FROM outbox WHERE user_id = ANY($1) OR payload->>'symbol' = $2)`, []any{users, symbol}},
+			{`DELETE FROM outbox WHERE user_id = ANY($1) OR payload->>'symbol' = $2`, []any{users, symbol}},
+			{`DELETE FROM trades WHERE bid_order_id IN (SELECT id FROM orders WHERE symbol = $1)`, []any{symbol}},
+			{`DELETE FROM engine_events WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM book_sequences WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM candles WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM orders WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM users WHERE id = ANY($1)`, []any{users}},
+		} {
+			if _, err := pool.Exec(ctx, c.query, c.args...); err != nil {
+				t.Logf("Failed to clean up: %v", err)
+			}
+		}
+	})
+
+	original := map[string]int{}
+
+	place := func(side string, price float64, qty int) *store.Order {
+		owner := u.ID
+		if side == "BUY" {
+			owner = buyer.ID
+		}
+
+		o, err := storage.CreateOrder(ctx, store.Order{UserID: owner, Symbol: symbol, Side: side, Price: price, Quantity: qty})
+		if err != nil {
+			t.Fatalf("Failed to create order: %v", err)
+		}
+		original[o.ID] = qty
+		return o
+	}
+
+	for range 5 {
+		place("BUY", 100, 7)
+	}
+
+	var sells []*store.Order
+	for i := range 20 {
+		sells = append(sells, place("SELL", float64(95+i%5), 2))
+	}
+
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			engine := New(store.NewStorageFromPool(pool))
+			for range 5 {
+				engine.matchOrders(ctx, symbol)
+			}
+		}()
+	}
+
+	// Cancels race the engines; each one either wins or finds the order gone.
+	for _, o := range sells[len(sells)-5:] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			storage.CancelOrder(ctx, o.ID, u.ID)
+		}()
+	}
+
+	wg.Wait()
+
+	rows, err := pool.Query(ctx, `
+	SELECT o.id, o.quantity, o.filled_quantity, o.status,
+		COALESCE((SELECT SUM(t.quantity) FROM trades t WHERE t.bid_order_id = o.id OR t.ask_order_id = o.id), 0)
+	FROM orders o
+	WHERE o.symbol = $1
+	`, symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, status string
+		var quantity, filled, traded int
+
+		if err := rows.Scan(&id, &quantity, &filled, &status, &traded); err != nil {
+			t.Fatal(err)
+		}
+
+		if quantity < 0 || quantity+filled != original[id] || filled != traded {
+			t.Errorf("Order %s (%s): quantity %d, filled %d, traded %d, placed %d", id, status, quantity, filled, traded, original[id])
+		}
+		if status == "FILLED" && quantity != 0 {
+			t.Errorf("Order %s is FILLED with %d left", id, quantity)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	bid, _ := storage.GetBestBuyOrder(ctx, symbol)
+	ask, _ := storage.GetBestSellOrder(ctx, symbol)
+
+	if bid != nil && ask != nil && bid.Price >= ask.Price {
+		t.Errorf("Expected the book to be uncrossed, best bid %v best ask %v", bid.Price, ask.Price)
+	}
+}
+
+func TestMatchOrders_SelfTradePrevention(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "wash_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &store.User{Username: "wash_counterparty", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	place := func(id string, owner *store.User, side string, price float64, offset time.Duration) {
+		t.Helper()
+
+		_, err := storage.RestoreOrder(ctx, store.Order{
+			ID: id, UserID: owner.ID, Symbol: "WASH-USD", Side: side, Price: price, Quantity: 1,
+			CreatedAt: baseTime.Add(offset),
+		})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+	}
+
+	// u's sell is best and would cross u's own bid; other's sell behind it
+	// should still trade once u's sell is cancelled.
+	place("00000000-0000-4000-8000-0000000000b1", u, "BUY", 100, 0)
+	place("00000000-0000-4000-8000-0000000000b2", other, "SELL", 100, time.Second)
+	place("00000000-0000-4000-8000-0000000000b3", u, "SELL", 99, 2*time.Second)
+
+	New(storage).runMatchingCycle(ctx, "WASH-USD")
+
+	wash, err := storage.GetOrder(ctx, "00000000-0000-4000-8000-0000000000b3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wash.Status != "CANCELLED" {
+		t.Errorf("Expected u's newer sell to be cancelled, got %s", wash.Status)
+	}
+
+	trades, err := storage.GetTrades(ctx, store.TradeQuery{Symbol: "WASH-USD", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].SellerID != "00000000-0000-4000-8000-0000000000b2" {
+		t.Errorf("Expected a single trade against the other user, got %+v", trades)
+	}
+}
+
+func TestMatchOrders_IcebergSweep(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	maker, err := storage.CreateUser(ctx, &store.User{Username: "iceberg_maker", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	taker, err := storage.CreateUser(ctx, &store.User{Username: "iceberg_taker", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// The bid sweeps all four slices of the resting iceberg. Each new slice
+	// goes to the back of the queue, but the iceberg is still the maker.
+	if _, err := storage.RestoreOrder(ctx, store.Order{
+		ID: "00000000-0000-4000-8000-0000000000e1", UserID: maker.ID, Symbol: "SWEEP-USD", Side: "SELL",
+		Price: 100, Quantity: 10, DisplayQuantity: 3, CreatedAt: baseTime,
+	}); err != nil {
+		t.Fatalf("Failed to place iceberg: %v", err)
+	}
+	if _, err := storage.RestoreOrder(ctx, store.Order{
+		ID: "00000000-0000-4000-8000-0000000000e2", UserID: taker.ID, Symbol: "SWEEP-USD", Side: "BUY",
+		Price: 102, Quantity: 10, CreatedAt: baseTime.Add(time.Second),
+	}); err != nil {
+		t.Fatalf("Failed to place bid: %v", err)
+	}
+
+	New(storage).runMatchingCycle(ctx, "SWEEP-USD")
+
+	trades, err := storage.GetTrades(ctx, store.TradeQuery{Symbol: "SWEEP-USD", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trades) != 4 {
+		t.Fatalf("Expected a fill per slice, got %+v", trades)
+	}
+
+	filled := 0
+	for _, trade := range trades {
+		if trade.Price != 100 || trade.TakerSide != "BUY" {
+			t.Errorf("Expected every fill at the iceberg's 100 taken by the BUY, got %+v", trade)
+		}
+		filled += trade.Quantity
+	}
+	if filled != 10 {
+		t.Errorf("Expected the iceberg to fill 10, got %d", filled)
+	}
+}
+
+func TestMatchOrders_StopTriggers(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "stop_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &store.User{Username: "stop_counterparty", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	place := func(id string, owner *store.User, side string, price float64, offset time.Duration) {
+		t.Helper()
+
+		_, err := storage.RestoreOrder(ctx, store.Order{
+			ID: id, UserID: owner.ID, Symbol: "STOP-USD", Side: side, Price: price, Quantity: 1,
+			CreatedAt: baseTime.Add(offset),
+		})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+	}
+
+	// The first trade, at 95, reaches u's stop, which then sells into the
+	// bid left behind.
+	_, err = storage.RestoreOrder(ctx, store.Order{
+		ID: "00000000-0000-4000-8000-0000000000d1", UserID: u.ID, Symbol: "STOP-USD", Side: "SELL",
+		Type: store.StopLimit, StopPrice: 95, Price: 94, Quantity: 1, CreatedAt: baseTime,
+	})
+	if err != nil {
+		t.Fatalf("Failed to place stop: %v", err)
+	}
+
+	place("00000000-0000-4000-8000-0000000000d2", other, "BUY", 95, time.Second)
+	place("00000000-0000-4000-8000-0000000000d3", other, "BUY", 94, 2*time.Second)
+	place("00000000-0000-4000-8000-0000000000d4", other, "SELL", 95, 3*time.Second)
+
+	New(storage).runMatchingCycle(ctx, "STOP-USD")
+
+	stop, err := storage.GetOrder(ctx, "00000000-0000-4000-8000-0000000000d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Status != "FILLED" {
+		t.Errorf("Expected the stop to trigger and fill, got %s", stop.Status)
+	}
+
+	trades, err := storage.GetTrades(ctx, store.TradeQuery{Symbol: "STOP-USD", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Errorf("Expected two trades, got %+v", trades)
+	}
+}
+
+func TestMatchOrders_CircuitBreaker(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "breaker_buyer", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &store.User{Username: "breaker_seller", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO trading_pairs (symbol, base_asset, quote_asset, trading_phase) VALUES ('BRKR-USD', 'BRKR', 'USD', 'CONTINUOUS')`); err != nil {
+		t.Fatalf("Failed to seed DB: %v", err)
+	}
+	if _, err := storage.SetPairProtection(ctx, "BRKR-USD", store.PairProtection{
+		CircuitBreakerPercent: 5, CircuitBreakerWindowSeconds: 60, CircuitBreakerHaltSeconds: 300,
+	}); err != nil {
+		t.Fatalf("SetPairProtection failed: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	place := func(id string, owner *store.User, side string, price float64, offset time.Duration) {
+		t.Helper()
+
+		_, err := storage.RestoreOrder(ctx, store.Order{
+			ID: id, UserID: owner.ID, Symbol: "BRKR-USD", Side: side, Price: price, Quantity: 1,
+			CreatedAt: baseTime.Add(offset),
+		})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+	}
+
+	engine := New(storage)
+
+	place("00000000-0000-4000-8000-0000000000e1", u, "BUY", 100, 0)
+	place("00000000-0000-4000-8000-0000000000e2", other, "SELL", 100, time.Second)
+
+	engine.runMatchingCycle(ctx, "BRKR-USD")
+
+	// The trade at 90 moves the price 10% and trips the breaker, so the
+	// second pair of orders is left crossed on the book for the re-opening
+	// auction.
+	place("00000000-0000-4000-8000-0000000000e3", u, "BUY", 90, 2*time.Second)
+	place("00000000-0000-4000-8000-0000000000e4", other, "SELL", 90, 3*time.Second)
+	place("00000000-0000-4000-8000-0000000000e5", u, "BUY", 90, 4*time.Second)
+	place("00000000-0000-4000-8000-0000000000e6", other, "SELL", 90, 5*time.Second)
+
+	engine.runMatchingCycle(ctx, "BRKR-USD")
+
+	trades, err := storage.GetTrades(ctx, store.TradeQuery{Symbol: "BRKR-USD", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Errorf("Expected matching to halt after two trades, got %+v", trades)
+	}
+
+	for _, id := range []string{"00000000-0000-4000-8000-0000000000e5", "00000000-0000-4000-8000-0000000000e6"} {
+		o, err := storage.GetOrder(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.Status != "PENDING" {
+			t.Errorf("Expected order %s to rest during the halt, got %s", id, o.Status)
+		}
+	}
 }
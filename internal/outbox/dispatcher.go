@@ -0,0 +1,192 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+// Dispatcher delivers outbox messages to each of its sinks at least once.
+type Dispatcher struct {
+	store       *store.Storage
+	sinks       []Sink
+	interval    time.Duration
+	batch       int
+	start       store.OutboxStart
+	maxAttempts int
+	retention   time.Duration
+}
+
+type Option func(*Dispatcher)
+
+// WithInterval sets how often each sink is polled for pending messages.
+func WithInterval(d time.Duration) Option {
+	return func(o *Dispatcher) {
+		o.interval = d
+	}
+}
+
+// WithBatchSize sets how many messages a sink is sent per poll.
+func WithBatchSize(n int) Option {
+	return func(o *Dispatcher) {
+		o.batch = n
+	}
+}
+
+// WithStart sets where a sink that has never run starts reading the
+// outbox. Sinks that have run before carry on where they were.
+func WithStart(start store.OutboxStart) Option {
+	return func(o *Dispatcher) {
+		o.start = start
+	}
+}
+
+// WithMaxAttempts sets how many times a message is tried before it is
+// dead-lettered.
+func WithMaxAttempts(n int) Option {
+	return func(o *Dispatcher) {
+		o.maxAttempts = n
+	}
+}
+
+// WithRetention sets how long settled messages are kept.
+func WithRetention(d time.Duration) Option {
+	return func(o *Dispatcher) {
+		o.retention = d
+	}
+}
+
+func NewDispatcher(s *store.Storage, sinks []Sink, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:       s,
+		sinks:       sinks,
+		interval:    time.Second,
+		batch:       100,
+		start:       store.OutboxStartEarliest,
+		maxAttempts: 10,
+		retention:   7 * 24 * time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Info("Outbox Dispatcher Started", "sinks", len(d.sinks))
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.runPruner(ctx)
+	}()
+
+	for _, sink := range d.sinks {
+		if err := d.store.RegisterOutboxSink(ctx, sink.Name(), d.start); err != nil {
+			slog.Error("Failed to register outbox sink", "error", err, "sink", sink.Name())
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.runSink(ctx, sink)
+		}()
+	}
+
+	wg.Wait()
+	slog.Info("Outbox Dispatcher shutting down...")
+}
+
+func (d *Dispatcher) runSink(ctx context.Context, sink Sink) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.dispatch(ctx, sink); err != nil {
+				slog.Error("Outbox delivery failed", "error", err, "sink", sink.Name())
+			}
+		}
+	}
+}
+
+// runPruner deletes settled messages past the retention, hourly.
+func (d *Dispatcher) runPruner(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.prune(ctx); err != nil {
+				slog.Error("Outbox pruning failed", "error", err)
+			}
+		}
+	}
+}
+
+// prune deletes settled messages past the retention, a batch at a time, and
+// returns how many it deleted.
+func (d *Dispatcher) prune(ctx context.Context) (int64, error) {
+	var total int64
+
+	for {
+		n, err := d.store.PruneOutbox(ctx, d.retention, 1000)
+		total += n
+		if err != nil || n < 1000 {
+			return total, err
+		}
+	}
+}
+
+// dispatch sends one batch to sink in order, stopping at the first failure.
+func (d *Dispatcher) dispatch(ctx context.Context, sink Sink) (int, error) {
+	if err := d.store.AdvanceOutboxSink(ctx, sink.Name()); err != nil {
+		return 0, err
+	}
+
+	messages, err := d.store.GetPendingOutbox(ctx, sink.Name(), d.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+
+	for _, msg := range messages {
+		deliveryErr := sink.Deliver(ctx, msg)
+
+		if err := d.store.RecordOutboxDelivery(ctx, msg.ID, sink.Name(), deliveryErr); err != nil {
+			return delivered, err
+		}
+
+		if deliveryErr != nil {
+			if msg.Attempts+1 < d.maxAttempts {
+				return delivered, deliveryErr
+			}
+
+			if err := d.store.DeadLetterOutbox(ctx, msg.ID, sink.Name()); err != nil {
+				return delivered, err
+			}
+
+			slog.Error("Outbox message dead-lettered", "error", deliveryErr, "sink", sink.Name(), "message_id", msg.ID, "attempts", msg.Attempts+1)
+			continue
+		}
+
+		delivered++
+	}
+
+	return delivered, nil
+}
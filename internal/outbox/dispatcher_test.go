@@ -0,0 +1,233 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+// fakeSink records what it is sent and fails while failures is positive.
+type fakeSink struct {
+	name      string
+	failures  int
+	delivered []store.OutboxMessage
+}
+
+func (f *fakeSink) Name() string {
+	return f.name
+}
+
+func (f *fakeSink) Deliver(ctx context.Context, msg store.OutboxMessage) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("sink unavailable")
+	}
+
+	f.delivered = append(f.delivered, msg)
+	return nil
+}
+
+func (f *fakeSink) keys() []string {
+	keys := []string{}
+	for _, m := range f.delivered {
+		keys = append(keys, m.Key)
+	}
+	return keys
+}
+
+// drain dispatches until nothing is left or a delivery fails.
+func drain(t *testing.T, d *Dispatcher, sink Sink) error {
+	t.Helper()
+
+	for {
+		n, err := d.dispatch(context.Background(), sink)
+		if err != nil || n == 0 {
+			return err
+		}
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "outbox_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	bid, err := storage.CreateOrder(ctx, store.Order{UserID: u.ID, Symbol: "OBX-USD", Side: "BUY", Price: 100, Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ask, err := storage.CreateOrder(ctx, store.Order{UserID: u.ID, Symbol: "OBX-USD", Side: "SELL", Price: 100, Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := storage.CreateTrade(ctx, 100, 1, bid.ID, ask.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := storage.CreateTrade(ctx, 100, 1, bid.ID, ask.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDispatcher(storage, nil, WithBatchSize(1000))
+
+	// The test database may hold messages from earlier activity, so only our
+	// two trades are checked.
+	ours := func(keys []string) []string {
+		out := []string{}
+		for _, k := range keys {
+			if k == first.ID || k == second.ID {
+				out = append(out, k)
+			}
+		}
+		return out
+	}
+
+	t.Run("Delivers each trade once, in order", func(t *testing.T) {
+		sink := &fakeSink{name: "test-healthy"}
+
+		if err := drain(t, d, sink); err != nil {
+			t.Fatalf("dispatch failed: %v", err)
+		}
+
+		got := ours(sink.keys())
+		if len(got) != 2 || got[0] != first.ID || got[1] != second.ID {
+			t.Fatalf("Expected both trades in order, got %v", got)
+		}
+
+		if sink.delivered[len(sink.delivered)-1].Topic != store.TopicTrades {
+			t.Errorf("Expected topic %s, got %s", store.TopicTrades, sink.delivered[0].Topic)
+		}
+
+		if err := drain(t, d, sink); err != nil {
+			t.Fatalf("dispatch failed: %v", err)
+		}
+		if got := ours(sink.keys()); len(got) != 2 {
+			t.Errorf("Expected no redelivery once delivered, got %v", got)
+		}
+	})
+
+	t.Run("Retries a failed delivery without skipping ahead", func(t *testing.T) {
+		sink := &fakeSink{name: "test-flaky", failures: 1}
+
+		if err := drain(t, d, sink); err == nil {
+			t.Fatal("Expected the first dispatch to fail")
+		}
+		if len(sink.delivered) != 0 {
+			t.Fatalf("Expected nothing delivered past the failure, got %v", sink.keys())
+		}
+
+		pending, err := storage.GetPendingOutbox(ctx, sink.Name(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].Attempts != 1 {
+			t.Errorf("Expected the failed message to record one attempt, got %+v", pending)
+		}
+
+		if err := drain(t, d, sink); err != nil {
+			t.Fatalf("dispatch failed: %v", err)
+		}
+		if got := ours(sink.keys()); len(got) != 2 {
+			t.Errorf("Expected both trades after the retry, got %v", got)
+		}
+	})
+
+	t.Run("Dead-letters a message after its last attempt", func(t *testing.T) {
+		sink := &fakeSink{name: "test-dead", failures: 2}
+
+		if err := storage.RegisterOutboxSink(ctx, sink.Name(), store.OutboxStartLatest); err != nil {
+			t.Fatal(err)
+		}
+
+		bid, err := storage.CreateOrder(ctx, store.Order{UserID: u.ID, Symbol: "OBX-USD", Side: "BUY", Price: 100, Quantity: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ask, err := storage.CreateOrder(ctx, store.Order{UserID: u.ID, Symbol: "OBX-USD", Side: "SELL", Price: 100, Quantity: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.CreateTrade(ctx, 100, 1, bid.ID, ask.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := storage.GetPendingOutbox(ctx, sink.Name(), 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) < 2 {
+			t.Fatalf("Expected only the new trade's messages to be pending, got %+v", pending)
+		}
+
+		d := NewDispatcher(storage, nil, WithBatchSize(1000), WithMaxAttempts(2))
+
+		if err := drain(t, d, sink); err == nil {
+			t.Fatal("Expected the first attempt to fail")
+		}
+		if err := drain(t, d, sink); err != nil {
+			t.Fatalf("Expected the second failure to dead-letter, got %v", err)
+		}
+
+		if len(sink.delivered) != len(pending)-1 || sink.delivered[0].ID != pending[1].ID {
+			t.Errorf("Expected every message but the first to be delivered, got %v", sink.keys())
+		}
+
+		left, err := storage.GetPendingOutbox(ctx, sink.Name(), 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != 0 {
+			t.Errorf("Expected nothing pending after the dead letter, got %+v", left)
+		}
+	})
+
+	t.Run("Prunes settled messages past the retention", func(t *testing.T) {
+		sink := &fakeSink{name: "test-prune"}
+
+		// Only this sink's floor should decide what is settled.
+		if _, err := tx.Exec(ctx, `DELETE FROM outbox_sinks`); err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.RegisterOutboxSink(ctx, sink.Name(), store.OutboxStartEarliest); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := drain(t, d, sink); err != nil {
+			t.Fatalf("dispatch failed: %v", err)
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE outbox SET created_at = created_at - INTERVAL '30 days'`); err != nil {
+			t.Fatal(err)
+		}
+
+		// Moves the floor past what was just delivered.
+		if err := drain(t, d, sink); err != nil {
+			t.Fatalf("dispatch failed: %v", err)
+		}
+
+		pruner := NewDispatcher(storage, nil, WithRetention(24*time.Hour))
+
+		if n, err := pruner.prune(ctx); err != nil || n == 0 {
+			t.Fatalf("Expected messages to be pruned, got %d, %v", n, err)
+		}
+
+		var left int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM outbox WHERE key = ANY($1)`, []string{first.ID, second.ID}).Scan(&left); err != nil {
+			t.Fatal(err)
+		}
+		if left != 0 {
+			t.Errorf("Expected both trades to be pruned, %d left", left)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+// Sink is somewhere outbox messages are delivered; Name must be stable across restarts.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, msg store.OutboxMessage) error
+}
+
+// ChannelSink hands messages to a consumer in the same process. Delivery
+// blocks until the consumer takes the message.
+type ChannelSink struct {
+	name     string
+	messages chan store.OutboxMessage
+}
+
+func NewChannelSink(name string, buffer int) *ChannelSink {
+	return &ChannelSink{
+		name:     name,
+		messages: make(chan store.OutboxMessage, buffer),
+	}
+}
+
+func (c *ChannelSink) Name() string {
+	return c.name
+}
+
+func (c *ChannelSink) Messages() <-chan store.OutboxMessage {
+	return c.messages
+}
+
+func (c *ChannelSink) Deliver(ctx context.Context, msg store.OutboxMessage) error {
+	select {
+	case c.messages <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WebhookSink POSTs each message's payload to a URL. Any response other than
+// 2xx counts as a failed delivery.
+type WebhookSink struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(name, url string) *WebhookSink {
+	return &WebhookSink{
+		name:   name,
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (w *WebhookSink) Name() string {
+	return w.name
+}
+
+func (w *WebhookSink) Deliver(ctx context.Context, msg store.OutboxMessage) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(msg.Payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Outbox-Id", strconv.FormatInt(msg.ID, 10))
+	req.Header.Set("X-Outbox-Topic", msg.Topic)
+	req.Header.Set("X-Outbox-Key", msg.Key)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Publisher is the subset of a message broker client the outbox needs.
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, data []byte) error
+}
+
+// PublisherSink forwards messages to a broker through a Publisher.
+type PublisherSink struct {
+	name      string
+	publisher Publisher
+}
+
+func NewPublisherSink(name string, p Publisher) *PublisherSink {
+	return &PublisherSink{name: name, publisher: p}
+}
+
+func (p *PublisherSink) Name() string {
+	return p.name
+}
+
+func (p *PublisherSink) Deliver(ctx context.Context, msg store.OutboxMessage) error {
+	return p.publisher.Publish(ctx, msg.Topic, msg.Key, msg.Payload)
+}
@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+func TestWebhookSink(t *testing.T) {
+	msg := store.OutboxMessage{ID: 42, Topic: store.TopicTrades, Key: "trade-1", Payload: json.RawMessage(`{"id":"trade-1"}`)}
+
+	t.Run("Posts the payload with message headers", func(t *testing.T) {
+		var gotBody string
+		var gotHeader http.Header
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			gotBody, gotHeader = string(body), r.Header
+		}))
+		defer ts.Close()
+
+		if err := NewWebhookSink("test", ts.URL).Deliver(context.Background(), msg); err != nil {
+			t.Fatalf("Deliver failed: %v", err)
+		}
+
+		if gotBody != `{"id":"trade-1"}` {
+			t.Errorf("Unexpected body: %s", gotBody)
+		}
+		if gotHeader.Get("X-Outbox-Id") != "42" || gotHeader.Get("X-Outbox-Topic") != "trades" || gotHeader.Get("X-Outbox-Key") != "trade-1" {
+			t.Errorf("Unexpected headers: %v", gotHeader)
+		}
+	})
+
+	t.Run("Treats a non-2xx response as a failure", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		if err := NewWebhookSink("test", ts.URL).Deliver(context.Background(), msg); err == nil {
+			t.Error("Expected an error but got none")
+		}
+	})
+}
+
+func TestChannelSink(t *testing.T) {
+	sink := NewChannelSink("test", 1)
+
+	if err := sink.Deliver(context.Background(), store.OutboxMessage{ID: 1}); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	if got := <-sink.Messages(); got.ID != 1 {
+		t.Errorf("Expected message 1, got %+v", got)
+	}
+
+	t.Run("Gives up when the context ends", func(t *testing.T) {
+		full := NewChannelSink("test", 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := full.Deliver(ctx, store.OutboxMessage{ID: 2}); err == nil {
+			t.Error("Expected an error but got none")
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OrderAmendment changes a resting order in place; zero fields are left as they are.
+type OrderAmendment struct {
+	Price    float64
+	Quantity int
+}
+
+// AmendOrder changes a PENDING order's price or quantity, returning it before and after.
+func (s *Storage) AmendOrder(ctx context.Context, orderID, userID string, a OrderAmendment) (before, after *Order, err error) {
+	if a.Price < 0 {
+		return nil, nil, fmt.Errorf("price must be positive: %w", ErrValidation)
+	}
+	if a.Quantity < 0 {
+		return nil, nil, fmt.Errorf("quantity must be positive: %w", ErrValidation)
+	}
+	if a.Price == 0 && a.Quantity == 0 {
+		return nil, nil, fmt.Errorf("price or quantity is required: %w", ErrValidation)
+	}
+
+	if !isUUID(orderID) || !isUUID(userID) {
+		return nil, nil, ErrOrderNotFound
+	}
+
+	return s.amendOrder(ctx, orderID, userID, a, time.Time{}, time.Time{})
+}
+
+// RestoreAmendment re-applies an amend taken from the journal, giving the
+// order the price, quantity and time priority it was journaled with.
+func (s *Storage) RestoreAmendment(ctx context.Context, order Order) (*Order, error) {
+	_, after, err := s.amendOrder(ctx, order.ID, order.UserID, OrderAmendment{Price: order.Price, Quantity: order.Quantity}, order.CreatedAt, order.PriorityAt)
+	return after, err
+}
+
+func (s *Storage) amendOrder(ctx context.Context, orderID, userID string, a OrderAmendment, createdAt, priorityAt time.Time) (*Order, *Order, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := lockBook(ctx, tx, orderID); err != nil {
+		return nil, nil, err
+	}
+
+	inTx := &Storage{db: tx}
+
+	before, err := inTx.getOrder(ctx, "id = $1 AND user_id = $2 AND status = 'PENDING' FOR UPDATE", orderID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	after := *before
+	if a.Price > 0 {
+		after.Price = a.Price
+	}
+	if a.Quantity > 0 {
+		after.Quantity = a.Quantity
+	}
+	if after.DisplayQuantity > 0 {
+		after.VisibleQuantity = min(after.VisibleQuantity, after.Quantity)
+	}
+
+	// Replayed amends were checked when they were first made.
+	if createdAt.IsZero() && after.Price != before.Price {
+		if err := checkPriceBand(ctx, tx, after.Symbol, after.Price); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	losesPriority := after.Price != before.Price || after.Quantity > before.Quantity
+
+	if createdAt.IsZero() && !losesPriority && after.Quantity == before.Quantity {
+		return before, &after, nil
+	}
+
+	var restoreAt, restorePriorityAt *time.Time
+	if !createdAt.IsZero() {
+		utc, priorityUTC := createdAt.UTC(), priorityAt.UTC()
+		restoreAt, restorePriorityAt = &utc, &priorityUTC
+	}
+
+	query := `
+    UPDATE orders
+    SET price = $2,
+        quantity = $3,
+        visible_quantity = CASE WHEN display_quantity IS NOT NULL THEN LEAST(visible_quantity, $3) END,
+        created_at = COALESCE($4, CASE WHEN $5 THEN clock.ts ELSE created_at END),
+        priority_at = COALESCE($6, CASE WHEN $5 THEN clock.ts ELSE priority_at END)
+    FROM (SELECT clock_timestamp()::timestamp AS ts) clock
+    WHERE id = $1
+    RETURNING created_at, priority_at`
+
+	err = tx.QueryRow(ctx, query, orderID, after.Price, after.Quantity, restoreAt, losesPriority, restorePriorityAt).Scan(&after.CreatedAt, &after.PriorityAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrOrderNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to amend order: %w", err)
+	}
+
+	after.UpdateID, err = nextUpdateID(ctx, tx, after.Symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := appendEvent(ctx, tx, after.Symbol, EventOrderAmended, after); err != nil {
+		return nil, nil, err
+	}
+
+	if err := appendOrderEvent(ctx, tx, after.UserID, OrderEventOrder, after); err != nil {
+		return nil, nil, err
+	}
+
+	after.Depth, err = readDepth(ctx, tx, after.Symbol, *before, after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return before, &after, nil
+}
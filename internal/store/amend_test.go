@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestAmendOrder(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "amend_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &User{Username: "amend_other", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	place := func(id string, offset time.Duration) *Order {
+		t.Helper()
+
+		o, err := storage.RestoreOrder(ctx, Order{
+			ID: id, UserID: u.ID, Symbol: "AMD-USD", Side: "BUY", Price: 100, Quantity: 5,
+			CreatedAt: baseTime.Add(offset),
+		})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+		return o
+	}
+
+	first := place("00000000-0000-4000-8000-0000000000a1", 0)
+	second := place("00000000-0000-4000-8000-0000000000a2", time.Second)
+
+	best := func() string {
+		t.Helper()
+
+		o, err := storage.GetBestBuyOrder(ctx, "AMD-USD")
+		if err != nil || o == nil {
+			t.Fatalf("Failed to fetch best bid: %v", err)
+		}
+		return o.ID
+	}
+
+	t.Run("Validates the amendment", func(t *testing.T) {
+		for _, a := range []OrderAmendment{{}, {Price: -1}, {Quantity: -1}} {
+			if _, _, err := storage.AmendOrder(ctx, first.ID, u.ID, a); !errors.Is(err, ErrValidation) {
+				t.Errorf("%+v: expected ErrValidation, got %v", a, err)
+			}
+		}
+	})
+
+	t.Run("Only amends the owner's resting orders", func(t *testing.T) {
+		if _, _, err := storage.AmendOrder(ctx, first.ID, other.ID, OrderAmendment{Quantity: 1}); !errors.Is(err, ErrOrderNotFound) {
+			t.Errorf("Expected ErrOrderNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Reducing quantity keeps priority", func(t *testing.T) {
+		before, after, err := storage.AmendOrder(ctx, first.ID, u.ID, OrderAmendment{Quantity: 3})
+		if err != nil {
+			t.Fatalf("AmendOrder failed: %v", err)
+		}
+
+		if before.Quantity != 5 || after.Quantity != 3 || !after.CreatedAt.Equal(before.CreatedAt) || after.UpdateID == 0 {
+			t.Errorf("Expected 5 -> 3 with the same timestamp, got %+v -> %+v", before, after)
+		}
+		if best() != first.ID {
+			t.Error("Expected the reduced order to stay first in the queue")
+		}
+	})
+
+	t.Run("An unchanged amend is not recorded", func(t *testing.T) {
+		_, after, err := storage.AmendOrder(ctx, first.ID, u.ID, OrderAmendment{Price: 100, Quantity: 3})
+		if err != nil {
+			t.Fatalf("AmendOrder failed: %v", err)
+		}
+		if after.UpdateID != 0 {
+			t.Errorf("Expected no book change, got update %d", after.UpdateID)
+		}
+	})
+
+	t.Run("Increasing quantity loses priority", func(t *testing.T) {
+		_, after, err := storage.AmendOrder(ctx, first.ID, u.ID, OrderAmendment{Quantity: 10})
+		if err != nil {
+			t.Fatalf("AmendOrder failed: %v", err)
+		}
+
+		if !after.CreatedAt.After(second.CreatedAt) {
+			t.Errorf("Expected a new timestamp, got %v", after.CreatedAt)
+		}
+		if best() != second.ID {
+			t.Error("Expected the increased order to move behind the other")
+		}
+	})
+
+	t.Run("Changing price loses priority and is journaled", func(t *testing.T) {
+		_, after, err := storage.AmendOrder(ctx, second.ID, u.ID, OrderAmendment{Price: 101})
+		if err != nil {
+			t.Fatalf("AmendOrder failed: %v", err)
+		}
+
+		if after.Price != 101 || !after.CreatedAt.After(second.CreatedAt) {
+			t.Errorf("Expected a repriced order with a new timestamp, got %+v", after)
+		}
+
+		events, err := storage.GetJournal(ctx, JournalQuery{Symbol: "AMD-USD", Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last := events[len(events)-1]
+		amended, err := last.Order()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last.Type != EventOrderAmended || amended.ID != second.ID || amended.Price != 101 {
+			t.Errorf("Expected an ORDER_AMENDED event for the repriced order, got %s %+v", last.Type, amended)
+		}
+	})
+}
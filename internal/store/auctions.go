@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TradingPhase is how a pair's orders are matched.
+type TradingPhase string
+
+const (
+	// PhaseContinuous matches orders as they cross.
+	PhaseContinuous TradingPhase = "CONTINUOUS"
+	// PhaseAuction collects orders without matching them until the auction
+	// ends.
+	PhaseAuction TradingPhase = "AUCTION"
+	// PhaseUncrossing matches what crosses at the end of an auction, all at
+	// the auction price.
+	PhaseUncrossing TradingPhase = "UNCROSSING"
+)
+
+// ErrTradingPhase means a pair is not in a phase the change can be made in.
+var ErrTradingPhase = errors.New("not allowed in the pair's trading phase")
+
+// TradingPhaseChange records a pair moving to a new phase.
+type TradingPhaseChange struct {
+	Phase     TradingPhase `json:"phase"`
+	EndsAt    *time.Time   `json:"ends_at,omitempty"`
+	Price     float64      `json:"price,omitempty"`
+	Volume    int          `json:"volume,omitempty"`
+	Reference float64      `json:"reference,omitempty"`
+}
+
+// GetTradingPair returns a pair, active or not.
+func (s *Storage) GetTradingPair(ctx context.Context, symbol string) (*TradingPair, error) {
+	var pair TradingPair
+
+	err := scanPair(s.db.QueryRow(ctx, `SELECT `+pairColumns+` FROM trading_pairs WHERE symbol = $1`, symbol), &pair)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPairNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trading pair: %w", err)
+	}
+
+	return &pair, nil
+}
+
+// StartAuction starts an auction that ends after duration, or when EndAuction is called if it is zero.
+func (s *Storage) StartAuction(ctx context.Context, symbol string, duration time.Duration) (*TradingPair, error) {
+	if duration < 0 {
+		return nil, fmt.Errorf("duration must not be negative: %w", ErrValidation)
+	}
+
+	where := "trading_phase IN ('CONTINUOUS', 'AUCTION')"
+	change := TradingPhaseChange{Phase: PhaseAuction}
+
+	if duration == 0 {
+		return s.changePhase(ctx, symbol, where, "NULL", change)
+	}
+
+	return s.changePhase(ctx, symbol, where, "NOW() + make_interval(secs => $4)", change, duration.Seconds())
+}
+
+// ScheduleOpeningAuction ends a new pair's opening auction OpeningAuctionSeconds from now.
+func (s *Storage) ScheduleOpeningAuction(ctx context.Context, symbol string) (*TradingPair, error) {
+	where := "trading_phase = 'AUCTION' AND halted_until IS NULL AND opening_auction_seconds IS NOT NULL"
+
+	return s.changePhase(ctx, symbol, where, "NOW() + make_interval(secs => opening_auction_seconds)", TradingPhaseChange{Phase: PhaseAuction})
+}
+
+// EndAuction ends a pair's auction now; the engine uncrosses it on its next
+// cycle. It is ErrTradingPhase if the pair is not in an auction.
+func (s *Storage) EndAuction(ctx context.Context, symbol string) (*TradingPair, error) {
+	return s.changePhase(ctx, symbol, "trading_phase = 'AUCTION'", "NOW()", TradingPhaseChange{Phase: PhaseAuction})
+}
+
+// StartUncross moves an ended auction to UNCROSSING, or ErrStaleMatch if the book moved past lastUpdateID.
+func (s *Storage) StartUncross(ctx context.Context, symbol string, lastUpdateID int64, auction TradingPhaseChange) error {
+	auction.Phase, auction.EndsAt = PhaseUncrossing, nil
+	if auction.Volume == 0 {
+		auction = TradingPhaseChange{Phase: PhaseContinuous}
+	}
+
+	where := `trading_phase = 'AUCTION'
+        AND COALESCE((SELECT last_update_id FROM book_sequences b WHERE b.symbol = trading_pairs.symbol), 0) = $4`
+
+	_, err := s.changePhase(ctx, symbol, where, "NULL", auction, lastUpdateID)
+	if errors.Is(err, ErrTradingPhase) {
+		if pair, getErr := s.GetTradingPair(ctx, symbol); getErr == nil && pair.Phase == PhaseAuction {
+			return ErrStaleMatch
+		}
+	}
+
+	return err
+}
+
+// FinishUncross returns an uncrossed pair to continuous matching. It is
+// ErrTradingPhase if the pair is not uncrossing.
+func (s *Storage) FinishUncross(ctx context.Context, symbol string) error {
+	_, err := s.changePhase(ctx, symbol, "trading_phase = 'UNCROSSING'", "NULL", TradingPhaseChange{Phase: PhaseContinuous})
+	return err
+}
+
+// RestoreTradingPhase re-applies a phase change taken from the journal.
+func (s *Storage) RestoreTradingPhase(ctx context.Context, symbol string, change TradingPhaseChange) error {
+	_, err := s.changePhase(ctx, symbol, "TRUE", "$4::timestamptz", change, change.EndsAt)
+	return err
+}
+
+// changePhase moves a pair to change's phase where it holds; arguments to where and endsAt start at $4.
+func (s *Storage) changePhase(ctx context.Context, symbol, where, endsAt string, change TradingPhaseChange, args ...any) (*TradingPair, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := lockSequence(ctx, tx, symbol); err != nil {
+		return nil, err
+	}
+
+	query := `
+    UPDATE trading_pairs
+    SET trading_phase = $2,
+        halted_until = ` + endsAt + `,
+        auction_price = NULLIF($3::numeric, 0),
+        opening_auction_seconds = NULL
+    WHERE symbol = $1 AND ` + where + `
+    RETURNING ` + pairColumns
+
+	var pair TradingPair
+
+	args = append([]any{symbol, string(change.Phase), change.Price}, args...)
+
+	err = scanPair(tx.QueryRow(ctx, query, args...), &pair)
+	if errors.Is(err, pgx.ErrNoRows) {
+		inTx := &Storage{db: tx}
+		if _, err := inTx.GetTradingPair(ctx, symbol); err != nil {
+			return nil, err
+		}
+		return nil, ErrTradingPhase
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to change trading phase: %w", err)
+	}
+
+	change.EndsAt = pair.HaltedUntil
+
+	if err := appendEvent(ctx, tx, symbol, EventTradingPhaseChanged, change); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &pair, nil
+}
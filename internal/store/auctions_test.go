@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestTradingPhases(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	if _, err := tx.Exec(ctx, `INSERT INTO trading_pairs (symbol, base_asset, quote_asset) VALUES ('PHS-USD', 'PHS', 'USD')`); err != nil {
+		t.Fatalf("Failed to seed DB: %v", err)
+	}
+
+	phase := func() *TradingPair {
+		t.Helper()
+
+		pair, err := storage.GetTradingPair(ctx, "PHS-USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pair
+	}
+
+	t.Run("A new pair opens with an auction", func(t *testing.T) {
+		if pair := phase(); pair.Phase != PhaseAuction || pair.HaltedUntil != nil || pair.OpeningAuctionSeconds != 300 {
+			t.Errorf("Expected an opening auction yet to be scheduled, got %+v", pair)
+		}
+
+		if _, err := storage.GetTradingPair(ctx, "NOPE-USD"); !errors.Is(err, ErrPairNotFound) {
+			t.Errorf("Expected ErrPairNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Schedules the opening auction once", func(t *testing.T) {
+		pair, err := storage.ScheduleOpeningAuction(ctx, "PHS-USD")
+		if err != nil {
+			t.Fatalf("ScheduleOpeningAuction failed: %v", err)
+		}
+		if pair.HaltedUntil == nil || !pair.HaltedUntil.After(time.Now().Add(4*time.Minute)) || pair.AuctionEnded || pair.OpeningAuctionSeconds != 0 {
+			t.Errorf("Expected the opening auction to end in five minutes, got %+v", pair)
+		}
+
+		if _, err := storage.ScheduleOpeningAuction(ctx, "PHS-USD"); !errors.Is(err, ErrTradingPhase) {
+			t.Errorf("Expected ErrTradingPhase, got %v", err)
+		}
+	})
+
+	t.Run("Schedules the end of the auction", func(t *testing.T) {
+		if _, err := storage.StartAuction(ctx, "PHS-USD", -time.Second); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+
+		pair, err := storage.StartAuction(ctx, "PHS-USD", time.Minute)
+		if err != nil {
+			t.Fatalf("StartAuction failed: %v", err)
+		}
+		if pair.HaltedUntil == nil || !pair.HaltedUntil.After(time.Now()) || pair.AuctionEnded {
+			t.Errorf("Expected the auction to end in a minute, got %+v", pair)
+		}
+	})
+
+	t.Run("Uncrosses the book it was worked out from", func(t *testing.T) {
+		if pair, err := storage.EndAuction(ctx, "PHS-USD"); err != nil || !pair.AuctionEnded {
+			t.Fatalf("Expected EndAuction to end the auction, got %+v, %v", pair, err)
+		}
+
+		lastUpdateID, err := storage.GetLastUpdateID(ctx, "PHS-USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		auction := TradingPhaseChange{Price: 100, Volume: 5, Reference: 100}
+
+		if err := storage.StartUncross(ctx, "PHS-USD", lastUpdateID+1, auction); !errors.Is(err, ErrStaleMatch) {
+			t.Errorf("Expected ErrStaleMatch for a changed book, got %v", err)
+		}
+		if err := storage.StartUncross(ctx, "PHS-USD", lastUpdateID, auction); err != nil {
+			t.Fatalf("StartUncross failed: %v", err)
+		}
+		if pair := phase(); pair.Phase != PhaseUncrossing || pair.AuctionPrice != 100 {
+			t.Errorf("Expected the pair to uncross at 100, got %+v", pair)
+		}
+
+		if _, err := storage.StartAuction(ctx, "PHS-USD", 0); !errors.Is(err, ErrTradingPhase) {
+			t.Errorf("Expected ErrTradingPhase starting an auction mid-uncross, got %v", err)
+		}
+	})
+
+	t.Run("Returns to continuous matching", func(t *testing.T) {
+		if err := storage.FinishUncross(ctx, "PHS-USD"); err != nil {
+			t.Fatalf("FinishUncross failed: %v", err)
+		}
+		if pair := phase(); pair.Phase != PhaseContinuous || pair.AuctionPrice != 0 || pair.HaltedUntil != nil {
+			t.Errorf("Expected continuous matching, got %+v", pair)
+		}
+
+		if err := storage.FinishUncross(ctx, "PHS-USD"); !errors.Is(err, ErrTradingPhase) {
+			t.Errorf("Expected ErrTradingPhase, got %v", err)
+		}
+		if _, err := storage.EndAuction(ctx, "PHS-USD"); !errors.Is(err, ErrTradingPhase) {
+			t.Errorf("Expected ErrTradingPhase, got %v", err)
+		}
+	})
+
+	t.Run("Journals every change", func(t *testing.T) {
+		events, err := storage.GetJournal(ctx, JournalQuery{Symbol: "PHS-USD", Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var phases []TradingPhase
+		for _, e := range events {
+			change, err := e.TradingPhaseChange()
+			if err != nil {
+				t.Fatal(err)
+			}
+			phases = append(phases, change.Phase)
+		}
+
+		want := []TradingPhase{PhaseAuction, PhaseAuction, PhaseAuction, PhaseUncrossing, PhaseContinuous}
+		if len(phases) != len(want) {
+			t.Fatalf("Expected phases %v, got %v", want, phases)
+		}
+		for i := range want {
+			if phases[i] != want[i] {
+				t.Errorf("Expected phases %v, got %v", want, phases)
+				break
+			}
+		}
+	})
+}
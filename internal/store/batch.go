@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// MaxBatchSize is the most orders one batch can place or cancel.
+const MaxBatchSize = 50
+
+// ErrBatchRejected is returned by an all-or-nothing batch in which some item
+// failed. Nothing in the batch was applied; the per-item errors say why.
+var ErrBatchRejected = errors.New("batch rejected")
+
+func validateBatchSize(n int) error {
+	if n == 0 {
+		return fmt.Errorf("batch is empty: %w", ErrValidation)
+	}
+	if n > MaxBatchSize {
+		return fmt.Errorf("batch cannot hold more than %d orders: %w", MaxBatchSize, ErrValidation)
+	}
+	return nil
+}
+
+// isItemError reports whether err belongs to one batch item rather than to
+// the batch as a whole.
+func isItemError(err error) bool {
+	return errors.Is(err, ErrValidation) || errors.Is(err, ErrDuplicateClientOrderID) || errors.Is(err, ErrOrderNotFound)
+}
+
+// CreateOrders places a batch of orders in one transaction, returning one error per order.
+// Like CreateOrder, it does not reserve funds.
+func (s *Storage) CreateOrders(ctx context.Context, orders []Order, allOrNothing bool) ([]*Order, []error, error) {
+	if err := validateBatchSize(len(orders)); err != nil {
+		return nil, nil, err
+	}
+
+	symbols := []string{}
+	for _, o := range orders {
+		symbols = append(symbols, o.Symbol)
+	}
+
+	lock := func(tx DBTX) error {
+		return lockBooks(ctx, tx, symbols...)
+	}
+
+	return runBatch(ctx, s, len(orders), allOrNothing, lock, func(inTx *Storage, i int) (*Order, error) {
+		return inTx.CreateOrder(ctx, orders[i])
+	})
+}
+
+// CancelOrders cancels a batch of the user's orders in one transaction, with
+// the same per-item errors and all-or-nothing behaviour as CreateOrders.
+func (s *Storage) CancelOrders(ctx context.Context, orderIDs []string, userID string, allOrNothing bool) ([]*Order, []error, error) {
+	if err := validateBatchSize(len(orderIDs)); err != nil {
+		return nil, nil, err
+	}
+
+	ids := []string{}
+	for _, id := range orderIDs {
+		if isUUID(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	lock := func(tx DBTX) error {
+		query := `SELECT DISTINCT symbol FROM orders WHERE id = ANY($1::uuid[]) AND user_id = $2`
+
+		rows, err := tx.Query(ctx, query, ids, userID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch batch symbols: %w", err)
+		}
+
+		symbols := []string{}
+
+		for rows.Next() {
+			var symbol string
+			if err := rows.Scan(&symbol); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to fetch batch symbol: %w", err)
+			}
+			symbols = append(symbols, symbol)
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return lockBooks(ctx, tx, symbols...)
+	}
+
+	return runBatch(ctx, s, len(orderIDs), allOrNothing, lock, func(inTx *Storage, i int) (*Order, error) {
+		return inTx.CancelOrder(ctx, orderIDs[i], userID)
+	})
+}
+
+// lockBooks takes the book locks of every symbol in a fixed order.
+func lockBooks(ctx context.Context, db DBTX, symbols ...string) error {
+	symbols = slices.Clone(symbols)
+	slices.Sort(symbols)
+
+	for _, symbol := range slices.Compact(symbols) {
+		if err := lockSequence(ctx, db, symbol); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runBatch(ctx context.Context, s *Storage, n int, allOrNothing bool, lock func(tx DBTX) error, apply func(inTx *Storage, i int) (*Order, error)) ([]*Order, []error, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := lock(tx); err != nil {
+		return nil, nil, err
+	}
+
+	inTx := &Storage{db: tx}
+
+	results := make([]*Order, n)
+	errs := make([]error, n)
+	failed := false
+
+	for i := range n {
+		order, err := apply(inTx, i)
+
+		if err != nil && !isItemError(err) {
+			return nil, nil, err
+		}
+
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+
+		results[i] = order
+	}
+
+	if failed && allOrNothing {
+		return nil, errs, ErrBatchRejected
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return results, errs, nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestOrderBatches(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "batch_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	quote := func(side string, price float64) Order {
+		return Order{UserID: u.ID, Symbol: "BAT-USD", Side: side, Price: price, Quantity: 1}
+	}
+
+	t.Run("Rejects empty and oversized batches", func(t *testing.T) {
+		if _, _, err := storage.CreateOrders(ctx, nil, false); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+		if _, _, err := storage.CreateOrders(ctx, make([]Order, MaxBatchSize+1), false); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+	})
+
+	var placed []*Order
+
+	t.Run("Places valid orders and reports the rest", func(t *testing.T) {
+		orders, errs, err := storage.CreateOrders(ctx, []Order{quote("BUY", 99), quote("HOLD", 99), quote("SELL", 101)}, false)
+		if err != nil {
+			t.Fatalf("CreateOrders failed: %v", err)
+		}
+
+		if orders[0] == nil || orders[2] == nil || errs[0] != nil || errs[2] != nil {
+			t.Errorf("Expected the valid orders placed, got %+v %v", orders, errs)
+		}
+		if orders[1] != nil || !errors.Is(errs[1], ErrValidation) {
+			t.Errorf("Expected the invalid order rejected, got %+v %v", orders[1], errs[1])
+		}
+
+		placed = []*Order{orders[0], orders[2]}
+	})
+
+	t.Run("All or nothing places nothing on a bad item", func(t *testing.T) {
+		before, err := storage.GetOrderBookDepth(ctx, BookQuery{Symbol: "BAT-USD", Depth: FullBookDepth})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, errs, err := storage.CreateOrders(ctx, []Order{quote("BUY", 98), quote("BUY", -1)}, true)
+		if !errors.Is(err, ErrBatchRejected) || errs[0] != nil || !errors.Is(errs[1], ErrValidation) {
+			t.Fatalf("Expected ErrBatchRejected blaming the second order, got %v %v", err, errs)
+		}
+
+		after, err := storage.GetOrderBookDepth(ctx, BookQuery{Symbol: "BAT-USD", Depth: FullBookDepth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(after.Bids) != len(before.Bids) {
+			t.Errorf("Expected no new bids, got %+v", after.Bids)
+		}
+	})
+
+	t.Run("Cancels a batch with per-item results", func(t *testing.T) {
+		ids := []string{placed[0].ID, "not-an-id", placed[1].ID}
+
+		cancelled, errs, err := storage.CancelOrders(ctx, ids, u.ID, false)
+		if err != nil {
+			t.Fatalf("CancelOrders failed: %v", err)
+		}
+
+		if cancelled[0].Status != "CANCELLED" || cancelled[2].Status != "CANCELLED" {
+			t.Errorf("Expected both orders cancelled, got %+v", cancelled)
+		}
+		if !errors.Is(errs[1], ErrOrderNotFound) {
+			t.Errorf("Expected ErrOrderNotFound for the bad ID, got %v", errs[1])
+		}
+	})
+}
+
+func TestOrderBatches_OppositeOrder(t *testing.T) {
+	pool := testutils.SetupTestPool(t)
+	storage := NewStorageFromPool(pool)
+	ctx := context.Background()
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	first, second := "BTA-"+suffix, "BTB-"+suffix
+
+	u, err := storage.CreateUser(ctx, &User{Username: "batch_race_" + suffix, PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		symbols := []string{first, second}
+
+		for _, c := range []struct {
+			query string
+			args  []any
+		}{
+			{`DELETE FROM engine_events WHERE symbol = ANY($1)`, []any{symbols}},
+			{`DELETE FROM book_sequences WHERE symbol = ANY($1)`, []any{symbols}},
+			{`DELETE FROM orders WHERE symbol = ANY($1)`, []any{symbols}},
+			{`DELETE FROM users WHERE id = $1`, []any{u.ID}},
+		} {
+			if _, err := pool.Exec(ctx, c.query, c.args...); err != nil {
+				t.Logf("Failed to clean up: %v", err)
+			}
+		}
+	})
+
+	quote := func(symbol string) Order {
+		return Order{UserID: u.ID, Symbol: symbol, Side: "BUY", Price: 100, Quantity: 1}
+	}
+
+	// Each batch places into both books, the two in opposite orders. Without
+	// the up-front locks they deadlock.
+	errs := make(chan error, 2)
+
+	for _, batch := range [][]Order{{quote(first), quote(second)}, {quote(second), quote(first)}} {
+		go func() {
+			for range 20 {
+				if _, _, err := storage.CreateOrders(ctx, batch, true); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Errorf("Batch failed: %v", err)
+		}
+	}
+}
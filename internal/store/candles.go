@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Intervals lists every candle width kept in the candles table.
+var Intervals = []Interval{Minute, FiveMinutes, FifteenMinutes, Hour, FourHours, Day, Week, Month}
+
+type IntervalCandle struct {
+	Interval Interval `json:"interval"`
+	Candle
+}
+
+// UpdateCandles folds a trade into every interval's candle, in print order.
+func (s *Storage) UpdateCandles(ctx context.Context, trade *Trade) ([]IntervalCandle, error) {
+	args := []any{trade.Symbol, trade.Price, trade.Quantity}
+	values := make([]string, 0, len(Intervals))
+
+	for _, interval := range Intervals {
+		args = append(args, string(interval), interval.Truncate(trade.Timestamp))
+		values = append(values, fmt.Sprintf("($1, $%d, $%d, $2, $2, $2, $2, $3)", len(args)-1, len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO candles (symbol, period, bucket, open, high, low, close, volume)
+		VALUES %s
+		ON CONFLICT (symbol, period, bucket) DO UPDATE SET
+			high = GREATEST(candles.high, EXCLUDED.high),
+			low = LEAST(candles.low, EXCLUDED.low),
+			close = EXCLUDED.close,
+			volume = candles.volume + EXCLUDED.volume
+		RETURNING period, bucket, open, high, low, close, volume
+	`, strings.Join(values, ", "))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update candles: %w", err)
+	}
+	defer rows.Close()
+
+	updated := []IntervalCandle{}
+
+	for rows.Next() {
+		var c IntervalCandle
+
+		if err := rows.Scan(&c.Interval, &c.Time, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume); err != nil {
+			return nil, fmt.Errorf("failed to scan candle: %w", err)
+		}
+
+		c.Time = c.Time.UTC()
+		updated = append(updated, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to update candles: %w", err)
+	}
+
+	return updated, nil
+}
+
+// BackfillCandles rebuilds the candles table from the full trade history.
+func (s *Storage) BackfillCandles(ctx context.Context) error {
+	tx, err := s.db.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM candles"); err != nil {
+		return fmt.Errorf("failed to clear candles: %w", err)
+	}
+
+	for _, interval := range Intervals {
+		bucket, err := interval.bucketSQL("t.timestamp")
+		if err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`
+			INSERT INTO candles (symbol, period, bucket, open, high, low, close, volume)
+			SELECT
+				o.symbol,
+				$1::varchar,
+				%s AS bucket,
+				(array_agg(t.price ORDER BY t.timestamp ASC, t.id ASC))[1],
+				MAX(t.price),
+				MIN(t.price),
+				(array_agg(t.price ORDER BY t.timestamp DESC, t.id DESC))[1],
+				SUM(t.quantity)
+			FROM trades t
+			JOIN orders o ON t.bid_order_id = o.id
+			GROUP BY o.symbol, 3
+		`, bucket)
+
+		if _, err := tx.Exec(ctx, query, string(interval)); err != nil {
+			return fmt.Errorf("failed to backfill %s candles: %w", interval, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestUpdateCandles(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	s := NewStorage(tx)
+	ctx := context.Background()
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	trades := []Trade{
+		{Symbol: "AGG-USD", Price: 100, Quantity: 1, Timestamp: baseTime.Add(5 * time.Second)},
+		{Symbol: "AGG-USD", Price: 150, Quantity: 2, Timestamp: baseTime.Add(20 * time.Second)},
+		{Symbol: "AGG-USD", Price: 90, Quantity: 3, Timestamp: baseTime.Add(40 * time.Second)},
+		{Symbol: "AGG-USD", Price: 120, Quantity: 1, Timestamp: baseTime.Add(70 * time.Second)},
+	}
+
+	for i := range trades {
+		updated, err := s.UpdateCandles(ctx, &trades[i])
+		if err != nil {
+			t.Fatalf("UpdateCandles failed: %v", err)
+		}
+		if len(updated) != len(Intervals) {
+			t.Fatalf("Expected %d updated candles, got %d", len(Intervals), len(updated))
+		}
+	}
+
+	minutes, err := s.GetKlineRange(ctx, KlineQuery{Symbol: "AGG-USD", Interval: Minute, StartTime: baseTime, EndTime: baseTime.Add(2 * time.Minute), Limit: 10, Ascending: true})
+	if err != nil {
+		t.Fatalf("GetKlineRange failed: %v", err)
+	}
+
+	if len(minutes) != 2 {
+		t.Fatalf("Expected 2 minute candles, got %d", len(minutes))
+	}
+
+	first := minutes[0]
+	if first.Open != 100 || first.High != 150 || first.Low != 90 || first.Close != 90 || first.Volume != 6 {
+		t.Errorf("10:00 candle wrong: %+v", first)
+	}
+
+	hours, err := s.GetKlines(ctx, "AGG-USD", Hour, 10)
+	if err != nil {
+		t.Fatalf("GetKlines failed: %v", err)
+	}
+
+	if len(hours) != 1 || hours[0].Close != 120 || hours[0].Volume != 7 {
+		t.Errorf("Hourly candle should aggregate all four trades, got %+v", hours)
+	}
+}
+
+func TestBackfillCandles(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	s := NewStorage(tx)
+	ctx := context.Background()
+
+	var bidID, askID string
+
+	err := tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('FILL-USD', 'BUY', 1, 1, 'FILLED') RETURNING id`).Scan(&bidID)
+	if err != nil {
+		t.Fatal("Failed to create dummy Buy Order:", err)
+	}
+
+	err = tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('FILL-USD', 'SELL', 1, 1, 'FILLED') RETURNING id`).Scan(&askID)
+	if err != nil {
+		t.Fatal("Failed to create dummy Sell Order:", err)
+	}
+
+	// A Sunday and the following Monday land in different ISO weeks.
+	sunday := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	for i, price := range []float64{10, 20} {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO trades (bid_order_id, ask_order_id, price, quantity, timestamp)
+			VALUES ($1, $2, $3, 1, $4)
+		`, bidID, askID, price, sunday.Add(time.Duration(i)*24*time.Hour))
+		if err != nil {
+			t.Fatal("Failed to seed trade:", err)
+		}
+	}
+
+	if err := s.BackfillCandles(ctx); err != nil {
+		t.Fatalf("BackfillCandles failed: %v", err)
+	}
+
+	weeks, err := s.GetKlines(ctx, "FILL-USD", Week, 10)
+	if err != nil {
+		t.Fatalf("GetKlines failed: %v", err)
+	}
+
+	if len(weeks) != 2 {
+		t.Fatalf("Expected 2 weekly candles, got %d", len(weeks))
+	}
+
+	if want := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC); !weeks[0].Time.Equal(want) {
+		t.Errorf("Latest week should start Monday %v, got %v", want, weeks[0].Time)
+	}
+
+	months, err := s.GetKlines(ctx, "FILL-USD", Month, 10)
+	if err != nil {
+		t.Fatalf("GetKlines failed: %v", err)
+	}
+
+	if len(months) != 1 || months[0].Open != 10 || months[0].Close != 20 {
+		t.Errorf("Monthly candle wrong: %+v", months)
+	}
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Fill struct {
+	TradeID   string    `json:"trade_id"`
+	OrderID   string    `json:"order_id"`
+	Symbol    string    `json:"symbol"`
+	Side      string    `json:"side"`
+	Price     float64   `json:"price"`
+	Quantity  int       `json:"quantity"`
+	Fee       float64   `json:"fee"`
+	FeeAsset  string    `json:"fee_asset"`
+	Liquidity string    `json:"liquidity"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FillQuery filters a user's fills, newest first, or oldest first from After.
+type FillQuery struct {
+	UserID    string
+	Symbol    string
+	OrderID   string
+	StartTime time.Time
+	EndTime   time.Time
+	Cursor    string
+	After     string
+	Limit     int
+}
+
+const fillColumns = `
+	t.id, o.id, o.symbol, o.side, t.price, t.quantity,
+	CASE WHEN t.taker_side = o.side THEN t.taker_fee ELSE t.maker_fee END AS fee,
+	COALESCE(p.quote_asset, '') AS fee_asset,
+	CASE WHEN t.taker_side = o.side THEN 'TAKER' ELSE 'MAKER' END AS liquidity,
+	t.timestamp
+`
+
+// fillCursor is the (timestamp, trade id, order id) of the last fill on a page.
+type fillCursor struct {
+	Timestamp time.Time
+	TradeID   string
+	OrderID   string
+}
+
+// Cursor returns an opaque position for this fill, usable as FillQuery.Cursor
+// or FillQuery.After.
+func (f Fill) Cursor() string {
+	raw := strings.Join([]string{f.Timestamp.UTC().Format(time.RFC3339Nano), f.TradeID, f.OrderID}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFillCursor(cursor string) (*fillCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", ErrValidation)
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid cursor: %w", ErrValidation)
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", ErrValidation)
+	}
+
+	if !isUUID(parts[1]) || !isUUID(parts[2]) {
+		return nil, fmt.Errorf("invalid cursor: %w", ErrValidation)
+	}
+
+	return &fillCursor{Timestamp: ts.UTC(), TradeID: parts[1], OrderID: parts[2]}, nil
+}
+
+// GetUserFills returns one page of the user's executions, newest first, and
+// the cursor for the next page ("" when there are no more rows).
+func (s *Storage) GetUserFills(ctx context.Context, q FillQuery) ([]Fill, string, error) {
+	fills := []Fill{}
+
+	if q.Limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive: %w", ErrValidation)
+	}
+
+	conditions := []string{"o.user_id = $1"}
+	args := []any{q.UserID}
+
+	addCondition := func(format string, values ...any) {
+		placeholders := make([]any, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf(format, placeholders...))
+	}
+
+	if q.Symbol != "" {
+		addCondition("o.symbol = %s", q.Symbol)
+	}
+	if q.OrderID != "" {
+		if !isUUID(q.OrderID) {
+			return nil, "", fmt.Errorf("order_id must be a UUID: %w", ErrValidation)
+		}
+		addCondition("o.id = %s", q.OrderID)
+	}
+	if !q.StartTime.IsZero() {
+		addCondition("t.timestamp >= %s", q.StartTime.UTC())
+	}
+	if !q.EndTime.IsZero() {
+		addCondition("t.timestamp < %s", q.EndTime.UTC())
+	}
+	if q.Cursor != "" && q.After != "" {
+		return nil, "", fmt.Errorf("cursor and after cannot be combined: %w", ErrValidation)
+	}
+	if q.Cursor != "" {
+		c, err := decodeFillCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		addCondition("(t.timestamp, t.id, o.id) < (%s::timestamp, %s::uuid, %s::uuid)", c.Timestamp, c.TradeID, c.OrderID)
+	}
+
+	order := "DESC"
+	if q.After != "" {
+		c, err := decodeFillCursor(q.After)
+		if err != nil {
+			return nil, "", err
+		}
+		addCondition("(t.timestamp, t.id, o.id) > (%s::timestamp, %s::uuid, %s::uuid)", c.Timestamp, c.TradeID, c.OrderID)
+		order = "ASC"
+	}
+
+	args = append(args, q.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM trades t
+		JOIN orders o ON o.id IN (t.bid_order_id, t.ask_order_id)
+		LEFT JOIN trading_pairs p ON p.symbol = o.symbol
+		WHERE %s
+		ORDER BY t.timestamp %s, t.id %s, o.id %s
+		LIMIT $%d
+	`, fillColumns, strings.Join(conditions, " AND "), order, order, order, len(args))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch fills: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f := Fill{}
+
+		if err := scanFill(rows, &f); err != nil {
+			return nil, "", err
+		}
+
+		fills = append(fills, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("row iteration error: %w", err)
+	}
+
+	nextCursor := ""
+	if len(fills) > q.Limit {
+		fills = fills[:q.Limit]
+		nextCursor = fills[len(fills)-1].Cursor()
+	}
+
+	return fills, nextCursor, nil
+}
+
+// GetFill returns one side of a trade as seen by the owner of orderID.
+func (s *Storage) GetFill(ctx context.Context, tradeID, orderID string) (*Fill, error) {
+	f := Fill{}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM trades t
+		JOIN orders o ON o.id IN (t.bid_order_id, t.ask_order_id)
+		LEFT JOIN trading_pairs p ON p.symbol = o.symbol
+		WHERE t.id = $1 AND o.id = $2
+	`, fillColumns)
+
+	if err := scanFill(s.db.QueryRow(ctx, query, tradeID, orderID), &f); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// GetOrderFills returns every fill of one order, oldest first.
+func (s *Storage) GetOrderFills(ctx context.Context, orderID string) ([]Fill, error) {
+	fills := []Fill{}
+
+	if !isUUID(orderID) {
+		return nil, ErrOrderNotFound
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM trades t
+		JOIN orders o ON o.id IN (t.bid_order_id, t.ask_order_id)
+		LEFT JOIN trading_pairs p ON p.symbol = o.symbol
+		WHERE o.id = $1
+		ORDER BY t.timestamp ASC, t.id ASC
+	`, fillColumns)
+
+	rows, err := s.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch fills: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f := Fill{}
+
+		if err := scanFill(rows, &f); err != nil {
+			return nil, err
+		}
+
+		fills = append(fills, f)
+	}
+
+	return fills, rows.Err()
+}
+
+func scanFill(row pgx.Row, f *Fill) error {
+	if err := row.Scan(
+		&f.TradeID,
+		&f.OrderID,
+		&f.Symbol,
+		&f.Side,
+		&f.Price,
+		&f.Quantity,
+		&f.Fee,
+		&f.FeeAsset,
+		&f.Liquidity,
+		&f.Timestamp,
+	); err != nil {
+		return fmt.Errorf("failed to scan fill: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestGetUserFills(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	maker, err := storage.CreateUser(ctx, &User{Username: "fills_maker", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create maker: %v", err)
+	}
+
+	taker, err := storage.CreateUser(ctx, &User{Username: "fills_taker", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create taker: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO trading_pairs (symbol, base_asset, quote_asset, is_active)
+		VALUES ('BTC-USD', 'BTC', 'USD', true)
+		ON CONFLICT (symbol) DO NOTHING
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var askID, bidID string
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO orders (user_id, symbol, side, price, quantity, status, created_at)
+		VALUES ($1, 'BTC-USD', 'SELL', 100, 3, 'PENDING', $2)
+		RETURNING id
+	`, maker.ID, baseTime).Scan(&askID)
+	if err != nil {
+		t.Fatal("Failed to create ask order:", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO orders (user_id, symbol, side, price, quantity, status, created_at)
+		VALUES ($1, 'BTC-USD', 'BUY', 100, 3, 'PENDING', $2)
+		RETURNING id
+	`, taker.ID, baseTime.Add(time.Second)).Scan(&bidID)
+	if err != nil {
+		t.Fatal("Failed to create bid order:", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := storage.CreateTrade(ctx, 100, 1, bidID, askID); err != nil {
+			t.Fatalf("Failed to create trade: %v", err)
+		}
+	}
+
+	t.Run("Taker sees fee and liquidity role", func(t *testing.T) {
+		fills, _, err := storage.GetUserFills(ctx, FillQuery{UserID: taker.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("GetUserFills failed: %v", err)
+		}
+
+		if len(fills) != 3 {
+			t.Fatalf("Expected 3 fills, got %d", len(fills))
+		}
+
+		f := fills[0]
+
+		if f.Liquidity != "TAKER" || f.Side != "BUY" || f.OrderID != bidID {
+			t.Errorf("Unexpected fill: %+v", f)
+		}
+		if f.Fee != 100*TakerFeeRate {
+			t.Errorf("Expected taker fee %v, got %v", 100*TakerFeeRate, f.Fee)
+		}
+		if f.FeeAsset != "USD" {
+			t.Errorf("Expected fee asset USD, got %s", f.FeeAsset)
+		}
+	})
+
+	t.Run("Maker sees only their side", func(t *testing.T) {
+		fills, _, err := storage.GetUserFills(ctx, FillQuery{UserID: maker.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("GetUserFills failed: %v", err)
+		}
+
+		if len(fills) != 3 {
+			t.Fatalf("Expected 3 fills, got %d", len(fills))
+		}
+
+		if fills[0].Liquidity != "MAKER" || fills[0].Fee != 100*MakerFeeRate {
+			t.Errorf("Unexpected maker fill: %+v", fills[0])
+		}
+	})
+
+	t.Run("Cursor pagination walks every fill once", func(t *testing.T) {
+		seen := map[string]bool{}
+		cursor := ""
+
+		for page := 0; page < 3; page++ {
+			fills, next, err := storage.GetUserFills(ctx, FillQuery{UserID: taker.ID, Limit: 2, Cursor: cursor})
+			if err != nil {
+				t.Fatalf("GetUserFills failed: %v", err)
+			}
+
+			for _, f := range fills {
+				if seen[f.TradeID] {
+					t.Errorf("Trade %s returned twice", f.TradeID)
+				}
+				seen[f.TradeID] = true
+			}
+
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		if len(seen) != 3 {
+			t.Errorf("Expected 3 distinct fills across pages, got %d", len(seen))
+		}
+	})
+
+	t.Run("Filters by symbol", func(t *testing.T) {
+		fills, _, err := storage.GetUserFills(ctx, FillQuery{UserID: taker.ID, Symbol: "ETH-USD", Limit: 10})
+		if err != nil {
+			t.Fatalf("GetUserFills failed: %v", err)
+		}
+
+		if len(fills) != 0 {
+			t.Errorf("Expected 0 ETH-USD fills, got %d", len(fills))
+		}
+	})
+
+	t.Run("Rejects a malformed cursor", func(t *testing.T) {
+		_, _, err := storage.GetUserFills(ctx, FillQuery{UserID: taker.ID, Limit: 10, Cursor: "not-a-cursor"})
+
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+	})
+
+	t.Run("After resumes forward from a fill", func(t *testing.T) {
+		all, _, err := storage.GetUserFills(ctx, FillQuery{UserID: taker.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("GetUserFills failed: %v", err)
+		}
+
+		oldest := all[len(all)-1]
+
+		fills, _, err := storage.GetUserFills(ctx, FillQuery{UserID: taker.ID, Limit: 10, After: oldest.Cursor()})
+		if err != nil {
+			t.Fatalf("GetUserFills failed: %v", err)
+		}
+
+		if len(fills) != 2 {
+			t.Fatalf("Expected 2 fills after the oldest, got %d", len(fills))
+		}
+		if fills[0].TradeID != all[1].TradeID || fills[1].TradeID != all[0].TradeID {
+			t.Errorf("Expected fills oldest first, got %+v", fills)
+		}
+	})
+
+	t.Run("GetFill returns one side of a trade", func(t *testing.T) {
+		all, _, err := storage.GetUserFills(ctx, FillQuery{UserID: maker.ID, Limit: 1})
+		if err != nil {
+			t.Fatalf("GetUserFills failed: %v", err)
+		}
+
+		fill, err := storage.GetFill(ctx, all[0].TradeID, askID)
+		if err != nil {
+			t.Fatalf("GetFill failed: %v", err)
+		}
+
+		if *fill != all[0] {
+			t.Errorf("Expected %+v, got %+v", all[0], *fill)
+		}
+	})
+
+	t.Run("Fully traded orders are FILLED", func(t *testing.T) {
+		order, err := storage.GetOrder(ctx, bidID)
+		if err != nil {
+			t.Fatalf("GetOrder failed: %v", err)
+		}
+
+		if order.Status != "FILLED" || order.Quantity != 0 || order.FilledQuantity != 3 {
+			t.Errorf("Expected a FILLED order with 3 filled, got %+v", order)
+		}
+	})
+}
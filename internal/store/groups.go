@@ -0,0 +1,414 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GroupType is the kind of order group.
+type GroupType string
+
+const (
+	// GroupOCO is a take-profit limit and a stop-loss, where one trading or
+	// triggering cancels the other.
+	GroupOCO GroupType = "OCO"
+	// GroupBracket is an entry order whose fill activates an OCO exit pair.
+	GroupBracket GroupType = "BRACKET"
+)
+
+// GroupRole is the part one order plays in its group.
+type GroupRole string
+
+const (
+	RoleEntry      GroupRole = "ENTRY"
+	RoleTakeProfit GroupRole = "TAKE_PROFIT"
+	RoleStopLoss   GroupRole = "STOP_LOSS"
+)
+
+var ErrOrderGroupNotFound = errors.New("order group not found")
+
+// OrderGroup links the legs of an OCO or bracket order.
+type OrderGroup struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Symbol    string    `json:"symbol"`
+	Type      GroupType `json:"type"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	Orders    []Order   `json:"orders,omitempty"`
+	// UpdateID and Depth are those of the cancel, on a group returned by
+	// CancelOrderGroup.
+	UpdateID int64        `json:"-"`
+	Depth    *DepthChange `json:"-"`
+}
+
+// OrderGroupRequest describes a new group; side and quantity are as for OrderGroupParams.
+type OrderGroupRequest struct {
+	UserID   string
+	Symbol   string
+	Type     GroupType
+	Side     string
+	Quantity int
+	// EntryPrice is the limit price of a bracket's entry.
+	EntryPrice float64
+	// TakeProfitPrice is the limit price of the take-profit exit.
+	TakeProfitPrice float64
+	// StopPrice triggers the stop-loss exit, which then rests at
+	// StopLimitPrice, or at StopPrice if that is zero.
+	StopPrice      float64
+	StopLimitPrice float64
+	STPMode        STPMode
+}
+
+// legs builds the group's orders, in the order they are placed.
+func (r OrderGroupRequest) legs() ([]Order, error) {
+	exitSide := OrderSide(r.Side)
+
+	switch r.Type {
+	case GroupOCO:
+		if r.EntryPrice != 0 {
+			return nil, fmt.Errorf("entry_price is only allowed on BRACKET groups: %w", ErrValidation)
+		}
+	case GroupBracket:
+		if r.EntryPrice <= 0 {
+			return nil, fmt.Errorf("entry_price must be positive: %w", ErrValidation)
+		}
+		exitSide = Sell
+		if OrderSide(r.Side) == Sell {
+			exitSide = Buy
+		}
+	default:
+		return nil, fmt.Errorf("type must be OCO or BRACKET: %w", ErrValidation)
+	}
+
+	if OrderSide(r.Side) != Buy && OrderSide(r.Side) != Sell {
+		return nil, fmt.Errorf("side must be BUY or SELL: %w", ErrValidation)
+	}
+
+	// A take-profit sells above the stop, or buys below it; a bracket's
+	// entry sits between the two.
+	low, high := r.StopPrice, r.TakeProfitPrice
+	if exitSide == Buy {
+		low, high = high, low
+	}
+	if low >= high {
+		return nil, fmt.Errorf("take_profit_price must be on the other side of stop_price from the stop-loss: %w", ErrValidation)
+	}
+	if r.Type == GroupBracket && (r.EntryPrice <= low || r.EntryPrice >= high) {
+		return nil, fmt.Errorf("entry_price must be between take_profit_price and stop_price: %w", ErrValidation)
+	}
+
+	stopLimit := r.StopLimitPrice
+	if stopLimit == 0 {
+		stopLimit = r.StopPrice
+	}
+
+	leg := func(role GroupRole, side OrderSide, price float64, status string) Order {
+		return Order{
+			UserID:    r.UserID,
+			Symbol:    r.Symbol,
+			Side:      string(side),
+			Price:     price,
+			Quantity:  r.Quantity,
+			Status:    status,
+			Type:      Limit,
+			GroupRole: role,
+			STPMode:   r.STPMode,
+		}
+	}
+
+	var legs []Order
+
+	// A bracket's exits wait off the book until the entry fills.
+	exitStatus := "PENDING"
+	if r.Type == GroupBracket {
+		legs = append(legs, leg(RoleEntry, OrderSide(r.Side), r.EntryPrice, "PENDING"))
+		exitStatus = "WAITING"
+	}
+
+	stop := leg(RoleStopLoss, exitSide, stopLimit, "WAITING")
+	stop.Type, stop.StopPrice = StopLimit, r.StopPrice
+
+	return append(legs, leg(RoleTakeProfit, exitSide, r.TakeProfitPrice, exitStatus), stop), nil
+}
+
+// CreateOrderGroup places every leg of an OCO or bracket order in one
+// transaction, under the book lock, and returns the group with its legs.
+func (s *Storage) CreateOrderGroup(ctx context.Context, r OrderGroupRequest) (*OrderGroup, error) {
+	legs, err := r.legs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, leg := range legs {
+		if err := s.validateOrder(leg); err != nil {
+			return nil, err
+		}
+	}
+
+	g := OrderGroup{UserID: r.UserID, Symbol: r.Symbol, Type: r.Type, Status: "ACTIVE"}
+	if r.Type == GroupBracket {
+		g.Status = "PENDING"
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := lockSequence(ctx, tx, g.Symbol); err != nil {
+		return nil, err
+	}
+
+	query := `
+    INSERT INTO order_groups (user_id, symbol, type, status)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id, created_at`
+
+	if err := tx.QueryRow(ctx, query, g.UserID, g.Symbol, string(g.Type), g.Status).Scan(&g.ID, &g.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create order group: %w", err)
+	}
+
+	if err := appendEvent(ctx, tx, g.Symbol, EventOrderGroupCreated, g); err != nil {
+		return nil, err
+	}
+
+	inTx := &Storage{db: tx}
+
+	for _, leg := range legs {
+		leg.GroupID = g.ID
+
+		placed, err := inTx.createOrder(ctx, leg)
+		if err != nil {
+			return nil, err
+		}
+
+		g.Orders = append(g.Orders, *placed)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// RestoreOrderGroup re-creates a group taken from the journal, before its
+// legs are restored.
+func (s *Storage) RestoreOrderGroup(ctx context.Context, g OrderGroup) error {
+	query := `
+    INSERT INTO order_groups (id, user_id, symbol, type, status, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := s.db.Exec(ctx, query, g.ID, g.UserID, g.Symbol, string(g.Type), g.Status, g.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to restore order group: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrderGroup returns one of the user's order groups with its legs.
+func (s *Storage) GetOrderGroup(ctx context.Context, groupID, userID string) (*OrderGroup, error) {
+	if !isUUID(groupID) || !isUUID(userID) {
+		return nil, ErrOrderGroupNotFound
+	}
+
+	g := OrderGroup{Orders: []Order{}}
+
+	query := `SELECT id, user_id, symbol, type, status, created_at FROM order_groups WHERE id = $1 AND user_id = $2`
+
+	err := s.db.QueryRow(ctx, query, groupID, userID).Scan(&g.ID, &g.UserID, &g.Symbol, &g.Type, &g.Status, &g.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch order group: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, `
+    SELECT `+orderColumns+`
+    FROM orders
+    WHERE group_id = $1
+    ORDER BY CASE group_role WHEN 'ENTRY' THEN 0 WHEN 'TAKE_PROFIT' THEN 1 ELSE 2 END`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order group legs: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var o Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, fmt.Errorf("failed to fetch order group leg: %w", err)
+		}
+		g.Orders = append(g.Orders, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// CancelOrderGroup cancels whatever is left of one of the user's groups by
+// cancelling one live leg, which takes the others with it.
+func (s *Storage) CancelOrderGroup(ctx context.Context, groupID, userID string) (*OrderGroup, error) {
+	if !isUUID(groupID) || !isUUID(userID) {
+		return nil, ErrOrderGroupNotFound
+	}
+
+	var orderID string
+
+	query := `
+    SELECT id FROM orders
+    WHERE group_id = $1 AND user_id = $2 AND status IN ('PENDING', 'WAITING')
+    ORDER BY created_at, id
+    LIMIT 1`
+
+	if err := s.db.QueryRow(ctx, query, groupID, userID).Scan(&orderID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch order group leg: %w", err)
+	}
+
+	cancelled, err := s.CancelOrder(ctx, orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := s.GetOrderGroup(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	g.UpdateID, g.Depth = cancelled.UpdateID, cancelled.Depth
+
+	return g, nil
+}
+
+// settleGroup applies a change to orderID to the rest of its group, returning the legs it changed.
+func settleGroup(ctx context.Context, db DBTX, orderID string) ([]Order, error) {
+	var groupID, symbol, groupStatus, role, status string
+
+	query := `
+    SELECT g.id, g.symbol, g.status, o.group_role, o.status
+    FROM orders o
+    JOIN order_groups g ON g.id = o.group_id
+    WHERE o.id = $1
+    FOR UPDATE OF g`
+
+	err := db.QueryRow(ctx, query, orderID).Scan(&groupID, &symbol, &groupStatus, &role, &status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch order group: %w", err)
+	}
+
+	next := ""
+
+	switch {
+	case groupStatus != "PENDING" && groupStatus != "ACTIVE":
+		return nil, nil
+	case status == "CANCELLED":
+		next = "CANCELLED"
+	case GroupRole(role) == RoleEntry:
+		if status != "FILLED" {
+			return nil, nil
+		}
+		return activateGroup(ctx, db, groupID, symbol)
+	case groupStatus == "ACTIVE":
+		next = "DONE"
+	default:
+		return nil, nil
+	}
+
+	if _, err := db.Exec(ctx, `UPDATE order_groups SET status = $2 WHERE id = $1`, groupID, next); err != nil {
+		return nil, fmt.Errorf("failed to update order group: %w", err)
+	}
+
+	rows, err := db.Query(ctx, `
+    UPDATE orders
+    SET status = 'CANCELLED'
+    WHERE group_id = $1 AND id <> $2 AND status IN ('PENDING', 'WAITING')
+    RETURNING `+orderColumns, groupID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel order group legs: %w", err)
+	}
+
+	var cancelled []Order
+
+	for rows.Next() {
+		var o Order
+		if err := scanOrder(rows, &o); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to cancel order group leg: %w", err)
+		}
+		cancelled = append(cancelled, o)
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, o := range cancelled {
+		change := OrderStatusChange{OrderID: o.ID, Status: o.Status, Quantity: o.Quantity, FilledQuantity: o.FilledQuantity}
+
+		if err := appendEvent(ctx, db, symbol, EventOrderStatusChanged, change); err != nil {
+			return nil, err
+		}
+
+		if err := appendOutbox(ctx, db, TopicCancels, o.ID, o.UserID, o); err != nil {
+			return nil, err
+		}
+
+		if err := appendOrderEvent(ctx, db, o.UserID, OrderEventOrder, o); err != nil {
+			return nil, err
+		}
+	}
+
+	return cancelled, nil
+}
+
+// activateGroup puts a bracket's take-profit on the book, at the back of its
+// price level, and arms its stop-loss. It returns the take-profit.
+func activateGroup(ctx context.Context, db DBTX, groupID, symbol string) ([]Order, error) {
+	if _, err := db.Exec(ctx, `UPDATE order_groups SET status = 'ACTIVE' WHERE id = $1`, groupID); err != nil {
+		return nil, fmt.Errorf("failed to activate order group: %w", err)
+	}
+
+	var o Order
+
+	query := `
+    UPDATE orders
+    SET status = 'PENDING', created_at = clock.ts, priority_at = clock.ts
+    FROM (SELECT clock_timestamp()::timestamp AS ts) clock
+    WHERE group_id = $1 AND group_role = 'TAKE_PROFIT' AND status = 'WAITING'
+    RETURNING ` + orderColumns
+
+	if err := scanOrder(db.QueryRow(ctx, query, groupID), &o); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to activate take-profit: %w", err)
+	}
+
+	if err := appendEvent(ctx, db, symbol, EventOrderActivated, o); err != nil {
+		return nil, err
+	}
+
+	if err := appendOrderEvent(ctx, db, o.UserID, OrderEventOrder, o); err != nil {
+		return nil, err
+	}
+
+	return []Order{o}, nil
+}
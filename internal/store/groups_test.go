@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestOrderGroups(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "group_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &User{Username: "group_counterparty", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	place := func(symbol, side string, price float64, qty int) *Order {
+		t.Helper()
+
+		o, err := storage.CreateOrder(ctx, Order{UserID: other.ID, Symbol: symbol, Side: side, Price: price, Quantity: qty})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+		return o
+	}
+
+	// trade prints a trade between two of other's orders, moving the last
+	// price of the symbol.
+	trade := func(symbol string, price float64) {
+		t.Helper()
+
+		buy, sell := place(symbol, "BUY", price, 1), place(symbol, "SELL", price, 1)
+		if _, err := storage.CreateTrade(ctx, price, 1, buy.ID, sell.ID); err != nil {
+			t.Fatalf("CreateTrade failed: %v", err)
+		}
+	}
+
+	status := func(id string) string {
+		t.Helper()
+
+		o, err := storage.GetOrder(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o.Status
+	}
+
+	groupStatus := func(id string) string {
+		t.Helper()
+
+		g, err := storage.GetOrderGroup(ctx, id, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g.Status
+	}
+
+	oco := func(symbol string) *OrderGroup {
+		t.Helper()
+
+		g, err := storage.CreateOrderGroup(ctx, OrderGroupRequest{
+			UserID: u.ID, Symbol: symbol, Type: GroupOCO, Side: "SELL", Quantity: 2,
+			TakeProfitPrice: 110, StopPrice: 90, StopLimitPrice: 89,
+		})
+		if err != nil {
+			t.Fatalf("CreateOrderGroup failed: %v", err)
+		}
+		return g
+	}
+
+	t.Run("Validates the group", func(t *testing.T) {
+		for _, r := range []OrderGroupRequest{
+			{Type: "OTO", Side: "SELL", Quantity: 1, TakeProfitPrice: 110, StopPrice: 90},
+			{Type: GroupOCO, Side: "SELL", Quantity: 1, TakeProfitPrice: 90, StopPrice: 110},
+			{Type: GroupOCO, Side: "BUY", Quantity: 1, TakeProfitPrice: 110, StopPrice: 90},
+			{Type: GroupBracket, Side: "BUY", Quantity: 1, TakeProfitPrice: 110, StopPrice: 90},
+			{Type: GroupBracket, Side: "BUY", Quantity: 1, EntryPrice: 120, TakeProfitPrice: 110, StopPrice: 90},
+		} {
+			r.UserID, r.Symbol = u.ID, "GRP-USD"
+
+			if _, err := storage.CreateOrderGroup(ctx, r); !errors.Is(err, ErrValidation) {
+				t.Errorf("%+v: expected ErrValidation, got %v", r, err)
+			}
+		}
+	})
+
+	t.Run("A take-profit fill cancels the stop-loss", func(t *testing.T) {
+		g := oco("OCO-USD")
+		takeProfit, stopLoss := g.Orders[0], g.Orders[1]
+
+		if g.Status != "ACTIVE" || takeProfit.Status != "PENDING" || stopLoss.Status != "WAITING" {
+			t.Fatalf("Expected an ACTIVE group with a resting take-profit and a waiting stop, got %+v", g)
+		}
+
+		bid := place("OCO-USD", "BUY", 110, 1)
+		if _, err := storage.CreateTrade(ctx, 110, 1, bid.ID, takeProfit.ID); err != nil {
+			t.Fatalf("CreateTrade failed: %v", err)
+		}
+
+		if got := status(stopLoss.ID); got != "CANCELLED" {
+			t.Errorf("Expected the stop-loss to be cancelled, got %s", got)
+		}
+		if got := status(takeProfit.ID); got != "PENDING" {
+			t.Errorf("Expected the rest of the take-profit to keep resting, got %s", got)
+		}
+		if got := groupStatus(g.ID); got != "DONE" {
+			t.Errorf("Expected the group to be DONE, got %s", got)
+		}
+	})
+
+	t.Run("A triggered stop-loss cancels the take-profit", func(t *testing.T) {
+		g := oco("STP-USD")
+		takeProfit, stopLoss := g.Orders[0], g.Orders[1]
+
+		trade("STP-USD", 91)
+
+		if triggered, err := storage.TriggerStop(ctx, "STP-USD"); err != nil || triggered != nil {
+			t.Fatalf("Expected nothing to trigger above the stop, got %+v, %v", triggered, err)
+		}
+
+		trade("STP-USD", 90)
+
+		triggered, err := storage.TriggerStop(ctx, "STP-USD")
+		if err != nil {
+			t.Fatalf("TriggerStop failed: %v", err)
+		}
+		if triggered == nil || triggered.ID != stopLoss.ID || triggered.Status != "PENDING" || triggered.Price != 89 {
+			t.Fatalf("Expected the stop-loss to rest at 89, got %+v", triggered)
+		}
+
+		if got := status(takeProfit.ID); got != "CANCELLED" {
+			t.Errorf("Expected the take-profit to be cancelled, got %s", got)
+		}
+		if got := groupStatus(g.ID); got != "DONE" {
+			t.Errorf("Expected the group to be DONE, got %s", got)
+		}
+	})
+
+	t.Run("A bracket's exits wait for the entry to fill", func(t *testing.T) {
+		g, err := storage.CreateOrderGroup(ctx, OrderGroupRequest{
+			UserID: u.ID, Symbol: "BRK-USD", Type: GroupBracket, Side: "BUY", Quantity: 2,
+			EntryPrice: 100, TakeProfitPrice: 110, StopPrice: 90,
+		})
+		if err != nil {
+			t.Fatalf("CreateOrderGroup failed: %v", err)
+		}
+
+		entry, takeProfit, stopLoss := g.Orders[0], g.Orders[1], g.Orders[2]
+
+		if g.Status != "PENDING" || entry.GroupRole != RoleEntry || takeProfit.Status != "WAITING" || takeProfit.Side != "SELL" {
+			t.Fatalf("Expected a PENDING bracket with its exits waiting, got %+v", g)
+		}
+
+		trade("BRK-USD", 90)
+
+		if triggered, err := storage.TriggerStop(ctx, "BRK-USD"); err != nil || triggered != nil {
+			t.Fatalf("Expected the stop-loss not to trigger before the entry fills, got %+v, %v", triggered, err)
+		}
+
+		ask := place("BRK-USD", "SELL", 100, 2)
+		if _, err := storage.CreateTrade(ctx, 100, 2, entry.ID, ask.ID); err != nil {
+			t.Fatalf("CreateTrade failed: %v", err)
+		}
+
+		if got := groupStatus(g.ID); got != "ACTIVE" {
+			t.Errorf("Expected the bracket to be ACTIVE, got %s", got)
+		}
+		if got := status(takeProfit.ID); got != "PENDING" {
+			t.Errorf("Expected the take-profit on the book, got %s", got)
+		}
+
+		cancelled, err := storage.CancelOrderGroup(ctx, g.ID, u.ID)
+		if err != nil {
+			t.Fatalf("CancelOrderGroup failed: %v", err)
+		}
+		if cancelled.Status != "CANCELLED" {
+			t.Errorf("Expected the group to be CANCELLED, got %s", cancelled.Status)
+		}
+		for _, o := range cancelled.Orders[1:] {
+			if o.Status != "CANCELLED" {
+				t.Errorf("Expected exit %s to be cancelled, got %s", o.GroupRole, o.Status)
+			}
+		}
+		if got := status(stopLoss.ID); got != "CANCELLED" {
+			t.Errorf("Expected the stop-loss to be cancelled, got %s", got)
+		}
+	})
+
+	t.Run("Hides groups from other users", func(t *testing.T) {
+		g := oco("HID-USD")
+
+		if _, err := storage.GetOrderGroup(ctx, g.ID, other.ID); !errors.Is(err, ErrOrderGroupNotFound) {
+			t.Errorf("Expected ErrOrderGroupNotFound, got %v", err)
+		}
+		if _, err := storage.CancelOrderGroup(ctx, g.ID, other.ID); !errors.Is(err, ErrOrderGroupNotFound) {
+			t.Errorf("Expected ErrOrderGroupNotFound, got %v", err)
+		}
+	})
+}
+
+func TestOrderGroups_CancelWhileFilling(t *testing.T) {
+	pool := testutils.SetupTestPool(t)
+	storage := NewStorageFromPool(pool)
+	ctx := context.Background()
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	symbol := "OCR-" + suffix
+
+	u, err := storage.CreateUser(ctx, &User{Username: "oco_race_" + suffix, PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &User{Username: "oco_race_other_" + suffix, PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		for _, c := range []struct {
+			query string
+			args  []any
+		}{
+			{`DELETE FROM trades WHERE bid_order_id IN (SELECT id FROM orders WHERE symbol = $1)`, []any{symbol}},
+			{`DELETE FROM candles WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM engine_events WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM book_sequences WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM orders WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM order_groups WHERE symbol = $1`, []any{symbol}},
+			{`DELETE FROM users WHERE id = ANY($1)`, []any{[]string{u.ID, other.ID}}},
+		} {
+			if _, err := pool.Exec(ctx, c.query, c.args...); err != nil {
+				t.Logf("Failed to clean up: %v", err)
+			}
+		}
+	})
+
+	// The stop-loss is cancelled while the take-profit fills. Each settles
+	// the group by cancelling the other leg; without the book lock on the
+	// cancel, the two deadlock.
+	for range 20 {
+		g, err := storage.CreateOrderGroup(ctx, OrderGroupRequest{
+			UserID: u.ID, Symbol: symbol, Type: GroupOCO, Side: "SELL", Quantity: 1,
+			TakeProfitPrice: 110, StopPrice: 90,
+		})
+		if err != nil {
+			t.Fatalf("CreateOrderGroup failed: %v", err)
+		}
+		takeProfit, stopLoss := g.Orders[0], g.Orders[1]
+
+		bid, err := storage.CreateOrder(ctx, Order{UserID: other.ID, Symbol: symbol, Side: "BUY", Price: 110, Quantity: 1})
+		if err != nil {
+			t.Fatalf("Failed to place bid: %v", err)
+		}
+
+		errs := make(chan error, 2)
+
+		go func() {
+			_, err := storage.CreateTrade(ctx, 110, 1, bid.ID, takeProfit.ID)
+			if errors.Is(err, ErrStaleMatch) {
+				err = nil
+			}
+			errs <- err
+		}()
+
+		go func() {
+			_, err := storage.CancelOrder(ctx, stopLoss.ID, u.ID)
+			if errors.Is(err, ErrOrderNotFound) {
+				err = nil
+			}
+			errs <- err
+		}()
+
+		for range 2 {
+			if err := <-errs; err != nil {
+				t.Fatalf("Expected the fill and the cancel to serialise, got %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotencyKeyTTL is how long a stored response is replayed. After that the
+// key can be used for a new request.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyLease is how long a claim on a key holds before another request may take it over.
+const IdempotencyLease = 30 * time.Second
+
+// MaxIdempotencyKeyLength bounds the keys clients may send.
+const MaxIdempotencyKeyLength = 255
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+// IdempotentResponse is the stored outcome of a request, replayed to retries.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// BeginIdempotentRequest claims key, or returns the response already stored under it.
+func (s *Storage) BeginIdempotentRequest(ctx context.Context, userID, key, requestHash string) (*IdempotentResponse, error) {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return nil, fmt.Errorf("idempotency key must be 1 to %d characters: %w", MaxIdempotencyKeyLength, ErrValidation)
+	}
+
+	claimQuery := `
+	INSERT INTO idempotency_keys (user_id, key, request_hash, locked_until)
+	VALUES ($1, $2, $3, NOW() + make_interval(secs => $5))
+	ON CONFLICT (user_id, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response = NULL,
+			locked_until = EXCLUDED.locked_until,
+			created_at = NOW()
+		WHERE idempotency_keys.created_at < NOW() - make_interval(secs => $4)
+		   OR (idempotency_keys.status_code IS NULL
+		       AND COALESCE(idempotency_keys.locked_until, '-infinity') < NOW())
+	RETURNING true
+	`
+
+	var claimed bool
+	err := s.db.QueryRow(ctx, claimQuery, userID, key, requestHash, IdempotencyKeyTTL.Seconds(), IdempotencyLease.Seconds()).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var storedHash string
+	var statusCode *int
+	resp := IdempotentResponse{}
+
+	query := `
+	SELECT request_hash, status_code, COALESCE(content_type, ''), COALESCE(response, '')
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`
+
+	if err := s.db.QueryRow(ctx, query, userID, key).Scan(&storedHash, &statusCode, &resp.ContentType, &resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to fetch idempotency key: %w", err)
+	}
+
+	if storedHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	if statusCode == nil {
+		return nil, ErrIdempotencyInProgress
+	}
+
+	resp.StatusCode = *statusCode
+
+	return &resp, nil
+}
+
+// CompleteIdempotentRequest stores the response to replay for key.
+func (s *Storage) CompleteIdempotentRequest(ctx context.Context, userID, key string, resp IdempotentResponse) error {
+	query := `
+	UPDATE idempotency_keys
+	SET status_code = $3, content_type = $4, response = $5, locked_until = NULL
+	WHERE user_id = $1 AND key = $2
+	`
+
+	if _, err := s.db.Exec(ctx, query, userID, key, resp.StatusCode, resp.ContentType, resp.Body); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey gives up an unfinished claim so the request can be
+// retried under the same key.
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`
+
+	if _, err := s.db.Exec(ctx, query, userID, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes keys older than IdempotencyKeyTTL,
+// which can no longer be replayed, and returns how many it deleted.
+func (s *Storage) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < NOW() - make_interval(secs => $1)`
+
+	tag, err := s.db.Exec(ctx, query, IdempotencyKeyTTL.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
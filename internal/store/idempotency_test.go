@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestIdempotentRequests(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "idempotent_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	t.Run("Claims a new key", func(t *testing.T) {
+		stored, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-1", "hash-a")
+		if err != nil || stored != nil {
+			t.Fatalf("Expected a fresh claim, got %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("Reports a request still in progress", func(t *testing.T) {
+		if _, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-1", "hash-a"); !errors.Is(err, ErrIdempotencyInProgress) {
+			t.Errorf("Expected ErrIdempotencyInProgress, got %v", err)
+		}
+	})
+
+	t.Run("Replays a completed response", func(t *testing.T) {
+		resp := IdempotentResponse{StatusCode: 202, ContentType: "application/json", Body: []byte(`{"ok":true}`)}
+
+		if err := storage.CompleteIdempotentRequest(ctx, u.ID, "key-1", resp); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-1", "hash-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored == nil || stored.StatusCode != 202 || string(stored.Body) != `{"ok":true}` || stored.ContentType != "application/json" {
+			t.Errorf("Expected the stored response, got %+v", stored)
+		}
+	})
+
+	t.Run("Rejects a different request under the same key", func(t *testing.T) {
+		if _, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-1", "hash-b"); !errors.Is(err, ErrIdempotencyKeyReused) {
+			t.Errorf("Expected ErrIdempotencyKeyReused, got %v", err)
+		}
+	})
+
+	t.Run("Releases an unfinished claim", func(t *testing.T) {
+		if _, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-2", "hash-a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.ReleaseIdempotencyKey(ctx, u.ID, "key-2"); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-2", "hash-b")
+		if err != nil || stored != nil {
+			t.Errorf("Expected the key to be free again, got %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("Takes over a claim whose lease ran out", func(t *testing.T) {
+		if _, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-3", "hash-a"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE idempotency_keys SET locked_until = NOW() - INTERVAL '1 second' WHERE user_id = $1 AND key = 'key-3'`, u.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := storage.BeginIdempotentRequest(ctx, u.ID, "key-3", "hash-a")
+		if err != nil || stored != nil {
+			t.Errorf("Expected the abandoned claim to be taken over, got %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("Deletes expired keys", func(t *testing.T) {
+		if _, err := tx.Exec(ctx, `UPDATE idempotency_keys SET created_at = created_at - INTERVAL '2 days' WHERE user_id = $1 AND key = 'key-1'`, u.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := storage.DeleteExpiredIdempotencyKeys(ctx); err != nil || n == 0 {
+			t.Fatalf("Expected expired keys to be deleted, got %d, %v", n, err)
+		}
+
+		var left int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM idempotency_keys WHERE user_id = $1`, u.ID).Scan(&left); err != nil {
+			t.Fatal(err)
+		}
+		if left != 2 {
+			t.Errorf("Expected the two live keys to be kept, got %d", left)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Journal event types, covering both the engine's inputs and its outputs.
+type JournalEventType string
+
+const (
+	EventOrderCreated        JournalEventType = "ORDER_CREATED"
+	EventOrderCancelled      JournalEventType = "ORDER_CANCELLED"
+	EventOrderAmended        JournalEventType = "ORDER_AMENDED"
+	EventTradeExecuted       JournalEventType = "TRADE_EXECUTED"
+	EventOrderStatusChanged  JournalEventType = "ORDER_STATUS_CHANGED"
+	EventSelfTradePrevented  JournalEventType = "SELF_TRADE_PREVENTED"
+	EventOrderReplenished    JournalEventType = "ORDER_REPLENISHED"
+	EventOrderGroupCreated   JournalEventType = "ORDER_GROUP_CREATED"
+	EventOrderTriggered      JournalEventType = "ORDER_TRIGGERED"
+	EventOrderActivated      JournalEventType = "ORDER_ACTIVATED"
+	EventTradingPhaseChanged JournalEventType = "TRADING_PHASE_CHANGED"
+)
+
+// JournalEvent is one entry in the engine journal; within a symbol Seq is commit order.
+type JournalEvent struct {
+	Seq       int64            `json:"seq"`
+	Symbol    string           `json:"symbol"`
+	Type      JournalEventType `json:"type"`
+	Payload   json.RawMessage  `json:"payload"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type OrderStatusChange struct {
+	OrderID        string `json:"order_id"`
+	Status         string `json:"status"`
+	Quantity       int    `json:"quantity"`
+	FilledQuantity int    `json:"filled_quantity"`
+}
+
+// OrderReplenishment records an iceberg showing its next slice, and the new
+// queue priority that came with it.
+type OrderReplenishment struct {
+	OrderID         string    `json:"order_id"`
+	VisibleQuantity int       `json:"visible_quantity"`
+	PriorityAt      time.Time `json:"priority_at"`
+}
+
+func (e JournalEvent) Order() (*Order, error) {
+	var o Order
+	if err := json.Unmarshal(e.Payload, &o); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.Seq, err)
+	}
+	return &o, nil
+}
+
+func (e JournalEvent) OrderGroup() (*OrderGroup, error) {
+	var g OrderGroup
+	if err := json.Unmarshal(e.Payload, &g); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.Seq, err)
+	}
+	return &g, nil
+}
+
+func (e JournalEvent) Trade() (*Trade, error) {
+	var t Trade
+	if err := json.Unmarshal(e.Payload, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.Seq, err)
+	}
+	return &t, nil
+}
+
+func (e JournalEvent) SelfTradePrevention() (*SelfTradePrevention, error) {
+	var p SelfTradePrevention
+	if err := json.Unmarshal(e.Payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.Seq, err)
+	}
+	return &p, nil
+}
+
+func (e JournalEvent) Replenishment() (*OrderReplenishment, error) {
+	var r OrderReplenishment
+	if err := json.Unmarshal(e.Payload, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.Seq, err)
+	}
+	return &r, nil
+}
+
+func (e JournalEvent) StatusChange() (*OrderStatusChange, error) {
+	var c OrderStatusChange
+	if err := json.Unmarshal(e.Payload, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.Seq, err)
+	}
+	return &c, nil
+}
+
+func (e JournalEvent) TradingPhaseChange() (*TradingPhaseChange, error) {
+	var c TradingPhaseChange
+	if err := json.Unmarshal(e.Payload, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.Seq, err)
+	}
+	return &c, nil
+}
+
+func appendEvent(ctx context.Context, db DBTX, symbol string, eventType JournalEventType, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	query := `INSERT INTO engine_events (symbol, type, payload) VALUES ($1, $2, $3)`
+
+	if _, err := db.Exec(ctx, query, symbol, string(eventType), data); err != nil {
+		return fmt.Errorf("failed to append %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// JournalQuery reads one symbol's journal after AfterSeq.
+type JournalQuery struct {
+	Symbol   string
+	AfterSeq int64
+	Limit    int
+}
+
+func (s *Storage) GetJournal(ctx context.Context, q JournalQuery) ([]JournalEvent, error) {
+	events := []JournalEvent{}
+
+	if q.Symbol == "" {
+		return nil, fmt.Errorf("symbol is required: %w", ErrValidation)
+	}
+	if q.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", ErrValidation)
+	}
+
+	query := `
+	SELECT seq, symbol, type, payload, created_at
+		FROM engine_events
+		WHERE symbol = $1 AND seq > $2
+		ORDER BY seq ASC
+		LIMIT $3
+	`
+
+	rows, err := s.db.Query(ctx, query, q.Symbol, q.AfterSeq, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var e JournalEvent
+		var eventType string
+
+		if err := rows.Scan(&e.Seq, &e.Symbol, &eventType, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to fetch journal event: %w", err)
+		}
+
+		e.Type = JournalEventType(eventType)
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// GetJournalSymbols lists every symbol with events in the journal.
+func (s *Storage) GetJournalSymbols(ctx context.Context) ([]string, error) {
+	symbols := []string{}
+
+	rows, err := s.db.Query(ctx, `SELECT DISTINCT symbol FROM engine_events ORDER BY symbol`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch journal symbols: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, fmt.Errorf("failed to fetch journal symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, rows.Err()
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestJournal(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "journal_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	var startSeq int64
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(seq), 0) FROM engine_events`).Scan(&startSeq); err != nil {
+		t.Fatal(err)
+	}
+
+	bid, err := storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "JRN-USD", Side: "BUY", Price: 100, Quantity: 2})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	ask, err := storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "JRN-USD", Side: "SELL", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	trade, err := storage.CreateTrade(ctx, 100, 1, bid.ID, ask.ID)
+	if err != nil {
+		t.Fatalf("CreateTrade failed: %v", err)
+	}
+
+	cancelled, err := storage.CancelOrder(ctx, bid.ID, u.ID)
+	if err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+
+	t.Run("Records inputs and outputs in order", func(t *testing.T) {
+		events, err := storage.GetJournal(ctx, JournalQuery{Symbol: "JRN-USD", AfterSeq: startSeq, Limit: 100})
+		if err != nil {
+			t.Fatalf("GetJournal failed: %v", err)
+		}
+
+		want := []JournalEventType{
+			EventOrderCreated,
+			EventOrderCreated,
+			EventTradeExecuted,
+			EventOrderStatusChanged,
+			EventOrderCancelled,
+			EventOrderStatusChanged,
+		}
+
+		if len(events) != len(want) {
+			t.Fatalf("Expected %d events, got %d: %+v", len(want), len(events), events)
+		}
+		for i, e := range events {
+			if e.Type != want[i] {
+				t.Errorf("Event %d: expected %s, got %s", i, want[i], e.Type)
+			}
+		}
+
+		created, err := events[0].Order()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.ID != bid.ID || created.UserID != u.ID || created.Quantity != 2 {
+			t.Errorf("Unexpected order payload: %+v", created)
+		}
+
+		journaled, err := events[2].Trade()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if journaled.ID != trade.ID || journaled.BuyerID != bid.ID || journaled.SellerID != ask.ID {
+			t.Errorf("Unexpected trade payload: %+v", journaled)
+		}
+
+		filled, err := events[3].StatusChange()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filled.OrderID != ask.ID || filled.Status != "FILLED" {
+			t.Errorf("Expected the ask to be FILLED, got %+v", filled)
+		}
+	})
+
+	t.Run("Cancel leaves the remainder off the book", func(t *testing.T) {
+		if cancelled.Status != "CANCELLED" || cancelled.Quantity != 1 || cancelled.FilledQuantity != 1 {
+			t.Errorf("Unexpected cancelled order: %+v", cancelled)
+		}
+
+		best, err := storage.GetBestBuyOrder(ctx, "JRN-USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if best != nil {
+			t.Errorf("Expected no resting bids, got %+v", best)
+		}
+	})
+
+	t.Run("Cannot cancel twice or cancel another user's order", func(t *testing.T) {
+		if _, err := storage.CancelOrder(ctx, bid.ID, u.ID); !errors.Is(err, ErrOrderNotFound) {
+			t.Errorf("Expected ErrOrderNotFound for a cancelled order, got %v", err)
+		}
+
+		other, err := storage.CreateUser(ctx, &User{Username: "journal_other", PasswordHash: "hash"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resting, err := storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "JRN-USD", Side: "BUY", Price: 90, Quantity: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := storage.CancelOrder(ctx, resting.ID, other.ID); !errors.Is(err, ErrOrderNotFound) {
+			t.Errorf("Expected ErrOrderNotFound for another user's order, got %v", err)
+		}
+	})
+}
+
+func TestGetJournal_RequiresSymbol(t *testing.T) {
+	storage := NewStorage(nil)
+
+	if _, err := storage.GetJournal(context.Background(), JournalQuery{Limit: 100}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation without a symbol, got %v", err)
+	}
+}
@@ -2,8 +2,12 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type Candle struct {
@@ -15,6 +19,7 @@ type Candle struct {
 	Volume float64   `json:"volume"`
 }
 
+// Interval is a candle width; buckets are aligned in UTC and Time is a bucket's start.
 type Interval string
 
 const (
@@ -23,57 +28,175 @@ const (
 	FifteenMinutes Interval = "15m"
 	Hour           Interval = "1h"
 	FourHours      Interval = "4h"
-	Day            Interval = "1 day"
+	Day            Interval = "1d"
+	Week           Interval = "1w"
+	Month          Interval = "1M"
 )
 
 func ParseInterval(s string) (Interval, error) {
 	// We use a switch to whitelist valid inputs
 	switch Interval(s) {
-	case Minute, FiveMinutes, FifteenMinutes, Hour, FourHours, Day:
+	case Minute, FiveMinutes, FifteenMinutes, Hour, FourHours, Day, Week, Month:
 		return Interval(s), nil
 	default:
 		return "", fmt.Errorf("invalid interval: %s", s)
 	}
 }
 
-func (s *Storage) GetKlines(ctx context.Context, symbol string, interval Interval, limit int) ([]Candle, error) {
-	klines := []Candle{}
-
-	var seconds int
-	switch interval {
+// seconds returns the fixed width of the interval, or 0 for the calendar
+// intervals (weeks and months) that are aligned by date instead.
+func (i Interval) seconds() int64 {
+	switch i {
 	case Minute:
-		seconds = 60
+		return 60
 	case FiveMinutes:
-		seconds = 300
+		return 300
 	case FifteenMinutes:
-		seconds = 900
+		return 900
 	case Hour:
-		seconds = 3600
+		return 3600
 	case FourHours:
-		seconds = 14400
+		return 14400
 	case Day:
-		seconds = 86400
+		return 86400
 	default:
-		return nil, fmt.Errorf("invalid interval: %s", interval)
+		return 0
+	}
+}
+
+// Truncate returns the start of the bucket containing t.
+func (i Interval) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+
+	switch i {
+	case Week:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	secs := i.seconds()
+	return time.Unix(t.Unix()-t.Unix()%secs, 0).UTC()
+}
+
+// Add moves an aligned bucket start n buckets forward (or back when n < 0).
+func (i Interval) Add(t time.Time, n int) time.Time {
+	switch i {
+	case Week:
+		return t.AddDate(0, 0, 7*n)
+	case Month:
+		return t.AddDate(0, n, 0)
 	}
 
+	return t.Add(time.Duration(n*int(i.seconds())) * time.Second)
+}
+
+// bucketSQL returns a SQL expression that maps a timestamp column onto the
+// start of its bucket, following the same alignment rules as Truncate.
+func (i Interval) bucketSQL(column string) (string, error) {
+	switch i {
+	case Week:
+		return fmt.Sprintf("date_trunc('week', %s) AT TIME ZONE 'UTC'", column), nil
+	case Month:
+		return fmt.Sprintf("date_trunc('month', %s) AT TIME ZONE 'UTC'", column), nil
+	}
+
+	secs := i.seconds()
+	if secs == 0 {
+		return "", fmt.Errorf("invalid interval: %s", i)
+	}
+
+	return fmt.Sprintf("to_timestamp(floor(extract(epoch from %s) / %d) * %d)", column, secs, secs), nil
+}
+
+// KlineQuery selects the latest Limit candles of a symbol, or the first Limit from StartTime.
+type KlineQuery struct {
+	Symbol    string
+	Interval  Interval
+	StartTime time.Time
+	EndTime   time.Time
+	Limit     int
+	Ascending bool
+}
+
+func (s *Storage) GetKlines(ctx context.Context, symbol string, interval Interval, limit int) ([]Candle, error) {
+	return s.GetKlineRange(ctx, KlineQuery{Symbol: symbol, Interval: interval, Limit: limit})
+}
+
+// GetKlineRange reads from the candles table maintained by UpdateCandles and
+// BackfillCandles rather than aggregating raw trades.
+func (s *Storage) GetKlineRange(ctx context.Context, q KlineQuery) ([]Candle, error) {
+	if q.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", ErrValidation)
+	}
+
+	if _, err := ParseInterval(string(q.Interval)); err != nil {
+		return nil, err
+	}
+
+	from := time.Time{}
+	if !q.StartTime.IsZero() {
+		from = q.Interval.Truncate(q.StartTime)
+	}
+
+	conditions := []string{"symbol = $1", "period = $2"}
+	args := []any{q.Symbol, string(q.Interval)}
+
+	if !from.IsZero() {
+		args = append(args, from)
+		conditions = append(conditions, fmt.Sprintf("bucket >= $%d", len(args)))
+	}
+	if !q.EndTime.IsZero() {
+		args = append(args, q.EndTime.UTC())
+		conditions = append(conditions, fmt.Sprintf("bucket < $%d", len(args)))
+	}
+
+	order := "DESC"
+	if !from.IsZero() {
+		order = "ASC"
+	}
+
+	args = append(args, q.Limit)
+
 	query := fmt.Sprintf(`
-		SELECT 
-			to_timestamp(floor(extract(epoch from t.timestamp) / %d) * %d) AS bucket,
-			(array_agg(t.price ORDER BY t.timestamp ASC))[1] AS open_price,
-			MAX(t.price) AS high_price,
-			MIN(t.price) AS low_price,
-			(array_agg(t.price ORDER BY t.timestamp DESC))[1] AS close_price,
-			SUM(t.quantity) AS volume
-		FROM trades t
-		JOIN orders o ON t.bid_order_id::text = o.id::text
-		WHERE o.symbol = $1
-		GROUP BY 1
-		ORDER BY 1 DESC
-		LIMIT $2
-	`, seconds, seconds)
-
-	rows, err := s.db.Query(ctx, query, symbol, limit)
+		SELECT bucket, open, high, low, close, volume
+		FROM candles
+		WHERE %s
+		ORDER BY bucket %s
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), order, len(args))
+
+	klines, err := s.queryCandles(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if order == "DESC" {
+		reverseCandles(klines)
+	}
+
+	var prevClose *float64
+	if !from.IsZero() {
+		prevClose, err = s.lastCloseBefore(ctx, q.Symbol, q.Interval, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filled := fillGaps(klines, q, from, prevClose, time.Now())
+
+	if !q.Ascending {
+		reverseCandles(filled)
+	}
+
+	return filled, nil
+}
+
+func (s *Storage) queryCandles(ctx context.Context, query string, args ...any) ([]Candle, error) {
+	klines := []Candle{}
+
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute kline query: %w", err)
 	}
@@ -92,6 +215,7 @@ func (s *Storage) GetKlines(ctx context.Context, symbol string, interval Interva
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan kline: %w", err)
 		}
+		k.Time = k.Time.UTC()
 		klines = append(klines, k)
 	}
 
@@ -101,3 +225,96 @@ func (s *Storage) GetKlines(ctx context.Context, symbol string, interval Interva
 
 	return klines, nil
 }
+
+func (s *Storage) lastCloseBefore(ctx context.Context, symbol string, interval Interval, before time.Time) (*float64, error) {
+	var price float64
+
+	query := `
+		SELECT close
+		FROM candles
+		WHERE symbol = $1 AND period = $2 AND bucket < $3
+		ORDER BY bucket DESC
+		LIMIT 1
+	`
+
+	err := s.db.QueryRow(ctx, query, symbol, string(interval), before).Scan(&price)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch previous close: %w", err)
+	}
+
+	return &price, nil
+}
+
+// fillGaps fills empty buckets with the previous close and zero volume.
+func fillGaps(candles []Candle, q KlineQuery, from time.Time, prevClose *float64, now time.Time) []Candle {
+	filled := []Candle{}
+
+	if len(candles) == 0 && prevClose == nil {
+		return filled
+	}
+
+	end := q.EndTime
+	if end.IsZero() || end.After(now) {
+		end = now
+	}
+
+	first := from
+	if first.IsZero() {
+		first = candles[0].Time
+	}
+
+	last := q.Interval.Truncate(end.Add(-time.Nanosecond))
+	if q.EndTime.IsZero() && len(candles) > 0 {
+		last = candles[len(candles)-1].Time
+	}
+
+	if from.IsZero() {
+		if windowStart := q.Interval.Add(last, -(q.Limit - 1)); windowStart.After(first) {
+			first = windowStart
+		}
+	} else {
+		if windowEnd := q.Interval.Add(first, q.Limit-1); windowEnd.Before(last) {
+			last = windowEnd
+		}
+	}
+
+	idx := 0
+	for idx < len(candles) && candles[idx].Time.Before(first) {
+		c := candles[idx].Close
+		prevClose = &c
+		idx++
+	}
+
+	for b := first; !b.After(last); b = q.Interval.Add(b, 1) {
+		if idx < len(candles) && candles[idx].Time.Equal(b) {
+			filled = append(filled, candles[idx])
+			c := candles[idx].Close
+			prevClose = &c
+			idx++
+			continue
+		}
+
+		if prevClose == nil {
+			continue
+		}
+
+		filled = append(filled, Candle{
+			Time:  b,
+			Open:  *prevClose,
+			High:  *prevClose,
+			Low:   *prevClose,
+			Close: *prevClose,
+		})
+	}
+
+	return filled
+}
+
+func reverseCandles(c []Candle) {
+	for i, j := 0, len(c)-1; i < j; i, j = i+1, j-1 {
+		c[i], c[j] = c[j], c[i]
+	}
+}
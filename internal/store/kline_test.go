@@ -66,6 +66,10 @@ func TestGetKlines(t *testing.T) {
 
 	err = tx.QueryRow(ctx, "SELECT count(*) FROM orders WHERE symbol='BTC-USD'").Scan(&orderCount)
 
+	if err := s.BackfillCandles(ctx); err != nil {
+		t.Fatalf("BackfillCandles failed: %v", err)
+	}
+
 	klines, err := s.GetKlines(ctx, "BTC-USD", Minute, 10)
 
 	if err != nil {
@@ -100,3 +104,162 @@ func TestGetKlines(t *testing.T) {
 		t.Errorf("Volume: got %v, want 3 (Sum of qty)", target.Volume)
 	}
 }
+
+func TestGetKlineRange(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	s := NewStorage(tx)
+	ctx := context.Background()
+
+	var bidID, askID string
+
+	err := tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('GAP-USD', 'BUY', 1, 1, 'FILLED') RETURNING id`).Scan(&bidID)
+	if err != nil {
+		t.Fatal("Failed to create dummy Buy Order:", err)
+	}
+
+	err = tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('GAP-USD', 'SELL', 1, 1, 'FILLED') RETURNING id`).Scan(&askID)
+	if err != nil {
+		t.Fatal("Failed to create dummy Sell Order:", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// 09:59 sets the previous close, then 10:00 and 10:03 trade with a gap between.
+	tradesToInsert := []struct {
+		Price  float64
+		Offset time.Duration
+	}{
+		{Price: 90, Offset: -30 * time.Second},
+		{Price: 100, Offset: 10 * time.Second},
+		{Price: 120, Offset: 3*time.Minute + 10*time.Second},
+	}
+
+	for _, tr := range tradesToInsert {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO trades (bid_order_id, ask_order_id, price, quantity, timestamp)
+			VALUES ($1, $2, $3, 1, $4)
+		`, bidID, askID, tr.Price, baseTime.Add(tr.Offset))
+		if err != nil {
+			t.Fatal("Failed to seed trade:", err)
+		}
+	}
+
+	if err := s.BackfillCandles(ctx); err != nil {
+		t.Fatalf("BackfillCandles failed: %v", err)
+	}
+
+	klines, err := s.GetKlineRange(ctx, KlineQuery{
+		Symbol:    "GAP-USD",
+		Interval:  Minute,
+		StartTime: baseTime,
+		EndTime:   baseTime.Add(5 * time.Minute),
+		Limit:     10,
+		Ascending: true,
+	})
+	if err != nil {
+		t.Fatalf("GetKlineRange failed: %v", err)
+	}
+
+	if len(klines) != 5 {
+		t.Fatalf("Expected 5 contiguous candles, got %d", len(klines))
+	}
+
+	for i, k := range klines {
+		if want := baseTime.Add(time.Duration(i) * time.Minute); !k.Time.Equal(want) {
+			t.Errorf("Candle %d: time %v, want %v", i, k.Time, want)
+		}
+	}
+
+	if klines[1].Close != 100 || klines[1].Volume != 0 {
+		t.Errorf("Gap candle should repeat previous close with zero volume, got %+v", klines[1])
+	}
+	if klines[3].Close != 120 || klines[3].Volume != 1 {
+		t.Errorf("10:03 candle wrong, got %+v", klines[3])
+	}
+	if klines[4].Open != 120 || klines[4].Volume != 0 {
+		t.Errorf("Trailing candle should carry 120 forward, got %+v", klines[4])
+	}
+}
+
+func TestIntervalTruncate(t *testing.T) {
+	ts := time.Date(2024, 3, 14, 15, 47, 12, 0, time.UTC) // a Thursday
+
+	tests := []struct {
+		interval Interval
+		want     time.Time
+	}{
+		{Minute, time.Date(2024, 3, 14, 15, 47, 0, 0, time.UTC)},
+		{FifteenMinutes, time.Date(2024, 3, 14, 15, 45, 0, 0, time.UTC)},
+		{FourHours, time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC)},
+		{Day, time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{Month, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.interval), func(t *testing.T) {
+			if got := tt.interval.Truncate(ts); !got.Equal(tt.want) {
+				t.Errorf("Truncate: got %v, want %v", got, tt.want)
+			}
+			if got := tt.interval.Truncate(tt.want); !got.Equal(tt.want) {
+				t.Errorf("Truncate is not idempotent: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := Month.Add(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1); !got.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Month.Add: got %v", got)
+	}
+}
+
+func TestFillGaps(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	now := base.Add(time.Hour)
+
+	candles := []Candle{
+		{Time: base, Open: 1, High: 2, Low: 1, Close: 2, Volume: 3},
+		{Time: base.Add(3 * time.Minute), Open: 5, High: 5, Low: 5, Close: 5, Volume: 1},
+	}
+
+	t.Run("Fills interior gaps with previous close", func(t *testing.T) {
+		filled := fillGaps(candles, KlineQuery{Interval: Minute, Limit: 10}, time.Time{}, nil, now)
+
+		if len(filled) != 4 {
+			t.Fatalf("Expected 4 candles, got %d", len(filled))
+		}
+		if filled[1].Close != 2 || filled[2].Close != 2 || filled[2].Volume != 0 {
+			t.Errorf("Gap candles wrong: %+v %+v", filled[1], filled[2])
+		}
+	})
+
+	t.Run("Latest window honours limit", func(t *testing.T) {
+		filled := fillGaps(candles, KlineQuery{Interval: Minute, Limit: 2}, time.Time{}, nil, now)
+
+		if len(filled) != 2 {
+			t.Fatalf("Expected 2 candles, got %d", len(filled))
+		}
+		if !filled[0].Time.Equal(base.Add(2*time.Minute)) || filled[0].Close != 2 {
+			t.Errorf("Window should start at 10:02 carrying close 2, got %+v", filled[0])
+		}
+	})
+
+	t.Run("Leading buckets use the close before the range", func(t *testing.T) {
+		prev := 0.5
+		from := base.Add(-2 * time.Minute)
+
+		filled := fillGaps(candles, KlineQuery{Interval: Minute, Limit: 3}, from, &prev, now)
+
+		if len(filled) != 3 {
+			t.Fatalf("Expected 3 candles, got %d", len(filled))
+		}
+		if filled[0].Close != 0.5 || filled[2].Close != 2 {
+			t.Errorf("Unexpected leading candles: %+v", filled)
+		}
+	})
+
+	t.Run("No data returns no candles", func(t *testing.T) {
+		if filled := fillGaps(nil, KlineQuery{Interval: Minute, Limit: 5}, time.Time{}, nil, now); len(filled) != 0 {
+			t.Errorf("Expected no candles, got %d", len(filled))
+		}
+	})
+}
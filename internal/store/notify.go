@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OrdersChannel is the Postgres notification channel a new order's symbol is
+// sent on when its transaction commits.
+const OrdersChannel = "new_orders"
+
+// ErrListenUnsupported means the storage is not backed by a pool, so it has
+// no connection to spare for LISTEN.
+var ErrListenUnsupported = errors.New("storage cannot listen for notifications")
+
+// ListenOrders calls fn with the symbol of every order committed by any
+// process, until ctx is cancelled or the connection fails.
+func (s *Storage) ListenOrders(ctx context.Context, fn func(symbol string)) error {
+	pool, ok := s.db.(*pgxpool.Pool)
+	if !ok {
+		return ErrListenUnsupported
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+OrdersChannel); err != nil {
+		return fmt.Errorf("failed to listen for orders: %w", err)
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		fn(n.Payload)
+	}
+}
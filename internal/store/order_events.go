@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Order event types, whose payloads are an Order, a Fill and a SelfTradePrevention.
+const (
+	OrderEventOrder     = "order"
+	OrderEventFill      = "fill"
+	OrderEventSelfTrade = "self_trade"
+)
+
+// OrderEvent is one entry in a user's order event log, in commit order.
+type OrderEvent struct {
+	Seq       int64           `json:"seq"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// lockOrderEvents takes several users' order event locks in a fixed order.
+func lockOrderEvents(ctx context.Context, db DBTX, userIDs ...string) error {
+	userIDs = slices.Clone(userIDs)
+	slices.Sort(userIDs)
+
+	query := `
+	INSERT INTO order_event_sequences (user_id, last_seq)
+	VALUES ($1, 0)
+	ON CONFLICT (user_id) DO UPDATE SET last_seq = order_event_sequences.last_seq
+	`
+
+	for _, userID := range slices.Compact(userIDs) {
+		if userID == "" {
+			continue
+		}
+
+		if _, err := db.Exec(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to lock order events: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// appendOrderEvent logs an order event in the change's transaction, after its book locks.
+func appendOrderEvent(ctx context.Context, db DBTX, userID, eventType string, payload any) error {
+	if userID == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s order event: %w", eventType, err)
+	}
+
+	query := `
+	WITH seq AS (
+		INSERT INTO order_event_sequences (user_id, last_seq)
+		VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET last_seq = order_event_sequences.last_seq + 1
+		RETURNING last_seq
+	)
+	INSERT INTO order_events (user_id, seq, type, payload)
+	SELECT $1, last_seq, $2, $3 FROM seq
+	`
+
+	if _, err := db.Exec(ctx, query, userID, eventType, data); err != nil {
+		return fmt.Errorf("failed to append %s order event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// OrderEventQuery reads a user's order events forward from AfterSeq
+// (exclusive).
+type OrderEventQuery struct {
+	UserID   string
+	AfterSeq int64
+	Limit    int
+}
+
+func (s *Storage) GetOrderEvents(ctx context.Context, q OrderEventQuery) ([]OrderEvent, error) {
+	events := []OrderEvent{}
+
+	if q.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", ErrValidation)
+	}
+	if q.AfterSeq < 0 {
+		return nil, fmt.Errorf("seq must not be negative: %w", ErrValidation)
+	}
+	if !isUUID(q.UserID) {
+		return events, nil
+	}
+
+	query := `
+	SELECT seq, type, payload, created_at
+		FROM order_events
+		WHERE user_id = $1 AND seq > $2
+		ORDER BY seq ASC
+		LIMIT $3
+	`
+
+	rows, err := s.db.Query(ctx, query, q.UserID, q.AfterSeq, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order events: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var e OrderEvent
+
+		if err := rows.Scan(&e.Seq, &e.Type, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to fetch order event: %w", err)
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// GetLastOrderEventSeq returns the Seq of a user's latest order event, or
+// zero if they have none.
+func (s *Storage) GetLastOrderEventSeq(ctx context.Context, userID string) (int64, error) {
+	var seq int64
+
+	if !isUUID(userID) {
+		return 0, nil
+	}
+
+	query := `SELECT COALESCE(MAX(last_seq), 0) FROM order_event_sequences WHERE user_id = $1`
+
+	if err := s.db.QueryRow(ctx, query, userID).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to fetch order event seq: %w", err)
+	}
+
+	return seq, nil
+}
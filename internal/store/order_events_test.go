@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestOrderEvents(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	maker, err := storage.CreateUser(ctx, &User{Username: "events_maker", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create maker: %v", err)
+	}
+
+	taker, err := storage.CreateUser(ctx, &User{Username: "events_taker", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create taker: %v", err)
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO trading_pairs (symbol, base_asset, quote_asset, trading_phase) VALUES ('EVT-USD', 'EVT', 'USD', 'CONTINUOUS')`); err != nil {
+		t.Fatalf("Failed to seed DB: %v", err)
+	}
+
+	ask, err := storage.CreateOrder(ctx, Order{UserID: maker.ID, Symbol: "EVT-USD", Side: "SELL", Price: 100, Quantity: 2})
+	if err != nil {
+		t.Fatalf("Failed to create ask: %v", err)
+	}
+
+	bid, err := storage.CreateOrder(ctx, Order{UserID: taker.ID, Symbol: "EVT-USD", Side: "BUY", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatalf("Failed to create bid: %v", err)
+	}
+
+	if _, err := storage.CreateTrade(ctx, 100, 1, bid.ID, ask.ID); err != nil {
+		t.Fatalf("Failed to create trade: %v", err)
+	}
+
+	if _, err := storage.CancelOrder(ctx, ask.ID, maker.ID); err != nil {
+		t.Fatalf("Failed to cancel ask: %v", err)
+	}
+
+	// statuses lists a user's events as the order status they carry, or the
+	// event type for anything else.
+	statuses := func(userID string, afterSeq int64) []string {
+		t.Helper()
+
+		events, err := storage.GetOrderEvents(ctx, OrderEventQuery{UserID: userID, AfterSeq: afterSeq, Limit: 10})
+		if err != nil {
+			t.Fatalf("GetOrderEvents failed: %v", err)
+		}
+
+		got := []string{}
+		for i, e := range events {
+			if e.Seq != afterSeq+int64(i)+1 {
+				t.Errorf("Expected seq %d, got %d", afterSeq+int64(i)+1, e.Seq)
+			}
+
+			if e.Type != OrderEventOrder {
+				got = append(got, e.Type)
+				continue
+			}
+
+			var o Order
+			if err := json.Unmarshal(e.Payload, &o); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, o.Status)
+		}
+		return got
+	}
+
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("Logs every change to the owner's orders in order", func(t *testing.T) {
+		if got, want := statuses(maker.ID, 0), []string{"PENDING", OrderEventFill, "PENDING", "CANCELLED"}; !equal(got, want) {
+			t.Errorf("Expected maker events %v, got %v", want, got)
+		}
+		if got, want := statuses(taker.ID, 0), []string{"PENDING", OrderEventFill, "FILLED"}; !equal(got, want) {
+			t.Errorf("Expected taker events %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Resumes after a seq", func(t *testing.T) {
+		if got, want := statuses(maker.ID, 2), []string{"PENDING", "CANCELLED"}; !equal(got, want) {
+			t.Errorf("Expected maker events %v, got %v", want, got)
+		}
+
+		last, err := storage.GetLastOrderEventSeq(ctx, maker.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last != 4 {
+			t.Errorf("Expected the last seq to be 4, got %d", last)
+		}
+	})
+
+	t.Run("Rejects a negative seq", func(t *testing.T) {
+		if _, err := storage.GetOrderEvents(ctx, OrderEventQuery{UserID: maker.ID, AfterSeq: -1, Limit: 10}); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+	})
+}
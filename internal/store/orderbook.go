@@ -0,0 +1,277 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Book depths a client may ask for. A depth of FullBookDepth returns every
+// level (or, for an L3 book, every order).
+const (
+	DefaultBookDepth = 20
+	FullBookDepth    = 0
+)
+
+var bookDepths = []int{5, 10, 20, 50, 100, FullBookDepth}
+
+// OrderBookEntry is one price level; Total is the cumulative quantity up to it.
+type OrderBookEntry struct {
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+	Total    int     `json:"total,omitempty"`
+}
+
+// OrderBook is a snapshot of the top of the book as of LastUpdateID.
+type OrderBook struct {
+	LastUpdateID int64            `json:"lastUpdateId"`
+	Bids         []OrderBookEntry `json:"bids"`
+	Asks         []OrderBookEntry `json:"asks"`
+}
+
+// DepthChange is every level a book change touched, read under the book lock.
+type DepthChange struct {
+	Bids []OrderBookEntry
+	Asks []OrderBookEntry
+}
+
+// readDepth reads the levels orders rest or rested at, after the transaction's last book change.
+func readDepth(ctx context.Context, db DBTX, symbol string, orders ...Order) (*DepthChange, error) {
+	depth := &DepthChange{Bids: []OrderBookEntry{}, Asks: []OrderBookEntry{}}
+	seen := map[OrderSide]map[float64]bool{Buy: {}, Sell: {}}
+
+	for _, o := range orders {
+		side := OrderSide(o.Side)
+		if o.Price <= 0 || seen[side] == nil || seen[side][o.Price] {
+			continue
+		}
+		seen[side][o.Price] = true
+
+		level, err := priceLevel(ctx, db, symbol, side, o.Price)
+		if err != nil {
+			return nil, err
+		}
+
+		if side == Buy {
+			depth.Bids = append(depth.Bids, level)
+		} else {
+			depth.Asks = append(depth.Asks, level)
+		}
+	}
+
+	slices.SortFunc(depth.Bids, func(a, b OrderBookEntry) int { return cmp.Compare(b.Price, a.Price) })
+	slices.SortFunc(depth.Asks, func(a, b OrderBookEntry) int { return cmp.Compare(a.Price, b.Price) })
+
+	return depth, nil
+}
+
+// BookQuery selects how much of a symbol's book to return, optionally grouped.
+type BookQuery struct {
+	Symbol   string
+	Depth    int
+	Grouping float64
+}
+
+func (q BookQuery) validate() error {
+	if !slices.Contains(bookDepths, q.Depth) {
+		return fmt.Errorf("depth must be one of 5, 10, 20, 50, 100 or full: %w", ErrValidation)
+	}
+	if q.Grouping < 0 {
+		return fmt.Errorf("grouping must be positive: %w", ErrValidation)
+	}
+	return nil
+}
+
+func (s *Storage) GetOrderBookDepth(ctx context.Context, q BookQuery) (*OrderBook, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+
+	// Read the sequence first: later changes' depth updates then correct any newer levels.
+	lastUpdateID, err := s.GetLastUpdateID(ctx, q.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	bids, err := s.getBookLevels(ctx, q, Buy)
+	if err != nil {
+		return nil, err
+	}
+
+	asks, err := s.getBookLevels(ctx, q, Sell)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderBook{LastUpdateID: lastUpdateID, Bids: bids, Asks: asks}, nil
+}
+
+func (s *Storage) getBookLevels(ctx context.Context, q BookQuery, side OrderSide) ([]OrderBookEntry, error) {
+	levels := []OrderBookEntry{}
+
+	args := []any{q.Symbol, string(side)}
+
+	bucket, order := "price", "DESC"
+	if side == Sell {
+		order = "ASC"
+	}
+
+	if q.Grouping > 0 {
+		args = append(args, q.Grouping)
+		if side == Buy {
+			bucket = "FLOOR(price / $3::numeric) * $3::numeric"
+		} else {
+			bucket = "CEIL(price / $3::numeric) * $3::numeric"
+		}
+	}
+
+	query := fmt.Sprintf(`
+	SELECT %s AS level, SUM(COALESCE(visible_quantity, quantity))
+		FROM orders
+		WHERE symbol = $1 AND side = $2 AND status = 'PENDING' AND quantity > 0
+		GROUP BY level
+		ORDER BY level %s
+	`, bucket, order)
+
+	if q.Depth != FullBookDepth {
+		args = append(args, q.Depth)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	total := 0
+
+	for rows.Next() {
+		oe := OrderBookEntry{}
+
+		if err := rows.Scan(&oe.Price, &oe.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to fetch order book level: %w", err)
+		}
+
+		total += oe.Quantity
+		oe.Total = total
+
+		levels = append(levels, oe)
+	}
+
+	return levels, rows.Err()
+}
+
+// OrderBookOrder is one resting order in an L3 book, under a keyed hash of its ID.
+type OrderBookOrder struct {
+	ID       string  `json:"id"`
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+	Total    int     `json:"total"`
+}
+
+type OrderBookL3 struct {
+	LastUpdateID int64            `json:"lastUpdateId"`
+	Bids         []OrderBookOrder `json:"bids"`
+	Asks         []OrderBookOrder `json:"asks"`
+}
+
+// GetOrderBookL3 lists individual resting orders in priority order. Depth
+// counts orders rather than price levels; grouping does not apply.
+func (s *Storage) GetOrderBookL3(ctx context.Context, q BookQuery) (*OrderBookL3, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	if q.Grouping > 0 {
+		return nil, fmt.Errorf("grouping cannot be used with an L3 book: %w", ErrValidation)
+	}
+
+	lastUpdateID, err := s.GetLastUpdateID(ctx, q.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	bids, err := s.getBookOrders(ctx, q, Buy)
+	if err != nil {
+		return nil, err
+	}
+
+	asks, err := s.getBookOrders(ctx, q, Sell)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderBookL3{LastUpdateID: lastUpdateID, Bids: bids, Asks: asks}, nil
+}
+
+func (s *Storage) getBookOrders(ctx context.Context, q BookQuery, side OrderSide) ([]OrderBookOrder, error) {
+	orders := []OrderBookOrder{}
+
+	args := []any{q.Symbol, string(side)}
+
+	order := "DESC"
+	if side == Sell {
+		order = "ASC"
+	}
+
+	query := fmt.Sprintf(`
+	SELECT id, price, COALESCE(visible_quantity, quantity)
+		FROM orders
+		WHERE symbol = $1 AND side = $2 AND status = 'PENDING' AND quantity > 0
+		ORDER BY price %s, priority_at ASC
+	`, order)
+
+	if q.Depth != FullBookDepth {
+		args = append(args, q.Depth)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	total := 0
+
+	for rows.Next() {
+		var id string
+		o := OrderBookOrder{}
+
+		if err := rows.Scan(&id, &o.Price, &o.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to fetch order book order: %w", err)
+		}
+
+		total += o.Quantity
+		o.ID = anonymiseOrderID(id)
+		o.Total = total
+
+		orders = append(orders, o)
+	}
+
+	return orders, rows.Err()
+}
+
+// bookIDSecret keys the L3 book's order IDs.
+var bookIDSecret = sync.OnceValue(func() []byte {
+	secret := os.Getenv("BOOK_ID_SECRET")
+
+	if secret == "" {
+		secret = "default-dev-secret-do-not-use-in-prod"
+	}
+	return []byte(secret)
+})
+
+func anonymiseOrderID(id string) string {
+	mac := hmac.New(sha256.New, bookIDSecret())
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestGetOrderBookDepth(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO orders (symbol, side, price, quantity, status) VALUES
+		('BOOK-USD', 'BUY', 99.50, 1, 'PENDING'),
+		('BOOK-USD', 'BUY', 99.00, 2, 'PENDING'),
+		('BOOK-USD', 'BUY', 98.00, 3, 'PENDING'),
+		('BOOK-USD', 'BUY', 89.00, 4, 'PENDING'),
+		('BOOK-USD', 'SELL', 100.50, 5, 'PENDING'),
+		('BOOK-USD', 'SELL', 101.00, 6, 'PENDING'),
+		('BOOK-USD', 'SELL', 111.00, 7, 'PENDING')
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Limits levels and accumulates totals", func(t *testing.T) {
+		book, err := storage.GetOrderBookDepth(ctx, BookQuery{Symbol: "BOOK-USD", Depth: 5})
+		if err != nil {
+			t.Fatalf("GetOrderBookDepth failed: %v", err)
+		}
+
+		if len(book.Bids) != 4 || len(book.Asks) != 3 {
+			t.Fatalf("Expected 4 bids and 3 asks, got %+v", book)
+		}
+
+		totals := []int{1, 3, 6, 10}
+		for i, level := range book.Bids {
+			if level.Total != totals[i] {
+				t.Errorf("Bid %d: expected total %d, got %d", i, totals[i], level.Total)
+			}
+		}
+
+		if book.Asks[2].Total != 18 {
+			t.Errorf("Expected cumulative ask total 18, got %d", book.Asks[2].Total)
+		}
+	})
+
+	t.Run("Groups bids down and asks up", func(t *testing.T) {
+		book, err := storage.GetOrderBookDepth(ctx, BookQuery{Symbol: "BOOK-USD", Depth: FullBookDepth, Grouping: 10})
+		if err != nil {
+			t.Fatalf("GetOrderBookDepth failed: %v", err)
+		}
+
+		wantBids := []OrderBookEntry{{Price: 90, Quantity: 6, Total: 6}, {Price: 80, Quantity: 4, Total: 10}}
+		wantAsks := []OrderBookEntry{{Price: 110, Quantity: 11, Total: 11}, {Price: 120, Quantity: 7, Total: 18}}
+
+		if len(book.Bids) != len(wantBids) || len(book.Asks) != len(wantAsks) {
+			t.Fatalf("Unexpected grouped book: %+v", book)
+		}
+		for i := range wantBids {
+			if book.Bids[i] != wantBids[i] {
+				t.Errorf("Bid %d: expected %+v, got %+v", i, wantBids[i], book.Bids[i])
+			}
+		}
+		for i := range wantAsks {
+			if book.Asks[i] != wantAsks[i] {
+				t.Errorf("Ask %d: expected %+v, got %+v", i, wantAsks[i], book.Asks[i])
+			}
+		}
+	})
+
+	t.Run("Rejects unsupported depths", func(t *testing.T) {
+		_, err := storage.GetOrderBookDepth(ctx, BookQuery{Symbol: "BOOK-USD", Depth: 7})
+
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+	})
+}
+
+func TestGetOrderBookL3(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	var firstID string
+
+	err := tx.QueryRow(ctx, `
+		INSERT INTO orders (symbol, side, price, quantity, status, created_at, priority_at)
+		VALUES ('L3-USD', 'BUY', 100, 1, 'PENDING', '2024-01-01 10:00:00', '2024-01-01 10:00:00')
+		RETURNING id
+	`).Scan(&firstID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO orders (symbol, side, price, quantity, status, created_at, priority_at) VALUES
+		('L3-USD', 'BUY', 100, 2, 'PENDING', '2024-01-01 10:00:01', '2024-01-01 10:00:01'),
+		('L3-USD', 'SELL', 101, 3, 'PENDING', '2024-01-01 10:00:02', '2024-01-01 10:00:02')
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := storage.GetOrderBookL3(ctx, BookQuery{Symbol: "L3-USD", Depth: DefaultBookDepth})
+	if err != nil {
+		t.Fatalf("GetOrderBookL3 failed: %v", err)
+	}
+
+	if len(book.Bids) != 2 || len(book.Asks) != 1 {
+		t.Fatalf("Expected 2 bid orders and 1 ask order, got %+v", book)
+	}
+
+	first := book.Bids[0]
+
+	if first.Quantity != 1 || book.Bids[1].Total != 3 {
+		t.Errorf("Expected orders in time priority with running totals, got %+v", book.Bids)
+	}
+	if first.ID == firstID || first.ID != anonymiseOrderID(firstID) {
+		t.Errorf("Expected an anonymised ID, got %s", first.ID)
+	}
+
+	t.Run("Rejects grouping", func(t *testing.T) {
+		_, err := storage.GetOrderBookL3(ctx, BookQuery{Symbol: "L3-USD", Depth: DefaultBookDepth, Grouping: 10})
+
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+	})
+}
@@ -2,19 +2,86 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type Order struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Symbol    string    `json:"symbol"`
-	Price     float64   `json:"price"`
-	Quantity  int       `json:"quantity"`
-	Side      string    `json:"side"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	Symbol         string    `json:"symbol"`
+	Price          float64   `json:"price"`
+	Quantity       int       `json:"quantity"`
+	FilledQuantity int       `json:"filled_quantity"`
+	Side           string    `json:"side"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	// PriorityAt is the order's place in the queue at its price.
+	PriorityAt time.Time `json:"priority_at"`
+	// Type is LIMIT unless the order is a stop, which waits off the book as
+	// WAITING until the last trade price reaches StopPrice.
+	Type      OrderType `json:"type,omitempty"`
+	StopPrice float64   `json:"stop_price,omitempty"`
+	// A trailing stop keeps StopPrice TrailingOffset or TrailingPercent behind TrailingWatermark.
+	TrailingOffset    float64 `json:"trailing_offset,omitempty"`
+	TrailingPercent   float64 `json:"trailing_percent,omitempty"`
+	TrailingWatermark float64 `json:"trailing_watermark,omitempty"`
+	// GroupID links the legs of an OCO or bracket order, and GroupRole says
+	// which leg this is.
+	GroupID   string    `json:"group_id,omitempty"`
+	GroupRole GroupRole `json:"group_role,omitempty"`
+	// ClientOrderID is an optional reference chosen by the owner, unique
+	// among their orders.
+	ClientOrderID string `json:"client_order_id,omitempty"`
+	// STPMode is what happens if the order would trade with another order
+	// of the same user. Orders placed without one take the owner's default.
+	STPMode STPMode `json:"stp_mode,omitempty"`
+	// DisplayQuantity makes the order an iceberg; VisibleQuantity is what is left of its slice.
+	DisplayQuantity int `json:"display_quantity,omitempty"`
+	VisibleQuantity int `json:"visible_quantity,omitempty"`
+	// UpdateID and Depth are only set on orders returned by an insert or a cancel.
+	UpdateID int64        `json:"-"`
+	Depth    *DepthChange `json:"-"`
+}
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrDuplicateClientOrderID is returned, along with the existing order,
+	// when a user reuses a client order ID.
+	ErrDuplicateClientOrderID = errors.New("client order ID already used")
+)
+
+// MaxClientOrderIDLength bounds client order IDs, which may only contain
+// letters, digits and "-_.:".
+const MaxClientOrderIDLength = 64
+
+// Shown is how much of the order the book shows, and so the most it can
+// trade in one match.
+func (o Order) Shown() int {
+	if o.DisplayQuantity > 0 {
+		return o.VisibleQuantity
+	}
+	return o.Quantity
+}
+
+type OrderType string
+
+const (
+	Limit OrderType = "LIMIT"
+	// StopLimit rests as a limit order at Price once the last trade reaches StopPrice.
+	StopLimit OrderType = "STOP_LIMIT"
+	// TrailingStop is a stop whose StopPrice follows the market. It rests
+	// as a limit order at the StopPrice it triggered at.
+	TrailingStop OrderType = "TRAILING_STOP"
+)
+
+// isStop reports whether the order waits off the book until it triggers.
+func (o Order) isStop() bool {
+	return o.Type == StopLimit || o.Type == TrailingStop
 }
 
 type OrderSide string
@@ -25,7 +92,11 @@ const (
 )
 
 func (s *Storage) validateOrder(order Order) error {
-	if order.Price <= 0 {
+	if order.Type == TrailingStop {
+		if order.Price != 0 {
+			return fmt.Errorf("price is not allowed on TRAILING_STOP orders, which rest at their stop price: %w", ErrValidation)
+		}
+	} else if order.Price <= 0 {
 		return fmt.Errorf("price must be positive: %w", ErrValidation)
 	}
 	if order.Quantity <= 0 {
@@ -35,44 +106,378 @@ func (s *Storage) validateOrder(order Order) error {
 	if side != Buy && side != Sell {
 		return fmt.Errorf("side must be BUY or SELL: %w", ErrValidation)
 	}
+	if order.Type != TrailingStop && (order.TrailingOffset != 0 || order.TrailingPercent != 0) {
+		return fmt.Errorf("trailing_offset and trailing_percent are only allowed on TRAILING_STOP orders: %w", ErrValidation)
+	}
+	switch order.Type {
+	case "", Limit:
+		if order.StopPrice != 0 {
+			return fmt.Errorf("stop_price is only allowed on STOP_LIMIT orders: %w", ErrValidation)
+		}
+	case StopLimit:
+		if order.StopPrice <= 0 {
+			return fmt.Errorf("stop_price must be positive: %w", ErrValidation)
+		}
+	case TrailingStop:
+		if order.StopPrice != 0 {
+			return fmt.Errorf("stop_price is set by the trail on TRAILING_STOP orders: %w", ErrValidation)
+		}
+		if order.TrailingOffset < 0 || order.TrailingPercent < 0 || order.TrailingPercent >= 100 {
+			return fmt.Errorf("trailing_offset must be positive and trailing_percent between 0 and 100: %w", ErrValidation)
+		}
+		if (order.TrailingOffset > 0) == (order.TrailingPercent > 0) {
+			return fmt.Errorf("exactly one of trailing_offset and trailing_percent is required: %w", ErrValidation)
+		}
+	default:
+		return fmt.Errorf("type must be LIMIT, STOP_LIMIT or TRAILING_STOP: %w", ErrValidation)
+	}
+	if order.DisplayQuantity < 0 {
+		return fmt.Errorf("display_quantity must not be negative: %w", ErrValidation)
+	}
+	if order.DisplayQuantity > 0 && order.DisplayQuantity >= order.Quantity {
+		return fmt.Errorf("display_quantity must be less than quantity: %w", ErrValidation)
+	}
+	if err := validateClientOrderID(order.ClientOrderID); err != nil {
+		return err
+	}
+	if err := validateSTPMode(order.STPMode); err != nil {
+		return err
+	}
 	return nil
 }
 
-func (s *Storage) CreateOrder(ctx context.Context, order Order) (string, error) {
-	var id string
+func validateClientOrderID(id string) error {
+	if len(id) > MaxClientOrderIDLength {
+		return fmt.Errorf("client_order_id must be at most %d characters: %w", MaxClientOrderIDLength, ErrValidation)
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.ContainsRune("-_.:", c):
+		default:
+			return fmt.Errorf("client_order_id may only contain letters, digits and -_.: %w", ErrValidation)
+		}
+	}
+
+	return nil
+}
+
+// CreateOrder places an order: PENDING on the book, or WAITING for a stop.
+func (s *Storage) CreateOrder(ctx context.Context, order Order) (*Order, error) {
+	order.Status = "PENDING"
+	if order.isStop() {
+		order.Status = "WAITING"
+	}
+
+	return s.createOrder(ctx, order)
+}
+
+// createOrder places an order with the status it is given.
+func (s *Storage) createOrder(ctx context.Context, order Order) (*Order, error) {
+	if order.Type == "" {
+		order.Type = Limit
+	}
 
 	if err := s.validateOrder(order); err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if order.Price > 0 {
+		if err := checkPriceBand(ctx, s.db, order.Symbol, order.Price); err != nil {
+			return nil, err
+		}
 	}
 
 	query := `
-    INSERT INTO orders (user_id, symbol, price, quantity, side, status) 
-    VALUES ($1, $2, $3, $4, $5, 'PENDING') 
-    RETURNING id`
+    INSERT INTO orders (user_id, symbol, price, quantity, side, status, client_order_id, stp_mode, display_quantity, visible_quantity,
+        order_type, stop_price, group_id, group_role, trailing_offset, trailing_percent)
+    VALUES ($1, $2, $3, $4, $5, $9, NULLIF($6, ''),
+        COALESCE(NULLIF($7, ''), (SELECT stp_mode FROM users WHERE id = $1)),
+        NULLIF($8::int, 0), NULLIF($8::int, 0),
+        $10, NULLIF($11::numeric, 0), NULLIF($12, '')::uuid, NULLIF($13, ''), NULLIF($14::numeric, 0), NULLIF($15::numeric, 0))
+    ON CONFLICT (user_id, client_order_id) WHERE client_order_id IS NOT NULL DO NOTHING
+    RETURNING id, status, created_at, priority_at, COALESCE(stp_mode, '')`
+
+	return s.insertOrder(ctx, order, query,
+		order.UserID,
+		order.Symbol,
+		order.Price,
+		order.Quantity,
+		order.Side,
+		order.ClientOrderID,
+		string(order.STPMode),
+		order.DisplayQuantity,
+		order.Status,
+		string(order.Type),
+		order.StopPrice,
+		order.GroupID,
+		string(order.GroupRole),
+		order.TrailingOffset,
+		order.TrailingPercent,
+	)
+}
 
-	err := s.db.QueryRow(ctx, query,
+// RestoreOrder re-inserts an order taken from the journal, keeping its
+// original ID and creation time so it regains the same time priority.
+func (s *Storage) RestoreOrder(ctx context.Context, order Order) (*Order, error) {
+	if order.Type == "" {
+		order.Type = Limit
+	}
+	if order.Status == "" {
+		order.Status = "PENDING"
+		if order.isStop() {
+			order.Status = "WAITING"
+		}
+	}
+
+	// The trail is worked out again from the replayed trades.
+	if order.Type == TrailingStop {
+		order.StopPrice, order.TrailingWatermark = 0, 0
+	}
+	if order.Status != "PENDING" && order.Status != "WAITING" {
+		return nil, fmt.Errorf("cannot restore a %s order: %w", order.Status, ErrValidation)
+	}
+
+	if err := s.validateOrder(order); err != nil {
+		return nil, err
+	}
+
+	query := `
+    INSERT INTO orders (id, user_id, symbol, price, quantity, side, status, created_at, priority_at, client_order_id, stp_mode, display_quantity, visible_quantity,
+        order_type, stop_price, group_id, group_role, trailing_offset, trailing_percent)
+    VALUES ($1, $2, $3, $4, $5, $6, $11, $7, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10::int, 0), NULLIF($10::int, 0),
+        $12, NULLIF($13::numeric, 0), NULLIF($14, '')::uuid, NULLIF($15, ''), NULLIF($16::numeric, 0), NULLIF($17::numeric, 0))
+    RETURNING id, status, created_at, priority_at, COALESCE(stp_mode, '')`
+
+	return s.insertOrder(ctx, order, query,
+		order.ID,
 		order.UserID,
 		order.Symbol,
 		order.Price,
 		order.Quantity,
 		order.Side,
-	).Scan(&id)
+		order.CreatedAt.UTC(),
+		order.ClientOrderID,
+		string(order.STPMode),
+		order.DisplayQuantity,
+		order.Status,
+		string(order.Type),
+		order.StopPrice,
+		order.GroupID,
+		string(order.GroupRole),
+		order.TrailingOffset,
+		order.TrailingPercent,
+	)
+}
+
+func (s *Storage) insertOrder(ctx context.Context, order Order, query string, args ...any) (*Order, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	order.VisibleQuantity = order.DisplayQuantity
+
+	if err := tx.QueryRow(ctx, query, args...).Scan(&order.ID, &order.Status, &order.CreatedAt, &order.PriorityAt, &order.STPMode); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) && order.ClientOrderID != "" {
+			inTx := &Storage{db: tx}
+			existing, err := inTx.GetOrderByClientID(ctx, order.UserID, order.ClientOrderID)
+			if err != nil {
+				return nil, err
+			}
+			return existing, ErrDuplicateClientOrderID
+		}
+		return nil, err
+	}
+
+	if order.Type == TrailingStop {
+		if err := startTrail(ctx, tx, &order); err != nil {
+			return nil, err
+		}
+	}
+
+	order.UpdateID, err = nextUpdateID(ctx, tx, order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appendEvent(ctx, tx, order.Symbol, EventOrderCreated, order); err != nil {
+		return nil, err
+	}
+
+	if err := appendOrderEvent(ctx, tx, order.UserID, OrderEventOrder, order); err != nil {
+		return nil, err
+	}
+
+	order.Depth, err = readDepth(ctx, tx, order.Symbol, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", OrdersChannel, order.Symbol); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// CancelOrder cancels the user's PENDING or WAITING order under the book lock.
+func (s *Storage) CancelOrder(ctx context.Context, orderID, userID string) (*Order, error) {
+	var o Order
+
+	if !isUUID(orderID) || !isUUID(userID) {
+		return nil, ErrOrderNotFound
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := lockBook(ctx, tx, orderID); err != nil {
+		return nil, err
+	}
+
+	query := `
+    UPDATE orders
+    SET status = 'CANCELLED'
+    WHERE id = $1 AND user_id = $2 AND status IN ('PENDING', 'WAITING')
+    RETURNING ` + orderColumns
+
+	err = scanOrder(tx.QueryRow(ctx, query, orderID, userID), &o)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
 
+	o.UpdateID, err = nextUpdateID(ctx, tx, o.Symbol)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if err := appendEvent(ctx, tx, o.Symbol, EventOrderCancelled, o); err != nil {
+		return nil, err
+	}
+
+	change := OrderStatusChange{OrderID: o.ID, Status: o.Status, Quantity: o.Quantity, FilledQuantity: o.FilledQuantity}
+
+	if err := appendEvent(ctx, tx, o.Symbol, EventOrderStatusChanged, change); err != nil {
+		return nil, err
+	}
+
+	if err := appendOutbox(ctx, tx, TopicCancels, o.ID, o.UserID, o); err != nil {
+		return nil, err
+	}
+
+	if err := appendOrderEvent(ctx, tx, o.UserID, OrderEventOrder, o); err != nil {
+		return nil, err
+	}
+
+	settled, err := settleGroup(ctx, tx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	o.Depth, err = readDepth(ctx, tx, o.Symbol, append(settled, o)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// GetOrder returns an order by ID. Quantity is what is left to fill.
+func (s *Storage) GetOrder(ctx context.Context, id string) (*Order, error) {
+	if !isUUID(id) {
+		return nil, ErrOrderNotFound
+	}
+
+	return s.getOrder(ctx, "id = $1", id)
+}
+
+// GetOrderByClientID returns one of a user's orders by its client order ID.
+func (s *Storage) GetOrderByClientID(ctx context.Context, userID, clientOrderID string) (*Order, error) {
+	if clientOrderID == "" || !isUUID(userID) {
+		return nil, ErrOrderNotFound
 	}
 
-	return id, nil
+	return s.getOrder(ctx, "user_id = $1 AND client_order_id = $2", userID, clientOrderID)
+}
+
+// orderColumns lists every column of an order, in the order scanOrder reads
+// them.
+const orderColumns = `id, COALESCE(user_id::text, ''), symbol, quantity, filled_quantity, price, side, status, created_at, priority_at,
+    COALESCE(client_order_id, ''), COALESCE(stp_mode, ''), COALESCE(display_quantity, 0), COALESCE(visible_quantity, 0),
+    order_type, COALESCE(stop_price, 0), COALESCE(group_id::text, ''), COALESCE(group_role, ''),
+    COALESCE(trailing_offset, 0), COALESCE(trailing_percent, 0), COALESCE(trailing_watermark, 0)`
+
+func scanOrder(row pgx.Row, o *Order) error {
+	return row.Scan(
+		&o.ID,
+		&o.UserID,
+		&o.Symbol,
+		&o.Quantity,
+		&o.FilledQuantity,
+		&o.Price,
+		&o.Side,
+		&o.Status,
+		&o.CreatedAt,
+		&o.PriorityAt,
+		&o.ClientOrderID,
+		&o.STPMode,
+		&o.DisplayQuantity,
+		&o.VisibleQuantity,
+		&o.Type,
+		&o.StopPrice,
+		&o.GroupID,
+		&o.GroupRole,
+		&o.TrailingOffset,
+		&o.TrailingPercent,
+		&o.TrailingWatermark,
+	)
+}
+
+func (s *Storage) getOrder(ctx context.Context, where string, args ...any) (*Order, error) {
+	var o Order
+
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE ` + where
+
+	err := scanOrder(s.db.QueryRow(ctx, query, args...), &o)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	return &o, nil
 }
 
 func (s *Storage) GetBestBuyOrder(ctx context.Context, symbol string) (*Order, error) {
 	var o Order
 
 	query := `
-    SELECT id, user_id, symbol, quantity, price, side, status, created_at 
+    SELECT id, user_id, symbol, quantity, price, side, status, created_at, COALESCE(stp_mode, ''),
+        COALESCE(display_quantity, 0), COALESCE(visible_quantity, 0)
     FROM orders 
     WHERE symbol = $1 AND side = 'BUY' AND quantity > 0 AND status = 'PENDING'
-    ORDER BY price DESC, created_at ASC 
+    ORDER BY price DESC, priority_at ASC
     LIMIT 1
     `
 
@@ -85,6 +490,9 @@ func (s *Storage) GetBestBuyOrder(ctx context.Context, symbol string) (*Order, e
 		&o.Side,
 		&o.Status,
 		&o.CreatedAt,
+		&o.STPMode,
+		&o.DisplayQuantity,
+		&o.VisibleQuantity,
 	)
 
 	if err != nil {
@@ -101,10 +509,11 @@ func (s *Storage) GetBestSellOrder(ctx context.Context, symbol string) (*Order,
 	var o Order
 
 	query := `
-    SELECT id, user_id, symbol, quantity, price, side, status, created_at
+    SELECT id, user_id, symbol, quantity, price, side, status, created_at, COALESCE(stp_mode, ''),
+        COALESCE(display_quantity, 0), COALESCE(visible_quantity, 0)
     FROM orders
     WHERE symbol = $1 AND side = 'SELL' AND quantity > 0 AND status = 'PENDING'
-    ORDER BY price ASC, created_at ASC
+    ORDER BY price ASC, priority_at ASC
     LIMIT 1`
 
 	err := s.db.QueryRow(ctx, query, symbol).Scan(
@@ -116,6 +525,9 @@ func (s *Storage) GetBestSellOrder(ctx context.Context, symbol string) (*Order,
 		&o.Side,
 		&o.Status,
 		&o.CreatedAt,
+		&o.STPMode,
+		&o.DisplayQuantity,
+		&o.VisibleQuantity,
 	)
 
 	if err != nil {
@@ -127,83 +539,27 @@ func (s *Storage) GetBestSellOrder(ctx context.Context, symbol string) (*Order,
 	return &o, nil
 }
 
-type OrderBookEntry struct {
-	Price    float64 `json:"price"`
-	Quantity float64 `json:"quantity"`
+func (s *Storage) GetOrderBook(ctx context.Context, symbol string) (*OrderBook, error) {
+	return s.GetOrderBookDepth(ctx, BookQuery{Symbol: symbol, Depth: DefaultBookDepth})
 }
 
-type OrderBook struct {
-	Bids []OrderBookEntry `json:"bids"`
-	Asks []OrderBookEntry `json:"asks"`
+// GetPriceLevel returns the shown quantity at one price on one side of the book.
+func (s *Storage) GetPriceLevel(ctx context.Context, symbol string, side OrderSide, price float64) (OrderBookEntry, error) {
+	return priceLevel(ctx, s.db, symbol, side, price)
 }
 
-func (s *Storage) GetOrderBook(ctx context.Context, symbol string) (*OrderBook, error) {
-
-	bidsSlice := []OrderBookEntry{}
-	asksSlice := []OrderBookEntry{}
-	var o OrderBook
-
-	buyQuery := `
-	SELECT price, SUM(quantity) 
-		FROM orders 
-		WHERE symbol = $1 AND side = 'BUY' AND status = 'PENDING' 
-		GROUP BY price 
-		ORDER BY price DESC 
-		LIMIT 20
-	`
-
-	bidsRows, bidsErrors := s.db.Query(ctx, buyQuery, symbol)
-
-	if bidsErrors != nil {
-		return nil, bidsErrors
-	}
-
-	defer bidsRows.Close()
-
-	for bidsRows.Next() {
-		oe := OrderBookEntry{}
-
-		rowError := bidsRows.Scan(&oe.Price, &oe.Quantity)
-
-		if rowError != nil {
-			return nil, fmt.Errorf("failed to fetch Trading Pair Row: %w", rowError)
-		}
-
-		bidsSlice = append(bidsSlice, oe)
-	}
+func priceLevel(ctx context.Context, db DBTX, symbol string, side OrderSide, price float64) (OrderBookEntry, error) {
+	level := OrderBookEntry{Price: price}
 
-	sellQuery := `
-	SELECT price, SUM(quantity) 
-		FROM orders 
-		WHERE symbol = $1 AND side = 'SELL' AND status = 'PENDING' 
-		GROUP BY price 
-		ORDER BY price ASC 
-		LIMIT 20
+	query := `
+	SELECT COALESCE(SUM(COALESCE(visible_quantity, quantity)), 0)
+		FROM orders
+		WHERE symbol = $1 AND side = $2 AND price = $3 AND status = 'PENDING' AND quantity > 0
 	`
 
-	asksRows, asksErrors := s.db.Query(ctx, sellQuery, symbol)
-
-	if asksErrors != nil {
-		return nil, asksErrors
-	}
-
-	defer asksRows.Close()
-
-	for asksRows.Next() {
-		oe := OrderBookEntry{}
-
-		rowError := asksRows.Scan(&oe.Price, &oe.Quantity)
-
-		if rowError != nil {
-			return nil, fmt.Errorf("failed to fetch Trading Pair Row: %w", rowError)
-		}
-
-		asksSlice = append(asksSlice, oe)
+	if err := db.QueryRow(ctx, query, symbol, string(side), price).Scan(&level.Quantity); err != nil {
+		return level, fmt.Errorf("failed to fetch price level: %w", err)
 	}
 
-	o.Asks = asksSlice
-	o.Bids = bidsSlice
-
-	return &o, nil
-
+	return level, nil
 }
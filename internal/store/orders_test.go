@@ -2,7 +2,9 @@ package store
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/Nevnet99/trade-engine/internal/testutils"
 )
@@ -28,12 +30,14 @@ func TestCreateOrder(t *testing.T) {
 		UserID:   u.ID,
 	}
 
-	id, err := storage.CreateOrder(ctx, newOrder)
+	created, err := storage.CreateOrder(ctx, newOrder)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
+	id := created.ID
+
 	if id == "" {
 		t.Errorf("Expected a generated ID, got empty string")
 	}
@@ -188,3 +192,181 @@ func TestGetOrderBook(t *testing.T) {
 		t.Errorf("Top ask incorrect. Expected 51k, got %v", book.Asks[0].Price)
 	}
 }
+
+func TestClientOrderID(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	alice, err := storage.CreateUser(ctx, &User{Username: "client_id_alice", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	bob, err := storage.CreateUser(ctx, &User{Username: "client_id_bob", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	order := Order{UserID: alice.ID, Symbol: "CID-USD", Side: "BUY", Price: 100, Quantity: 1, ClientOrderID: "bot-1"}
+
+	first, err := storage.CreateOrder(ctx, order)
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	t.Run("Rejects a malformed client order ID", func(t *testing.T) {
+		bad := order
+		bad.ClientOrderID = "has spaces"
+
+		if _, err := storage.CreateOrder(ctx, bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+	})
+
+	t.Run("Returns the existing order on reuse", func(t *testing.T) {
+		retry, err := storage.CreateOrder(ctx, order)
+
+		if !errors.Is(err, ErrDuplicateClientOrderID) {
+			t.Fatalf("Expected ErrDuplicateClientOrderID, got %v", err)
+		}
+		if retry == nil || retry.ID != first.ID {
+			t.Errorf("Expected the first order back, got %+v", retry)
+		}
+	})
+
+	t.Run("Scopes client order IDs to their user", func(t *testing.T) {
+		other := order
+		other.UserID = bob.ID
+
+		if _, err := storage.CreateOrder(ctx, other); err != nil {
+			t.Errorf("Expected another user to reuse the ID, got %v", err)
+		}
+
+		found, err := storage.GetOrderByClientID(ctx, alice.ID, "bot-1")
+		if err != nil || found.ID != first.ID || found.ClientOrderID != "bot-1" {
+			t.Errorf("Expected alice's order, got %+v, %v", found, err)
+		}
+
+		if _, err := storage.GetOrderByClientID(ctx, alice.ID, "bot-2"); !errors.Is(err, ErrOrderNotFound) {
+			t.Errorf("Expected ErrOrderNotFound, got %v", err)
+		}
+	})
+}
+
+func TestIcebergOrder(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	seller, err := storage.CreateUser(ctx, &User{Username: "iceberg_seller", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	buyer, err := storage.CreateUser(ctx, &User{Username: "iceberg_buyer", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Rejects a display quantity that hides nothing", func(t *testing.T) {
+		for _, display := range []int{-1, 10, 11} {
+			o := Order{UserID: seller.ID, Symbol: "ICE-USD", Side: "SELL", Price: 100, Quantity: 10, DisplayQuantity: display}
+
+			if _, err := storage.CreateOrder(ctx, o); !errors.Is(err, ErrValidation) {
+				t.Errorf("display %d: expected ErrValidation, got %v", display, err)
+			}
+		}
+	})
+
+	iceberg, err := storage.RestoreOrder(ctx, Order{
+		ID: "00000000-0000-4000-8000-0000000000c1", UserID: seller.ID, Symbol: "ICE-USD", Side: "SELL",
+		Price: 100, Quantity: 10, DisplayQuantity: 3, CreatedAt: baseTime,
+	})
+	if err != nil {
+		t.Fatalf("Failed to place iceberg: %v", err)
+	}
+
+	behind, err := storage.RestoreOrder(ctx, Order{
+		ID: "00000000-0000-4000-8000-0000000000c2", UserID: seller.ID, Symbol: "ICE-USD", Side: "SELL",
+		Price: 100, Quantity: 2, CreatedAt: baseTime.Add(time.Second),
+	})
+	if err != nil {
+		t.Fatalf("Failed to place order: %v", err)
+	}
+
+	bid, err := storage.CreateOrder(ctx, Order{UserID: buyer.ID, Symbol: "ICE-USD", Side: "BUY", Price: 100, Quantity: 10})
+	if err != nil {
+		t.Fatalf("Failed to place bid: %v", err)
+	}
+
+	t.Run("Shows only the visible slice", func(t *testing.T) {
+		level, err := storage.GetPriceLevel(ctx, "ICE-USD", Sell, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if level.Quantity != 5 {
+			t.Errorf("Expected 3 + 2 on the book, got %d", level.Quantity)
+		}
+
+		best, err := storage.GetBestSellOrder(ctx, "ICE-USD")
+		if err != nil || best == nil {
+			t.Fatalf("Failed to fetch best ask: %v", err)
+		}
+		if best.ID != iceberg.ID || best.Shown() != 3 {
+			t.Errorf("Expected the iceberg first showing 3, got %s showing %d", best.ID, best.Shown())
+		}
+	})
+
+	t.Run("Replenishes at the back of the queue", func(t *testing.T) {
+		if _, err := storage.CreateTrade(ctx, 100, 3, bid.ID, iceberg.ID); err != nil {
+			t.Fatalf("CreateTrade failed: %v", err)
+		}
+
+		o, err := storage.GetOrder(ctx, iceberg.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.Quantity != 7 || o.VisibleQuantity != 3 {
+			t.Errorf("Expected 7 left showing 3, got %d showing %d", o.Quantity, o.VisibleQuantity)
+		}
+
+		best, err := storage.GetBestSellOrder(ctx, "ICE-USD")
+		if err != nil || best == nil {
+			t.Fatalf("Failed to fetch best ask: %v", err)
+		}
+		if best.ID != behind.ID {
+			t.Errorf("Expected the replenished iceberg to lose priority to %s, got %s", behind.ID, best.ID)
+		}
+		if !o.CreatedAt.Equal(iceberg.CreatedAt) || !o.PriorityAt.After(o.CreatedAt) {
+			t.Errorf("Expected a new priority but the same created_at, got %v / %v", o.CreatedAt, o.PriorityAt)
+		}
+
+		events, err := storage.GetJournal(ctx, JournalQuery{Symbol: "ICE-USD", Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last := events[len(events)-1]
+		r, err := last.Replenishment()
+		if last.Type != EventOrderReplenished || err != nil || r.OrderID != iceberg.ID || r.VisibleQuantity != 3 {
+			t.Errorf("Expected the replenishment to be journaled, got %s %s", last.Type, last.Payload)
+		}
+	})
+
+	t.Run("Shows the remainder as the last slice", func(t *testing.T) {
+		for range 2 {
+			if _, err := storage.CreateTrade(ctx, 100, 3, bid.ID, iceberg.ID); err != nil {
+				t.Fatalf("CreateTrade failed: %v", err)
+			}
+		}
+
+		o, err := storage.GetOrder(ctx, iceberg.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.Quantity != 1 || o.VisibleQuantity != 1 {
+			t.Errorf("Expected 1 left showing 1, got %d showing %d", o.Quantity, o.VisibleQuantity)
+		}
+	})
+}
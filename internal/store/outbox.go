@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Outbox topics; all but trades belong to the user on the message.
+const (
+	TopicTrades     = "trades"
+	TopicFills      = "fills"
+	TopicCancels    = "cancels"
+	TopicSelfTrades = "self_trades"
+)
+
+// OutboxMessage is an event waiting to be delivered to downstream sinks.
+type OutboxMessage struct {
+	ID        int64           `json:"id"`
+	Topic     string          `json:"topic"`
+	Key       string          `json:"key"`
+	UserID    string          `json:"user_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	Attempts  int             `json:"-"`
+}
+
+func appendOutbox(ctx context.Context, db DBTX, topic, key, userID string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox message: %w", err)
+	}
+
+	query := `INSERT INTO outbox (topic, key, user_id, payload) VALUES ($1, $2, NULLIF($3, '')::uuid, $4)`
+
+	if _, err := db.Exec(ctx, query, topic, key, userID, data); err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// Where a sink that has never run starts reading the outbox.
+type OutboxStart string
+
+const (
+	// OutboxStartEarliest delivers every message still in the outbox.
+	OutboxStartEarliest OutboxStart = "EARLIEST"
+	// OutboxStartLatest delivers only messages written from about now on.
+	OutboxStartLatest OutboxStart = "LATEST"
+)
+
+// outboxSettleWindow is how old a message must be before a sink's floor may pass it.
+const outboxSettleWindow = time.Minute
+
+// RegisterOutboxSink records a sink the first time it runs, starting it at
+// start. A sink that is already registered carries on where it was.
+func (s *Storage) RegisterOutboxSink(ctx context.Context, sink string, start OutboxStart) error {
+	if start != OutboxStartEarliest && start != OutboxStartLatest {
+		return fmt.Errorf("start must be EARLIEST or LATEST: %w", ErrValidation)
+	}
+
+	query := `
+	INSERT INTO outbox_sinks (sink, settled_through)
+	SELECT $1, CASE WHEN $2 = 'LATEST' THEN COALESCE(MAX(id), 0) ELSE 0 END FROM outbox
+	ON CONFLICT (sink) DO NOTHING
+	`
+
+	if _, err := s.db.Exec(ctx, query, sink, string(start)); err != nil {
+		return fmt.Errorf("failed to register outbox sink: %w", err)
+	}
+
+	return nil
+}
+
+// AdvanceOutboxSink moves a sink's floor past the messages it has settled.
+func (s *Storage) AdvanceOutboxSink(ctx context.Context, sink string) error {
+	query := `
+	UPDATE outbox_sinks s
+	SET settled_through = COALESCE(
+		(SELECT MIN(o.id) - 1
+			FROM outbox o
+			LEFT JOIN outbox_deliveries d ON d.message_id = o.id AND d.sink = s.sink
+			WHERE o.id > s.settled_through
+			  AND (o.created_at > NOW() - $2 * INTERVAL '1 second'
+			       OR (d.delivered_at IS NULL AND d.dead_lettered_at IS NULL))),
+		(SELECT MAX(o.id) FROM outbox o WHERE o.id > s.settled_through),
+		s.settled_through)
+	WHERE s.sink = $1
+	`
+
+	if _, err := s.db.Exec(ctx, query, sink, outboxSettleWindow.Seconds()); err != nil {
+		return fmt.Errorf("failed to advance outbox sink: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingOutbox returns the oldest messages above sink's floor it has not settled.
+func (s *Storage) GetPendingOutbox(ctx context.Context, sink string, limit int) ([]OutboxMessage, error) {
+	messages := []OutboxMessage{}
+
+	query := `
+	SELECT o.id, o.topic, o.key, COALESCE(o.user_id::text, ''), o.payload, o.created_at, COALESCE(d.attempts, 0)
+		FROM outbox o
+		LEFT JOIN outbox_deliveries d ON d.message_id = o.id AND d.sink = $1
+		WHERE o.id > COALESCE((SELECT settled_through FROM outbox_sinks WHERE sink = $1), 0)
+		  AND d.delivered_at IS NULL AND d.dead_lettered_at IS NULL
+		ORDER BY o.id ASC
+		LIMIT $2
+	`
+
+	rows, err := s.db.Query(ctx, query, sink, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var m OutboxMessage
+
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Key, &m.UserID, &m.Payload, &m.CreatedAt, &m.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to fetch outbox message: %w", err)
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// RecordOutboxDelivery counts an attempt to deliver a message to sink. A nil
+// deliveryErr marks the message delivered.
+func (s *Storage) RecordOutboxDelivery(ctx context.Context, messageID int64, sink string, deliveryErr error) error {
+	var lastError *string
+	if deliveryErr != nil {
+		msg := deliveryErr.Error()
+		lastError = &msg
+	}
+
+	query := `
+	INSERT INTO outbox_deliveries (message_id, sink, attempts, last_error, delivered_at)
+	VALUES ($1, $2, 1, $3, CASE WHEN $3::text IS NULL THEN NOW() END)
+	ON CONFLICT (message_id, sink) DO UPDATE SET
+		attempts = outbox_deliveries.attempts + 1,
+		last_error = EXCLUDED.last_error,
+		delivered_at = EXCLUDED.delivered_at
+	`
+
+	if _, err := s.db.Exec(ctx, query, messageID, sink, lastError); err != nil {
+		return fmt.Errorf("failed to record outbox delivery: %w", err)
+	}
+
+	return nil
+}
+
+// DeadLetterOutbox gives up on delivering a message to sink. It stays in
+// outbox_deliveries with its last error until it is pruned.
+func (s *Storage) DeadLetterOutbox(ctx context.Context, messageID int64, sink string) error {
+	query := `UPDATE outbox_deliveries SET dead_lettered_at = NOW() WHERE message_id = $1 AND sink = $2`
+
+	if _, err := s.db.Exec(ctx, query, messageID, sink); err != nil {
+		return fmt.Errorf("failed to dead-letter outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// PruneOutbox deletes up to limit settled messages older than retention.
+func (s *Storage) PruneOutbox(ctx context.Context, retention time.Duration, limit int) (int64, error) {
+	query := `
+	WITH pruned AS (
+		SELECT o.id
+			FROM outbox o
+			WHERE o.created_at < NOW() - $1 * INTERVAL '1 second'
+			  AND o.id <= (SELECT COALESCE(MIN(settled_through), 0) FROM outbox_sinks)
+			  AND NOT EXISTS (
+				SELECT 1 FROM webhook_deliveries w WHERE w.message_id = o.id AND w.status = 'PENDING'
+			  )
+			ORDER BY o.id
+			LIMIT $2
+	), deliveries AS (
+		DELETE FROM outbox_deliveries WHERE message_id IN (SELECT id FROM pruned)
+	), webhooks AS (
+		DELETE FROM webhook_deliveries WHERE message_id IN (SELECT id FROM pruned)
+	)
+	DELETE FROM outbox WHERE id IN (SELECT id FROM pruned)
+	`
+
+	tag, err := s.db.Exec(ctx, query, retention.Seconds(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune outbox: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
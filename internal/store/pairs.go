@@ -2,20 +2,79 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
+var ErrPairNotFound = errors.New("trading pair not found")
+
 type TradingPair struct {
 	Symbol     string `json:"symbol"`
 	BaseAsset  string `json:"base_asset"`
 	QuoteAsset string `json:"quote_asset"`
 	IsActive   bool   `json:"is_active"`
+	PairProtection
+	// AuctionEnded reports whether HaltedUntil has passed by the database's clock.
+	Phase        TradingPhase `json:"trading_phase"`
+	HaltedUntil  *time.Time   `json:"halted_until,omitempty"`
+	AuctionEnded bool         `json:"-"`
+	AuctionPrice float64      `json:"auction_price,omitempty"`
+	LastPrice    float64      `json:"last_price,omitempty"`
+	// OpeningAuctionSeconds is how long a new pair's opening auction lasts.
+	OpeningAuctionSeconds int `json:"opening_auction_seconds,omitempty"`
+}
+
+// PairProtection guards a pair against orders and trades far from its
+// price. Zero fields turn a protection off.
+type PairProtection struct {
+	// PriceBandPercent rejects orders priced further than this from the
+	// last trade price, or from ReferencePrice before the pair has traded.
+	PriceBandPercent float64 `json:"price_band_percent,omitempty"`
+	ReferencePrice   float64 `json:"reference_price,omitempty"`
+	// CircuitBreakerPercent is how far a trade may move from the breaker's window before it halts.
+	CircuitBreakerPercent       float64 `json:"circuit_breaker_percent,omitempty"`
+	CircuitBreakerWindowSeconds int     `json:"circuit_breaker_window_seconds,omitempty"`
+	CircuitBreakerHaltSeconds   int     `json:"circuit_breaker_halt_seconds,omitempty"`
+}
+
+func (p PairProtection) validate() error {
+	if p.PriceBandPercent < 0 || p.PriceBandPercent >= 100 || p.CircuitBreakerPercent < 0 || p.CircuitBreakerPercent >= 100 {
+		return fmt.Errorf("percentages must be between 0 and 100: %w", ErrValidation)
+	}
+	if p.ReferencePrice < 0 {
+		return fmt.Errorf("reference_price must not be negative: %w", ErrValidation)
+	}
+	if p.CircuitBreakerWindowSeconds < 0 || p.CircuitBreakerHaltSeconds < 0 {
+		return fmt.Errorf("circuit breaker durations must not be negative: %w", ErrValidation)
+	}
+	if (p.CircuitBreakerPercent > 0) != (p.CircuitBreakerWindowSeconds > 0 && p.CircuitBreakerHaltSeconds > 0) {
+		return fmt.Errorf("a circuit breaker needs a percentage, a window and a halt: %w", ErrValidation)
+	}
+	return nil
+}
+
+const pairColumns = `symbol, base_asset, quote_asset, is_active,
+    COALESCE(price_band_percent, 0), COALESCE(reference_price, 0),
+    COALESCE(circuit_breaker_percent, 0), COALESCE(circuit_breaker_window_seconds, 0), COALESCE(circuit_breaker_halt_seconds, 0),
+    trading_phase, halted_until, COALESCE(halted_until <= NOW(), FALSE), COALESCE(auction_price, 0),
+    COALESCE((SELECT last_price FROM book_sequences b WHERE b.symbol = trading_pairs.symbol), 0),
+    COALESCE(opening_auction_seconds, 0)`
+
+func scanPair(row pgx.Row, p *TradingPair) error {
+	return row.Scan(&p.Symbol, &p.BaseAsset, &p.QuoteAsset, &p.IsActive,
+		&p.PriceBandPercent, &p.ReferencePrice,
+		&p.CircuitBreakerPercent, &p.CircuitBreakerWindowSeconds, &p.CircuitBreakerHaltSeconds,
+		&p.Phase, &p.HaltedUntil, &p.AuctionEnded, &p.AuctionPrice, &p.LastPrice,
+		&p.OpeningAuctionSeconds)
 }
 
 func (s *Storage) GetActiveTradingPairs(ctx context.Context) ([]TradingPair, error) {
 	query := `
-        SELECT symbol, base_asset, quote_asset, is_active 
-        FROM trading_pairs 
+        SELECT ` + pairColumns + `
+        FROM trading_pairs
         WHERE is_active = true
     `
 
@@ -32,7 +91,7 @@ func (s *Storage) GetActiveTradingPairs(ctx context.Context) ([]TradingPair, err
 	for rows.Next() {
 		pair := TradingPair{}
 
-		rowError := rows.Scan(&pair.Symbol, &pair.BaseAsset, &pair.QuoteAsset, &pair.IsActive)
+		rowError := scanPair(rows, &pair)
 
 		if rowError != nil {
 			return nil, fmt.Errorf("failed to fetch Trading Pair Row: %w", rowError)
@@ -44,3 +103,92 @@ func (s *Storage) GetActiveTradingPairs(ctx context.Context) ([]TradingPair, err
 	return pairs, nil
 
 }
+
+// SetPairProtection replaces a pair's price band and circuit breaker. It
+// does not end a halt already in progress.
+func (s *Storage) SetPairProtection(ctx context.Context, symbol string, p PairProtection) (*TradingPair, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	query := `
+    UPDATE trading_pairs
+    SET price_band_percent = NULLIF($2::numeric, 0),
+        reference_price = NULLIF($3::numeric, 0),
+        circuit_breaker_percent = NULLIF($4::numeric, 0),
+        circuit_breaker_window_seconds = NULLIF($5::int, 0),
+        circuit_breaker_halt_seconds = NULLIF($6::int, 0)
+    WHERE symbol = $1
+    RETURNING ` + pairColumns
+
+	var pair TradingPair
+
+	err := scanPair(s.db.QueryRow(ctx, query, symbol,
+		p.PriceBandPercent, p.ReferencePrice,
+		p.CircuitBreakerPercent, p.CircuitBreakerWindowSeconds, p.CircuitBreakerHaltSeconds,
+	), &pair)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPairNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set pair protection: %w", err)
+	}
+
+	return &pair, nil
+}
+
+// checkPriceBand rejects a price further from the symbol's last trade, or
+// its reference price before the first trade, than its price band allows.
+func checkPriceBand(ctx context.Context, db DBTX, symbol string, price float64) error {
+	query := `
+    SELECT p.price_band_percent, COALESCE(b.last_price, p.reference_price)
+    FROM trading_pairs p
+    LEFT JOIN book_sequences b ON b.symbol = p.symbol
+    WHERE p.symbol = $1 AND p.price_band_percent IS NOT NULL`
+
+	var band float64
+	var reference *float64
+
+	err := db.QueryRow(ctx, query, symbol).Scan(&band, &reference)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && reference == nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch price band: %w", err)
+	}
+
+	if low, high := *reference*(1-band/100), *reference*(1+band/100); price < low || price > high {
+		return fmt.Errorf("price must be within %v%% of %v, between %.2f and %.2f: %w", band, *reference, low, high, ErrValidation)
+	}
+
+	return nil
+}
+
+// tripCircuitBreaker starts a re-opening auction if a trade at price breaks the circuit breaker.
+func tripCircuitBreaker(ctx context.Context, db DBTX, symbol string, price float64) error {
+	query := `
+    UPDATE trading_pairs p
+    SET trading_phase = 'AUCTION',
+        halted_until = NOW() + make_interval(secs => p.circuit_breaker_halt_seconds)
+    WHERE p.symbol = $1 AND p.trading_phase = 'CONTINUOUS' AND p.circuit_breaker_percent IS NOT NULL
+      AND EXISTS (
+          SELECT 1 FROM trades t
+          JOIN orders o ON t.bid_order_id = o.id
+          WHERE o.symbol = $1
+            AND t.timestamp >= NOW() - make_interval(secs => p.circuit_breaker_window_seconds)
+            AND ABS($2::numeric - t.price) * 100 > t.price * p.circuit_breaker_percent
+      )
+    RETURNING halted_until`
+
+	change := TradingPhaseChange{Phase: PhaseAuction}
+
+	err := db.QueryRow(ctx, query, symbol, price).Scan(&change.EndsAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check circuit breaker: %w", err)
+	}
+
+	return appendEvent(ctx, db, symbol, EventTradingPhaseChanged, change)
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/Nevnet99/trade-engine/internal/testutils"
@@ -65,3 +66,106 @@ func TestGetActiveTradingPairs(t *testing.T) {
 	}
 
 }
+
+func TestPairProtection(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "band_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	for _, symbol := range []string{"BND-USD", "CBK-USD"} {
+		if _, err := tx.Exec(ctx, `INSERT INTO trading_pairs (symbol, base_asset, quote_asset, trading_phase) VALUES ($1, 'X', 'USD', 'CONTINUOUS')`, symbol); err != nil {
+			t.Fatalf("Failed to seed DB: %v", err)
+		}
+	}
+
+	place := func(symbol, side string, price float64) (*Order, error) {
+		return storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: symbol, Side: side, Price: price, Quantity: 1})
+	}
+
+	t.Run("Validates the protection", func(t *testing.T) {
+		for _, p := range []PairProtection{
+			{PriceBandPercent: 100},
+			{ReferencePrice: -1},
+			{CircuitBreakerPercent: 5},
+			{CircuitBreakerPercent: 5, CircuitBreakerWindowSeconds: 60},
+			{CircuitBreakerWindowSeconds: 60, CircuitBreakerHaltSeconds: 300},
+		} {
+			if _, err := storage.SetPairProtection(ctx, "BND-USD", p); !errors.Is(err, ErrValidation) {
+				t.Errorf("%+v: expected ErrValidation, got %v", p, err)
+			}
+		}
+
+		if _, err := storage.SetPairProtection(ctx, "NOPE-USD", PairProtection{}); !errors.Is(err, ErrPairNotFound) {
+			t.Errorf("Expected ErrPairNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Rejects orders outside the band around the reference price", func(t *testing.T) {
+		pair, err := storage.SetPairProtection(ctx, "BND-USD", PairProtection{PriceBandPercent: 10, ReferencePrice: 100})
+		if err != nil {
+			t.Fatalf("SetPairProtection failed: %v", err)
+		}
+		if pair.PriceBandPercent != 10 || pair.ReferencePrice != 100 || pair.HaltedUntil != nil {
+			t.Errorf("Expected a 10%% band around 100, got %+v", pair)
+		}
+
+		if _, err := place("BND-USD", "SELL", 89); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation below the band, got %v", err)
+		}
+
+		order, err := place("BND-USD", "SELL", 110)
+		if err != nil {
+			t.Fatalf("Expected an order at the edge of the band, got %v", err)
+		}
+
+		if _, _, err := storage.AmendOrder(ctx, order.ID, u.ID, OrderAmendment{Price: 111}); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation amending out of the band, got %v", err)
+		}
+	})
+
+	t.Run("Trips the circuit breaker on a large move", func(t *testing.T) {
+		if _, err := storage.SetPairProtection(ctx, "CBK-USD", PairProtection{
+			CircuitBreakerPercent: 5, CircuitBreakerWindowSeconds: 60, CircuitBreakerHaltSeconds: 300,
+		}); err != nil {
+			t.Fatalf("SetPairProtection failed: %v", err)
+		}
+
+		trade := func(price float64) {
+			t.Helper()
+
+			buy, err := place("CBK-USD", "BUY", price)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sell, err := place("CBK-USD", "SELL", price)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := storage.CreateTrade(ctx, price, 1, buy.ID, sell.ID); err != nil {
+				t.Fatalf("CreateTrade failed: %v", err)
+			}
+		}
+
+		trade(100)
+		trade(104)
+
+		if pair, err := storage.GetTradingPair(ctx, "CBK-USD"); err != nil || pair.Phase != PhaseContinuous {
+			t.Fatalf("Expected no halt within the breaker, got %+v, %v", pair, err)
+		}
+
+		trade(94)
+
+		pair, err := storage.GetTradingPair(ctx, "CBK-USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pair.Phase != PhaseAuction || pair.HaltedUntil == nil {
+			t.Errorf("Expected a move of more than 5%% to halt matching for an auction, got %+v", pair)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// nextUpdateID advances a symbol's book sequence in the transaction of the change it numbers.
+func nextUpdateID(ctx context.Context, db DBTX, symbol string) (int64, error) {
+	var id int64
+
+	query := `
+	INSERT INTO book_sequences (symbol, last_update_id)
+	VALUES ($1, 1)
+	ON CONFLICT (symbol) DO UPDATE SET last_update_id = book_sequences.last_update_id + 1
+	RETURNING last_update_id
+	`
+
+	if err := db.QueryRow(ctx, query, symbol).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to advance book sequence: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetLastUpdateID returns the sequence number of the latest book change for a
+// symbol, or zero if its book has never changed.
+func (s *Storage) GetLastUpdateID(ctx context.Context, symbol string) (int64, error) {
+	var id int64
+
+	query := `SELECT COALESCE(MAX(last_update_id), 0) FROM book_sequences WHERE symbol = $1`
+
+	if err := s.db.QueryRow(ctx, query, symbol).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to fetch book sequence: %w", err)
+	}
+
+	return id, nil
+}
+
+// lockBook serialises changes to the book of orderID's symbol for the rest of the transaction.
+func lockBook(ctx context.Context, db DBTX, orderID string) error {
+	query := `SELECT pg_advisory_xact_lock(hashtextextended(symbol, 0)) FROM orders WHERE id = $1`
+
+	if _, err := db.Exec(ctx, query, orderID); err != nil {
+		return fmt.Errorf("failed to lock book: %w", err)
+	}
+
+	return nil
+}
+
+// lockSequence takes the book sequence lock without advancing it.
+func lockSequence(ctx context.Context, db DBTX, symbol string) error {
+	if _, err := db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))`, symbol); err != nil {
+		return fmt.Errorf("failed to lock book: %w", err)
+	}
+
+	query := `
+	INSERT INTO book_sequences (symbol, last_update_id)
+	VALUES ($1, 0)
+	ON CONFLICT (symbol) DO UPDATE SET last_update_id = book_sequences.last_update_id
+	`
+
+	if _, err := db.Exec(ctx, query, symbol); err != nil {
+		return fmt.Errorf("failed to lock book sequence: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestBookSequence(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "seq_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	book, err := storage.GetOrderBook(ctx, "SEQ-USD")
+	if err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	if book.LastUpdateID != 0 {
+		t.Errorf("Expected an untouched book to be at 0, got %d", book.LastUpdateID)
+	}
+
+	bid, err := storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "SEQ-USD", Side: "BUY", Price: 100, Quantity: 2})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	ask, err := storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "SEQ-USD", Side: "SELL", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	if bid.UpdateID != 1 || ask.UpdateID != 2 {
+		t.Errorf("Expected update IDs 1 and 2, got %d and %d", bid.UpdateID, ask.UpdateID)
+	}
+
+	trade, err := storage.CreateTrade(ctx, 100, 1, bid.ID, ask.ID)
+	if err != nil {
+		t.Fatalf("CreateTrade failed: %v", err)
+	}
+	if trade.UpdateID != 3 {
+		t.Errorf("Expected the trade to be update 3, got %d", trade.UpdateID)
+	}
+
+	t.Run("Snapshot reports the latest update", func(t *testing.T) {
+		book, err := storage.GetOrderBook(ctx, "SEQ-USD")
+		if err != nil {
+			t.Fatalf("GetOrderBook failed: %v", err)
+		}
+
+		if book.LastUpdateID != 3 {
+			t.Errorf("Expected lastUpdateId 3, got %d", book.LastUpdateID)
+		}
+	})
+
+	t.Run("Sequences are per symbol", func(t *testing.T) {
+		other, err := storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "SEQ-EUR", Side: "BUY", Price: 100, Quantity: 1})
+		if err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+
+		if other.UpdateID != 1 {
+			t.Errorf("Expected a new symbol to start at 1, got %d", other.UpdateID)
+		}
+	})
+}
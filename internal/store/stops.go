@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// stopTriggered matches WAITING stops the last trade has reached, in an ACTIVE group if any.
+const stopTriggered = `status = 'WAITING' AND stop_price IS NOT NULL
+    AND (group_id IS NULL OR group_id IN (SELECT id FROM order_groups WHERE status = 'ACTIVE'))
+    AND (SELECT CASE orders.side WHEN 'SELL' THEN b.last_price <= orders.stop_price ELSE b.last_price >= orders.stop_price END
+        FROM book_sequences b WHERE b.symbol = orders.symbol)`
+
+// trailQuery moves trailing stops' watermarks, and stop prices, to a better price.
+const trailQuery = `
+    UPDATE orders o
+    SET trailing_watermark = t.watermark,
+        stop_price = CASE o.side
+            WHEN 'SELL' THEN t.watermark - COALESCE(o.trailing_offset, t.watermark * o.trailing_percent / 100)
+            ELSE t.watermark + COALESCE(o.trailing_offset, t.watermark * o.trailing_percent / 100)
+        END
+    FROM (
+        SELECT id, CASE side WHEN 'SELL' THEN GREATEST(trailing_watermark, %[1]s) ELSE LEAST(trailing_watermark, %[1]s) END AS watermark
+        FROM orders
+        WHERE %[2]s AND status = 'WAITING' AND order_type = 'TRAILING_STOP'
+    ) t
+    WHERE o.id = t.id AND t.watermark IS NOT NULL AND t.watermark IS DISTINCT FROM o.trailing_watermark`
+
+// trailStops ratchets a symbol's trailing stops in the transaction of a trade at price.
+func trailStops(ctx context.Context, db DBTX, symbol string, price float64) error {
+	if _, err := db.Exec(ctx, fmt.Sprintf(trailQuery, "$2::numeric", "symbol = $1"), symbol, price); err != nil {
+		return fmt.Errorf("failed to trail stops: %w", err)
+	}
+
+	return nil
+}
+
+// startTrail starts a new trailing stop's watermark at the last trade price.
+func startTrail(ctx context.Context, db DBTX, order *Order) error {
+	query := fmt.Sprintf(trailQuery, "(SELECT last_price FROM book_sequences b WHERE b.symbol = orders.symbol)", "id = $1") +
+		` RETURNING o.stop_price, o.trailing_watermark`
+
+	err := db.QueryRow(ctx, query, order.ID).Scan(&order.StopPrice, &order.TrailingWatermark)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to start trail: %w", err)
+	}
+
+	return nil
+}
+
+// TriggerStop puts a symbol's oldest triggered stop on the book, or returns nil if there is none.
+func (s *Storage) TriggerStop(ctx context.Context, symbol string) (*Order, error) {
+	candidate, err := s.getOrder(ctx, "symbol = $1 AND "+stopTriggered+" ORDER BY created_at, id LIMIT 1", symbol)
+	if errors.Is(err, ErrOrderNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch triggered stop: %w", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := lockBook(ctx, tx, candidate.ID); err != nil {
+		return nil, err
+	}
+
+	var o Order
+
+	query := `
+    UPDATE orders
+    SET status = 'PENDING',
+        created_at = clock.ts,
+        priority_at = clock.ts,
+        price = CASE WHEN order_type = 'TRAILING_STOP' THEN stop_price ELSE price END
+    FROM (SELECT clock_timestamp()::timestamp AS ts) clock
+    WHERE id = $1 AND ` + stopTriggered + `
+    RETURNING ` + orderColumns
+
+	if err := scanOrder(tx.QueryRow(ctx, query, candidate.ID), &o); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrStaleMatch
+		}
+		return nil, fmt.Errorf("failed to trigger stop: %w", err)
+	}
+
+	o.UpdateID, err = nextUpdateID(ctx, tx, o.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appendEvent(ctx, tx, o.Symbol, EventOrderTriggered, o); err != nil {
+		return nil, err
+	}
+
+	if err := appendOrderEvent(ctx, tx, o.UserID, OrderEventOrder, o); err != nil {
+		return nil, err
+	}
+
+	settled, err := settleGroup(ctx, tx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	o.Depth, err = readDepth(ctx, tx, o.Symbol, append(settled, o)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// RestorePriority gives a replayed PENDING order back its journaled created_at and priority_at.
+func (s *Storage) RestorePriority(ctx context.Context, orderID string, createdAt, priorityAt time.Time) error {
+	query := `UPDATE orders SET created_at = $2, priority_at = $3 WHERE id = $1 AND status = 'PENDING'`
+
+	tag, err := s.db.Exec(ctx, query, orderID, createdAt.UTC(), priorityAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to restore priority: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrOrderNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestTrailingStop(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "trailing_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &User{Username: "trailing_counterparty", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	trade := func(symbol string, price float64) {
+		t.Helper()
+
+		buy, err := storage.CreateOrder(ctx, Order{UserID: other.ID, Symbol: symbol, Side: "BUY", Price: price, Quantity: 1})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+		sell, err := storage.CreateOrder(ctx, Order{UserID: other.ID, Symbol: symbol, Side: "SELL", Price: price, Quantity: 1})
+		if err != nil {
+			t.Fatalf("Failed to place order: %v", err)
+		}
+		if _, err := storage.CreateTrade(ctx, price, 1, buy.ID, sell.ID); err != nil {
+			t.Fatalf("CreateTrade failed: %v", err)
+		}
+	}
+
+	trail := func(id string) (stop, watermark float64) {
+		t.Helper()
+
+		o, err := storage.GetOrder(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o.StopPrice, o.TrailingWatermark
+	}
+
+	t.Run("Validates the trail", func(t *testing.T) {
+		for _, o := range []Order{
+			{Type: TrailingStop},
+			{Type: TrailingStop, TrailingOffset: 5, TrailingPercent: 5},
+			{Type: TrailingStop, TrailingPercent: 100},
+			{Type: TrailingStop, TrailingOffset: 5, Price: 100},
+			{Type: TrailingStop, TrailingOffset: 5, StopPrice: 90},
+			{Type: Limit, TrailingOffset: 5, Price: 100},
+		} {
+			o.UserID, o.Symbol, o.Side, o.Quantity = u.ID, "TRL-USD", "SELL", 1
+
+			if _, err := storage.CreateOrder(ctx, o); !errors.Is(err, ErrValidation) {
+				t.Errorf("%+v: expected ErrValidation, got %v", o, err)
+			}
+		}
+	})
+
+	t.Run("A SELL stop ratchets up and triggers on the retrace", func(t *testing.T) {
+		trade("TRS-USD", 100)
+
+		stop, err := storage.CreateOrder(ctx, Order{
+			UserID: u.ID, Symbol: "TRS-USD", Side: "SELL", Quantity: 1, Type: TrailingStop, TrailingOffset: 5,
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+		if stop.Status != "WAITING" || stop.StopPrice != 95 || stop.TrailingWatermark != 100 {
+			t.Fatalf("Expected a waiting stop at 95 off 100, got %+v", stop)
+		}
+
+		trade("TRS-USD", 110)
+		trade("TRS-USD", 106)
+
+		if got, watermark := trail(stop.ID); got != 105 || watermark != 110 {
+			t.Errorf("Expected the stop to follow the high to 105 and stay there, got %v off %v", got, watermark)
+		}
+		if triggered, err := storage.TriggerStop(ctx, "TRS-USD"); err != nil || triggered != nil {
+			t.Fatalf("Expected nothing to trigger above the stop, got %+v, %v", triggered, err)
+		}
+
+		trade("TRS-USD", 105)
+
+		triggered, err := storage.TriggerStop(ctx, "TRS-USD")
+		if err != nil {
+			t.Fatalf("TriggerStop failed: %v", err)
+		}
+		if triggered == nil || triggered.ID != stop.ID || triggered.Status != "PENDING" || triggered.Price != 105 {
+			t.Errorf("Expected the stop to rest at 105, got %+v", triggered)
+		}
+	})
+
+	t.Run("A BUY stop follows the low by a percentage", func(t *testing.T) {
+		stop, err := storage.CreateOrder(ctx, Order{
+			UserID: u.ID, Symbol: "TRB-USD", Side: "BUY", Quantity: 1, Type: TrailingStop, TrailingPercent: 10,
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+		if stop.StopPrice != 0 {
+			t.Errorf("Expected no stop price before the first trade, got %v", stop.StopPrice)
+		}
+
+		trade("TRB-USD", 100)
+		trade("TRB-USD", 90)
+		trade("TRB-USD", 95)
+
+		if got, watermark := trail(stop.ID); got != 99 || watermark != 90 {
+			t.Errorf("Expected the stop at 99 off a low of 90, got %v off %v", got, watermark)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// STPMode says how a would-be self-trade is prevented. The mode of the
+// newer (taker) order decides.
+type STPMode string
+
+const (
+	// STPCancelNewest cancels the incoming order and leaves the resting one.
+	STPCancelNewest STPMode = "CANCEL_NEWEST"
+	// STPCancelOldest cancels the resting order so the incoming one can
+	// carry on matching.
+	STPCancelOldest STPMode = "CANCEL_OLDEST"
+	// STPCancelBoth cancels both orders.
+	STPCancelBoth STPMode = "CANCEL_BOTH"
+	// STPDecrementAndCancel takes the smaller quantity off both orders,
+	// cancelling whichever is left with nothing.
+	STPDecrementAndCancel STPMode = "DECREMENT_AND_CANCEL"
+)
+
+// DefaultSTPMode applies to orders and users that have not chosen one.
+const DefaultSTPMode = STPCancelNewest
+
+var STPModes = []STPMode{STPCancelNewest, STPCancelOldest, STPCancelBoth, STPDecrementAndCancel}
+
+func validateSTPMode(mode STPMode) error {
+	if mode == "" {
+		return nil
+	}
+
+	for _, m := range STPModes {
+		if mode == m {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("stp_mode must be CANCEL_NEWEST, CANCEL_OLDEST, CANCEL_BOTH or DECREMENT_AND_CANCEL: %w", ErrValidation)
+}
+
+// SelfTradePrevention records a trade that was not printed because both sides were one user's.
+type SelfTradePrevention struct {
+	Symbol      string   `json:"symbol"`
+	UserID      string   `json:"user_id"`
+	BuyOrderID  string   `json:"buy_order_id"`
+	SellOrderID string   `json:"sell_order_id"`
+	Mode        STPMode  `json:"mode"`
+	Quantity    int      `json:"quantity"`
+	Cancelled   []string `json:"cancelled_order_ids"`
+	// UpdateID and Depth are only set on the prevention PreventSelfTrade returns.
+	UpdateID int64        `json:"-"`
+	Depth    *DepthChange `json:"-"`
+}
+
+// SetSTPMode sets the mode a user's orders get when they do not name one.
+func (s *Storage) SetSTPMode(ctx context.Context, userID string, mode STPMode) error {
+	if mode == "" {
+		return fmt.Errorf("stp_mode is required: %w", ErrValidation)
+	}
+	if err := validateSTPMode(mode); err != nil {
+		return err
+	}
+
+	tag, err := s.db.Exec(ctx, `UPDATE users SET stp_mode = $2 WHERE id = $1`, userID, string(mode))
+	if err != nil {
+		return fmt.Errorf("failed to set stp mode: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// PreventSelfTrade applies the taker's STP mode to two crossing orders of one user.
+func (s *Storage) PreventSelfTrade(ctx context.Context, buyOrder, sellOrder *Order) (*SelfTradePrevention, error) {
+	if buyOrder.UserID == "" || buyOrder.UserID != sellOrder.UserID {
+		return nil, fmt.Errorf("orders %s and %s do not belong to the same user", buyOrder.ID, sellOrder.ID)
+	}
+
+	newest, oldest := buyOrder, sellOrder
+	if sellOrder.CreatedAt.After(buyOrder.CreatedAt) {
+		newest, oldest = sellOrder, buyOrder
+	}
+
+	mode := newest.STPMode
+	if mode == "" {
+		mode = DefaultSTPMode
+	}
+
+	p := SelfTradePrevention{
+		Symbol:      buyOrder.Symbol,
+		UserID:      buyOrder.UserID,
+		BuyOrderID:  buyOrder.ID,
+		SellOrderID: sellOrder.ID,
+		Mode:        mode,
+		Cancelled:   []string{},
+	}
+
+	cancel := map[string]bool{}
+
+	switch mode {
+	case STPCancelNewest:
+		cancel[newest.ID] = true
+	case STPCancelOldest:
+		cancel[oldest.ID] = true
+	case STPCancelBoth:
+		cancel[newest.ID] = true
+		cancel[oldest.ID] = true
+	case STPDecrementAndCancel:
+		p.Quantity = min(buyOrder.Quantity, sellOrder.Quantity)
+		cancel[buyOrder.ID] = buyOrder.Quantity == p.Quantity
+		cancel[sellOrder.ID] = sellOrder.Quantity == p.Quantity
+	default:
+		return nil, fmt.Errorf("unknown stp mode %q", mode)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := lockBook(ctx, tx, buyOrder.ID); err != nil {
+		return nil, err
+	}
+
+	query := `
+    UPDATE orders
+    SET quantity = quantity - $2,
+        visible_quantity = CASE WHEN display_quantity IS NOT NULL THEN LEAST(visible_quantity, quantity - $2) END,
+        status = CASE WHEN $3 THEN 'CANCELLED' ELSE status END
+    WHERE id = $1 AND status = 'PENDING' AND quantity = $4
+    RETURNING quantity, filled_quantity, status`
+
+	changes := []OrderStatusChange{}
+
+	for _, o := range []*Order{buyOrder, sellOrder} {
+		if !cancel[o.ID] && p.Quantity == 0 {
+			continue
+		}
+
+		change := OrderStatusChange{OrderID: o.ID}
+
+		err := tx.QueryRow(ctx, query, o.ID, p.Quantity, cancel[o.ID], o.Quantity).Scan(&change.Quantity, &change.FilledQuantity, &change.Status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrStaleMatch
+			}
+			return nil, fmt.Errorf("failed to prevent self-trade: %w", err)
+		}
+
+		if cancel[o.ID] {
+			p.Cancelled = append(p.Cancelled, o.ID)
+		}
+
+		changes = append(changes, change)
+	}
+
+	p.UpdateID, err = nextUpdateID(ctx, tx, p.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appendEvent(ctx, tx, p.Symbol, EventSelfTradePrevented, p); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if err := appendEvent(ctx, tx, p.Symbol, EventOrderStatusChanged, change); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := appendOrderEvent(ctx, tx, p.UserID, OrderEventSelfTrade, p); err != nil {
+		return nil, err
+	}
+
+	inTx := &Storage{db: tx}
+
+	for _, change := range changes {
+		changed, err := inTx.GetOrder(ctx, change.OrderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := appendOrderEvent(ctx, tx, p.UserID, OrderEventOrder, changed); err != nil {
+			return nil, err
+		}
+	}
+
+	touched := []Order{*buyOrder, *sellOrder}
+
+	for _, id := range p.Cancelled {
+		settled, err := settleGroup(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		touched = append(touched, settled...)
+	}
+
+	p.Depth, err = readDepth(ctx, tx, p.Symbol, touched...)
+	if err != nil {
+		return nil, err
+	}
+
+	key := p.Symbol + ":" + strconv.FormatInt(p.UpdateID, 10)
+
+	if err := appendOutbox(ctx, tx, TopicSelfTrades, key, p.UserID, p); err != nil {
+		return nil, err
+	}
+
+	for _, id := range p.Cancelled {
+		cancelled, err := inTx.GetOrder(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := appendOutbox(ctx, tx, TopicCancels, cancelled.ID, cancelled.UserID, cancelled); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
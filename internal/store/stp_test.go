@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestPreventSelfTrade(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &User{Username: "stp_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	n := 0
+
+	// pair rests a buy and then a newer sell, both u's, the sell carrying
+	// mode.
+	pair := func(mode STPMode, buyQty, sellQty int) (*Order, *Order) {
+		t.Helper()
+
+		var ids [2]string
+		for i := range ids {
+			if err := tx.QueryRow(ctx, `SELECT gen_random_uuid()::text`).Scan(&ids[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		n++
+
+		buy, err := storage.RestoreOrder(ctx, Order{ID: ids[0], UserID: u.ID, Symbol: "STP-USD", Side: "BUY", Price: 100, Quantity: buyQty, CreatedAt: baseTime.Add(time.Duration(n) * time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sell, err := storage.RestoreOrder(ctx, Order{ID: ids[1], UserID: u.ID, Symbol: "STP-USD", Side: "SELL", Price: 100, Quantity: sellQty, STPMode: mode, CreatedAt: baseTime.Add(time.Duration(n)*time.Minute + time.Second)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buy, sell
+	}
+
+	status := func(o *Order) (string, int) {
+		t.Helper()
+
+		got, err := storage.GetOrder(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Status, got.Quantity
+	}
+
+	cases := []struct {
+		mode                  STPMode
+		buyQty, sellQty       int
+		buyStatus, sellStatus string
+		buyLeft, sellLeft     int
+	}{
+		{STPCancelNewest, 3, 2, "PENDING", "CANCELLED", 3, 2},
+		{STPCancelOldest, 3, 2, "CANCELLED", "PENDING", 3, 2},
+		{STPCancelBoth, 3, 2, "CANCELLED", "CANCELLED", 3, 2},
+		{STPDecrementAndCancel, 3, 2, "PENDING", "CANCELLED", 1, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.mode), func(t *testing.T) {
+			buy, sell := pair(c.mode, c.buyQty, c.sellQty)
+
+			p, err := storage.PreventSelfTrade(ctx, buy, sell)
+			if err != nil {
+				t.Fatalf("PreventSelfTrade failed: %v", err)
+			}
+			if p.Mode != c.mode || p.UpdateID == 0 {
+				t.Errorf("Unexpected prevention %+v", p)
+			}
+
+			if st, left := status(buy); st != c.buyStatus || left != c.buyLeft {
+				t.Errorf("Buy: expected %s with %d left, got %s with %d", c.buyStatus, c.buyLeft, st, left)
+			}
+			if st, left := status(sell); st != c.sellStatus || left != c.sellLeft {
+				t.Errorf("Sell: expected %s with %d left, got %s with %d", c.sellStatus, c.sellLeft, st, left)
+			}
+
+			fills, _, err := storage.GetUserFills(ctx, FillQuery{UserID: u.ID, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fills) != 0 {
+				t.Errorf("Expected no fills, got %+v", fills)
+			}
+		})
+	}
+
+	t.Run("Reports a stale pair", func(t *testing.T) {
+		buy, sell := pair(STPCancelNewest, 1, 1)
+		sell.Quantity = 5
+
+		if _, err := storage.PreventSelfTrade(ctx, buy, sell); !errors.Is(err, ErrStaleMatch) {
+			t.Errorf("Expected ErrStaleMatch, got %v", err)
+		}
+	})
+
+	t.Run("Orders take the account's mode by default", func(t *testing.T) {
+		if err := storage.SetSTPMode(ctx, u.ID, "SOMETIMES"); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+		if err := storage.SetSTPMode(ctx, u.ID, STPCancelBoth); err != nil {
+			t.Fatal(err)
+		}
+
+		o, err := storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "STP-USD", Side: "BUY", Price: 1, Quantity: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.STPMode != STPCancelBoth {
+			t.Errorf("Expected CANCEL_BOTH from the account, got %q", o.STPMode)
+		}
+
+		o, err = storage.CreateOrder(ctx, Order{UserID: u.ID, Symbol: "STP-USD", Side: "BUY", Price: 1, Quantity: 1, STPMode: STPCancelOldest})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.STPMode != STPCancelOldest {
+			t.Errorf("Expected the order's own mode, got %q", o.STPMode)
+		}
+	})
+}
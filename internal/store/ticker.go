@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Ticker struct {
+	Symbol             string    `json:"symbol"`
+	LastPrice          float64   `json:"last_price"`
+	OpenPrice          float64   `json:"open_price"`
+	HighPrice          float64   `json:"high_price"`
+	LowPrice           float64   `json:"low_price"`
+	BaseVolume         float64   `json:"base_volume"`
+	QuoteVolume        float64   `json:"quote_volume"`
+	PriceChange        float64   `json:"price_change"`
+	PriceChangePercent float64   `json:"price_change_percent"`
+	TradeCount         int       `json:"trade_count"`
+	BidPrice           float64   `json:"bid_price"`
+	BidQuantity        int       `json:"bid_quantity"`
+	AskPrice           float64   `json:"ask_price"`
+	AskQuantity        int       `json:"ask_quantity"`
+	OpenTime           time.Time `json:"open_time"`
+	CloseTime          time.Time `json:"close_time"`
+}
+
+// GetTicker summarises the rolling 24 hours ending at now.
+func (s *Storage) GetTicker(ctx context.Context, symbol string, now time.Time) (*Ticker, error) {
+	now = now.UTC()
+
+	t := Ticker{
+		Symbol:    symbol,
+		OpenTime:  now.Add(-24 * time.Hour),
+		CloseTime: now,
+	}
+
+	latest, err := s.GetTrades(ctx, TradeQuery{Symbol: symbol, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(latest) > 0 {
+		t.LastPrice = latest[0].Price
+	}
+
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(t.quantity), 0),
+			COALESCE(SUM(t.price * t.quantity), 0),
+			MAX(t.price),
+			MIN(t.price),
+			(array_agg(t.price ORDER BY t.timestamp ASC, t.id ASC))[1]
+		FROM trades t
+		JOIN orders o ON t.bid_order_id = o.id
+		WHERE o.symbol = $1 AND t.timestamp >= $2 AND t.timestamp <= $3
+	`
+
+	var high, low, open *float64
+
+	err = s.db.QueryRow(ctx, query, symbol, t.OpenTime, t.CloseTime).Scan(
+		&t.TradeCount,
+		&t.BaseVolume,
+		&t.QuoteVolume,
+		&high,
+		&low,
+		&open,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch 24h statistics: %w", err)
+	}
+
+	t.OpenPrice, t.HighPrice, t.LowPrice = t.LastPrice, t.LastPrice, t.LastPrice
+
+	if open != nil {
+		t.OpenPrice, t.HighPrice, t.LowPrice = *open, *high, *low
+		t.PriceChange = t.LastPrice - t.OpenPrice
+		t.PriceChangePercent = t.PriceChange / t.OpenPrice * 100
+	}
+
+	book, err := s.GetOrderBook(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(book.Bids) > 0 {
+		t.BidPrice, t.BidQuantity = book.Bids[0].Price, book.Bids[0].Quantity
+	}
+	if len(book.Asks) > 0 {
+		t.AskPrice, t.AskQuantity = book.Asks[0].Price, book.Asks[0].Quantity
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestGetTicker(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	s := NewStorage(tx)
+	ctx := context.Background()
+
+	var bidID, askID string
+
+	err := tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('TICK-USD', 'BUY', 1, 1, 'FILLED') RETURNING id`).Scan(&bidID)
+	if err != nil {
+		t.Fatal("Failed to create dummy Buy Order:", err)
+	}
+
+	err = tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('TICK-USD', 'SELL', 1, 1, 'FILLED') RETURNING id`).Scan(&askID)
+	if err != nil {
+		t.Fatal("Failed to create dummy Sell Order:", err)
+	}
+
+	now := time.Now().UTC()
+
+	tradesToInsert := []struct {
+		Price    float64
+		Quantity int
+		Ago      time.Duration
+	}{
+		{Price: 50, Quantity: 9, Ago: 30 * time.Hour}, // outside the window
+		{Price: 100, Quantity: 1, Ago: 20 * time.Hour},
+		{Price: 130, Quantity: 2, Ago: 10 * time.Hour},
+		{Price: 110, Quantity: 1, Ago: time.Hour},
+	}
+
+	for _, tr := range tradesToInsert {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO trades (bid_order_id, ask_order_id, price, quantity, timestamp)
+			VALUES ($1, $2, $3, $4, $5)
+		`, bidID, askID, tr.Price, tr.Quantity, now.Add(-tr.Ago))
+		if err != nil {
+			t.Fatal("Failed to seed trade:", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO orders (symbol, side, price, quantity, status) VALUES
+		('TICK-USD', 'BUY', 105, 3, 'PENDING'),
+		('TICK-USD', 'SELL', 115, 4, 'PENDING')
+	`)
+	if err != nil {
+		t.Fatal("Failed to seed book:", err)
+	}
+
+	ticker, err := s.GetTicker(ctx, "TICK-USD", now)
+	if err != nil {
+		t.Fatalf("GetTicker failed: %v", err)
+	}
+
+	if ticker.LastPrice != 110 {
+		t.Errorf("LastPrice: got %v, want 110", ticker.LastPrice)
+	}
+	if ticker.OpenPrice != 100 || ticker.HighPrice != 130 || ticker.LowPrice != 100 {
+		t.Errorf("24h OHL wrong: %+v", ticker)
+	}
+	if ticker.BaseVolume != 4 || ticker.QuoteVolume != 470 {
+		t.Errorf("Volumes wrong: base %v quote %v", ticker.BaseVolume, ticker.QuoteVolume)
+	}
+	if ticker.PriceChangePercent != 10 {
+		t.Errorf("PriceChangePercent: got %v, want 10", ticker.PriceChangePercent)
+	}
+	if ticker.BidPrice != 105 || ticker.BidQuantity != 3 || ticker.AskPrice != 115 || ticker.AskQuantity != 4 {
+		t.Errorf("Best bid/ask wrong: %+v", ticker)
+	}
+
+	quiet, err := s.GetTicker(ctx, "TICK-USD", now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("GetTicker failed: %v", err)
+	}
+
+	if quiet.TradeCount != 0 || quiet.OpenPrice != 110 || quiet.PriceChange != 0 {
+		t.Errorf("A quiet market should report the last price with no change, got %+v", quiet)
+	}
+}
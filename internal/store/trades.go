@@ -2,74 +2,358 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type Trade struct {
 	ID        string    `json:"id"`
+	Symbol    string    `json:"symbol"`
 	BuyerID   string    `json:"buyer_id"`
 	SellerID  string    `json:"seller_id"`
 	Price     float64   `json:"price"`
 	Quantity  int       `json:"quantity"`
+	TakerSide string    `json:"taker_side"`
 	Timestamp time.Time `json:"timestamp"`
+	// UpdateID and Depth are only set on the trade CreateTrade returns.
+	UpdateID int64        `json:"-"`
+	Depth    *DepthChange `json:"-"`
+	// Candles are the candles the trade moved, updated in the same
+	// transaction. They are only set on the trade returned by CreateTrade.
+	Candles []IntervalCandle `json:"-"`
 }
 
-func (s *Storage) CreateTrade(ctx context.Context, price float64, qty int, buyerOrderID, sellerOrderID string) error {
+// Fees are charged in the quote asset. The maker is the order that was
+// resting on the book first; the taker is the one that crossed it.
+const (
+	MakerFeeRate = 0.001
+	TakerFeeRate = 0.002
+)
+
+// ErrStaleMatch means an order changed after it was read from the book; nothing was written.
+var ErrStaleMatch = errors.New("orders changed since they were matched")
+
+func (s *Storage) CreateTrade(ctx context.Context, price float64, qty int, buyerOrderID, sellerOrderID string) (*Trade, error) {
 
 	tx, err := s.db.Begin(ctx)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer tx.Rollback(ctx)
 
+	if err := lockBook(ctx, tx, buyerOrderID); err != nil {
+		return nil, err
+	}
+
+	notional := price * float64(qty)
+
 	tradeQuery := `
-	INSERT INTO trades (bid_order_id, ask_order_id, price, quantity)
-	VALUES ($1, $2, $3, $4)
+	WITH inserted AS (
+		INSERT INTO trades (bid_order_id, ask_order_id, price, quantity, taker_side, maker_fee, taker_fee)
+		SELECT b.id, a.id, $3::numeric, $4::int,
+			CASE WHEN b.created_at > a.created_at THEN 'BUY' ELSE 'SELL' END,
+			$5::numeric, $6::numeric
+		FROM orders b
+		JOIN orders a ON a.id = $2
+		WHERE b.id = $1
+		RETURNING id, bid_order_id, taker_side, timestamp
+	)
+	SELECT i.id, o.symbol, i.taker_side, i.timestamp
+	FROM inserted i
+	JOIN orders o ON o.id = i.bid_order_id
 	`
 
-	if _, err := tx.Exec(ctx, tradeQuery, buyerOrderID, sellerOrderID, price, qty); err != nil {
-		return fmt.Errorf("failed to insert trade: %w", err)
+	trade := Trade{
+		BuyerID:  buyerOrderID,
+		SellerID: sellerOrderID,
+		Price:    price,
+		Quantity: qty,
 	}
 
-	buyerQuery := `
-	UPDATE orders
-  SET quantity = quantity - $1
-  WHERE id = $2
-	`
+	err = tx.QueryRow(ctx, tradeQuery,
+		buyerOrderID,
+		sellerOrderID,
+		price,
+		qty,
+		notional*MakerFeeRate,
+		notional*TakerFeeRate,
+	).Scan(&trade.ID, &trade.Symbol, &trade.TakerSide, &trade.Timestamp)
 
-	if _, err := tx.Exec(ctx, buyerQuery, qty, buyerOrderID); err != nil {
-		return fmt.Errorf("failed to update buyer: %w", err)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to insert trade: order not found")
+		}
+		return nil, fmt.Errorf("failed to insert trade: %w", err)
 	}
 
-	sellerQuery := `
+	// An exhausted iceberg slice is replenished at the back of its level, keeping created_at.
+	orderQuery := `
+	WITH before AS (
+		SELECT visible_quantity FROM orders WHERE id = $2
+	)
 	UPDATE orders
-  SET quantity = quantity - $1
-  WHERE id = $2
+  SET quantity = quantity - $1,
+      filled_quantity = filled_quantity + $1,
+      status = CASE WHEN quantity - $1 <= 0 THEN 'FILLED' ELSE status END,
+      visible_quantity = CASE
+          WHEN display_quantity IS NULL THEN NULL
+          WHEN visible_quantity - $1 > 0 THEN visible_quantity - $1
+          ELSE LEAST(display_quantity, quantity - $1)
+      END,
+      priority_at = CASE
+          WHEN visible_quantity - $1 <= 0 AND quantity - $1 > 0 THEN clock_timestamp()::timestamp
+          ELSE priority_at
+      END
+  WHERE id = $2 AND status = 'PENDING' AND COALESCE(visible_quantity, quantity) >= $1
+  RETURNING quantity, filled_quantity, status, COALESCE(user_id::text, ''), price,
+      COALESCE(visible_quantity, 0), priority_at,
+      COALESCE((SELECT visible_quantity FROM before) - $1 <= 0 AND quantity > 0, false)
 	`
 
-	if _, err := tx.Exec(ctx, sellerQuery, qty, sellerOrderID); err != nil {
-		return fmt.Errorf("failed to update seller: %w", err)
+	changes := []OrderStatusChange{}
+	replenished := []OrderReplenishment{}
+	owners := map[string]string{}
+	prices := map[string]float64{}
+
+	for _, side := range []struct{ name, orderID string }{
+		{"buyer", buyerOrderID},
+		{"seller", sellerOrderID},
+	} {
+		change := OrderStatusChange{OrderID: side.orderID}
+		replenishment := OrderReplenishment{OrderID: side.orderID}
+		var userID string
+		var orderPrice float64
+		var isReplenished bool
+
+		err := tx.QueryRow(ctx, orderQuery, qty, side.orderID).Scan(
+			&change.Quantity,
+			&change.FilledQuantity,
+			&change.Status,
+			&userID,
+			&orderPrice,
+			&replenishment.VisibleQuantity,
+			&replenishment.PriorityAt,
+			&isReplenished,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrStaleMatch
+			}
+			return nil, fmt.Errorf("failed to update %s: %w", side.name, err)
+		}
+
+		if change.Status == "FILLED" {
+			changes = append(changes, change)
+		}
+
+		if isReplenished {
+			replenished = append(replenished, replenishment)
+		}
+
+		if userID != "" {
+			owners[side.orderID] = userID
+		}
+
+		prices[side.orderID] = orderPrice
+	}
+
+	trade.UpdateID, err = nextUpdateID(ctx, tx, trade.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stops trigger off the last trade price, and trailing stops follow it.
+	if _, err := tx.Exec(ctx, `UPDATE book_sequences SET last_price = $2 WHERE symbol = $1`, trade.Symbol, price); err != nil {
+		return nil, fmt.Errorf("failed to record last price: %w", err)
+	}
+
+	if err := trailStops(ctx, tx, trade.Symbol, price); err != nil {
+		return nil, err
+	}
+
+	if err := tripCircuitBreaker(ctx, tx, trade.Symbol, price); err != nil {
+		return nil, err
+	}
+
+	if err := appendEvent(ctx, tx, trade.Symbol, EventTradeExecuted, trade); err != nil {
+		return nil, err
+	}
+
+	// Klines are served from the candles, so they move with the trade.
+	inTx := &Storage{db: tx}
+
+	trade.Candles, err = inTx.UpdateCandles(ctx, &trade)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if err := appendEvent(ctx, tx, trade.Symbol, EventOrderStatusChanged, change); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range replenished {
+		if err := appendEvent(ctx, tx, trade.Symbol, EventOrderReplenished, r); err != nil {
+			return nil, err
+		}
+	}
+
+	// Each owner's fill and order are read back so they are logged with the trade.
+	if err := lockOrderEvents(ctx, tx, owners[buyerOrderID], owners[sellerOrderID]); err != nil {
+		return nil, err
+	}
+
+	fills := map[string]*Fill{}
+
+	for _, orderID := range []string{buyerOrderID, sellerOrderID} {
+		userID, ok := owners[orderID]
+		if !ok {
+			continue
+		}
+
+		fill, err := inTx.GetFill(ctx, trade.ID, orderID)
+		if err != nil {
+			return nil, err
+		}
+
+		order, err := inTx.GetOrder(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := appendOrderEvent(ctx, tx, userID, OrderEventFill, fill); err != nil {
+			return nil, err
+		}
+
+		if err := appendOrderEvent(ctx, tx, userID, OrderEventOrder, order); err != nil {
+			return nil, err
+		}
+
+		fills[orderID] = fill
+	}
+
+	touched := []Order{{Side: string(Buy), Price: prices[buyerOrderID]}, {Side: string(Sell), Price: prices[sellerOrderID]}}
+
+	for _, orderID := range []string{buyerOrderID, sellerOrderID} {
+		settled, err := settleGroup(ctx, tx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		touched = append(touched, settled...)
+	}
+
+	trade.Depth, err = readDepth(ctx, tx, trade.Symbol, touched...)
+	if err != nil {
+		return nil, err
 	}
 
-	return tx.Commit(ctx)
+	if err := appendOutbox(ctx, tx, TopicTrades, trade.ID, "", trade); err != nil {
+		return nil, err
+	}
+
+	for _, orderID := range []string{buyerOrderID, sellerOrderID} {
+		fill, ok := fills[orderID]
+		if !ok {
+			continue
+		}
+
+		if err := appendOutbox(ctx, tx, TopicFills, fill.Cursor(), owners[orderID], fill); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &trade, nil
+}
+
+// TradeQuery pages through a symbol's trades, forward from FromID or back from Before.
+type TradeQuery struct {
+	Symbol    string
+	FromID    string
+	Before    string
+	StartTime time.Time
+	EndTime   time.Time
+	Limit     int
 }
 
 func (s *Storage) GetRecentTrades(ctx context.Context, symbol string) ([]Trade, error) {
+	return s.GetTrades(ctx, TradeQuery{Symbol: symbol, Limit: 50})
+}
+
+func (s *Storage) GetTrades(ctx context.Context, q TradeQuery) ([]Trade, error) {
 	trades := []Trade{}
 
-	query := `
-		SELECT t.id, t.bid_order_id, t.ask_order_id, t.price, t.quantity, t.timestamp 
+	if q.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", ErrValidation)
+	}
+	if q.FromID != "" && q.Before != "" {
+		return nil, fmt.Errorf("from_id and before cannot be combined: %w", ErrValidation)
+	}
+	if q.FromID != "" && !isUUID(q.FromID) {
+		return nil, fmt.Errorf("from_id must be a trade ID: %w", ErrValidation)
+	}
+	if q.Before != "" && !isUUID(q.Before) {
+		return nil, fmt.Errorf("before must be a trade ID: %w", ErrValidation)
+	}
+
+	conditions := []string{"o.symbol = $1"}
+	args := []any{q.Symbol}
+
+	addCondition := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if q.FromID != "" {
+		at, err := s.tradeTimestamp(ctx, q.Symbol, q.FromID)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, at, q.FromID)
+		conditions = append(conditions, fmt.Sprintf("(t.timestamp, t.id) >= ($%d, $%d)", len(args)-1, len(args)))
+	}
+	if q.Before != "" {
+		at, err := s.tradeTimestamp(ctx, q.Symbol, q.Before)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, at, q.Before)
+		conditions = append(conditions, fmt.Sprintf("(t.timestamp, t.id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+	if !q.StartTime.IsZero() {
+		addCondition("t.timestamp >= $%d", q.StartTime.UTC())
+	}
+	if !q.EndTime.IsZero() {
+		addCondition("t.timestamp < $%d", q.EndTime.UTC())
+	}
+
+	order := "DESC"
+	if q.FromID != "" || (!q.StartTime.IsZero() && q.Before == "") {
+		order = "ASC"
+	}
+
+	args = append(args, q.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT t.id, o.symbol, t.bid_order_id, t.ask_order_id, t.price, t.quantity, COALESCE(t.taker_side, ''), t.timestamp 
 		FROM trades t
 		JOIN orders o ON t.bid_order_id = o.id
-		WHERE o.symbol = $1 
-		ORDER BY t.timestamp DESC
-		LIMIT 50
-	`
+		WHERE %s
+		ORDER BY t.timestamp %s, t.id %s
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), order, order, len(args))
 
-	rows, err := s.db.Query(ctx, query, symbol)
+	rows, err := s.db.Query(ctx, query, args...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Recent Trades: %w", err)
@@ -82,10 +366,12 @@ func (s *Storage) GetRecentTrades(ctx context.Context, symbol string) ([]Trade,
 
 		if err := rows.Scan(
 			&t.ID,
+			&t.Symbol,
 			&t.BuyerID,
 			&t.SellerID,
 			&t.Price,
 			&t.Quantity,
+			&t.TakerSide,
 			&t.Timestamp,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan trade: %w", err)
@@ -94,6 +380,27 @@ func (s *Storage) GetRecentTrades(ctx context.Context, symbol string) ([]Trade,
 		trades = append(trades, t)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
 	return trades, nil
 
 }
+
+// tradeTimestamp returns when a cursor trade of symbol happened, or ErrValidation.
+func (s *Storage) tradeTimestamp(ctx context.Context, symbol, id string) (time.Time, error) {
+	var at time.Time
+
+	query := `SELECT t.timestamp FROM trades t JOIN orders o ON t.bid_order_id = o.id WHERE t.id = $1 AND o.symbol = $2`
+
+	err := s.db.QueryRow(ctx, query, id, symbol).Scan(&at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("cursor trade not found: %w", ErrValidation)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch cursor trade: %w", err)
+	}
+
+	return at, nil
+}
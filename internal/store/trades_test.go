@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -70,15 +71,15 @@ func TestCreateTrade(t *testing.T) {
 			var buyID, sellID string
 			for _, o := range tc.setupOrders {
 				o.UserID = u.ID
-				id, err := storage.CreateOrder(ctx, o)
+				created, err := storage.CreateOrder(ctx, o)
 
 				if err != nil {
 					t.Fatalf("Setup failed: %v", err)
 				}
 				if o.Side == "BUY" {
-					buyID = id
+					buyID = created.ID
 				} else {
-					sellID = id
+					sellID = created.ID
 				}
 			}
 
@@ -86,7 +87,7 @@ func TestCreateTrade(t *testing.T) {
 				buyID = "00000000-0000-0000-0000-000000000000"
 			}
 
-			err := storage.CreateTrade(ctx, 100.0, tc.tradeQty, buyID, sellID)
+			trade, err := storage.CreateTrade(ctx, 100.0, tc.tradeQty, buyID, sellID)
 
 			if tc.expectError {
 				if err == nil {
@@ -98,6 +99,10 @@ func TestCreateTrade(t *testing.T) {
 				t.Fatalf("Did not expect error but got: %v", err)
 			}
 
+			if len(trade.Candles) != len(Intervals) {
+				t.Errorf("Expected the trade to move %d candles, got %d", len(Intervals), len(trade.Candles))
+			}
+
 			var currentQty int
 			var checkQuantityQuery string = "SELECT quantity FROM orders WHERE id = $1"
 
@@ -197,3 +202,103 @@ func TestGetRecentTrades(t *testing.T) {
 		t.Errorf("Expected 0 trades for ETH-USD, got %d", len(ethTrades))
 	}
 }
+
+func TestGetTrades(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	var bidID, askID string
+
+	err := tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('TAPE-USD', 'BUY', 100, 5, 'FILLED') RETURNING id`).Scan(&bidID)
+	if err != nil {
+		t.Fatal("Failed to create bid order:", err)
+	}
+
+	err = tx.QueryRow(ctx, `INSERT INTO orders (symbol, side, price, quantity, status) VALUES ('TAPE-USD', 'SELL', 100, 5, 'FILLED') RETURNING id`).Scan(&askID)
+	if err != nil {
+		t.Fatal("Failed to create ask order:", err)
+	}
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	ids := make([]string, 5)
+
+	for i := range ids {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO trades (bid_order_id, ask_order_id, price, quantity, timestamp)
+			VALUES ($1, $2, $3, 1, $4)
+			RETURNING id
+		`, bidID, askID, 100+i, baseTime.Add(time.Duration(i)*time.Minute)).Scan(&ids[i])
+		if err != nil {
+			t.Fatalf("Failed to insert trade: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     TradeQuery
+		wantPrice []float64
+	}{
+		{
+			name:      "Latest first",
+			query:     TradeQuery{Symbol: "TAPE-USD", Limit: 2},
+			wantPrice: []float64{104, 103},
+		},
+		{
+			name:      "Before walks backward",
+			query:     TradeQuery{Symbol: "TAPE-USD", Before: ids[3], Limit: 2},
+			wantPrice: []float64{102, 101},
+		},
+		{
+			name:      "FromID walks forward inclusively",
+			query:     TradeQuery{Symbol: "TAPE-USD", FromID: ids[1], Limit: 3},
+			wantPrice: []float64{101, 102, 103},
+		},
+		{
+			name:      "Time range is oldest first",
+			query:     TradeQuery{Symbol: "TAPE-USD", StartTime: baseTime.Add(time.Minute), EndTime: baseTime.Add(3 * time.Minute), Limit: 10},
+			wantPrice: []float64{101, 102},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trades, err := storage.GetTrades(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("GetTrades failed: %v", err)
+			}
+
+			if len(trades) != len(tc.wantPrice) {
+				t.Fatalf("Expected %d trades, got %d", len(tc.wantPrice), len(trades))
+			}
+
+			for i, want := range tc.wantPrice {
+				if trades[i].Price != want {
+					t.Errorf("Trade %d: want price %v, got %v", i, want, trades[i].Price)
+				}
+			}
+		})
+	}
+
+	t.Run("Rejects from_id combined with before", func(t *testing.T) {
+		_, err := storage.GetTrades(ctx, TradeQuery{Symbol: "TAPE-USD", FromID: ids[0], Before: ids[4], Limit: 10})
+
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation, got %v", err)
+		}
+	})
+
+	t.Run("Rejects a cursor that is not one of the symbol's trades", func(t *testing.T) {
+		missing := "00000000-0000-4000-8000-000000000000"
+
+		for _, q := range []TradeQuery{
+			{Symbol: "TAPE-USD", FromID: missing, Limit: 10},
+			{Symbol: "TAPE-USD", Before: missing, Limit: 10},
+			{Symbol: "OTHER-USD", Before: ids[3], Limit: 10},
+		} {
+			if _, err := storage.GetTrades(ctx, q); !errors.Is(err, ErrValidation) {
+				t.Errorf("Expected ErrValidation for %+v, got %v", q, err)
+			}
+		}
+	})
+}
@@ -85,3 +85,23 @@ func (s *Storage) GetUserByUsername(ctx context.Context, username string) (*User
 
 	return &u, nil
 }
+
+// IsAdmin reports whether a user may configure trading pairs. Unknown users
+// are not admins.
+func (s *Storage) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	if !isUUID(userID) {
+		return false, nil
+	}
+
+	var admin bool
+
+	err := s.db.QueryRow(ctx, `SELECT is_admin FROM users WHERE id = $1`, userID).Scan(&admin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	return admin, nil
+}
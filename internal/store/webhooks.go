@@ -0,0 +1,384 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WebhookEvents are the outbox topics a user can subscribe a webhook to.
+var WebhookEvents = []string{TopicFills, TopicCancels, TopicSelfTrades}
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Webhook is a user's subscription to some of their own events. Secret
+// signs every delivery and is only returned when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery statuses. A delivery is PENDING until it succeeds or runs out of
+// retries.
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryFailed    = "FAILED"
+)
+
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	WebhookID      string     `json:"webhook_id"`
+	MessageID      int64      `json:"message_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// WebhookJob is a claimed delivery with everything needed to send it.
+type WebhookJob struct {
+	DeliveryID string
+	WebhookID  string
+	Attempts   int
+	URL        string
+	Secret     string
+	Message    OutboxMessage
+}
+
+// WebhookAddrAllowed reports whether addr is public, so webhooks may be sent to it.
+func WebhookAddrAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+func validateWebhook(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL: %w", ErrValidation)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("url must not point at this server: %w", ErrValidation)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !WebhookAddrAllowed(addr) {
+		return fmt.Errorf("url must not point at a loopback, private or link-local address: %w", ErrValidation)
+	}
+
+	if len(events) == 0 {
+		return fmt.Errorf("at least one event is required: %w", ErrValidation)
+	}
+
+	for _, e := range events {
+		if !slices.Contains(WebhookEvents, e) {
+			return fmt.Errorf("unknown event %q: %w", e, ErrValidation)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, userID, rawURL string, events []string) (*Webhook, error) {
+	if err := validateWebhook(rawURL, events); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	w := Webhook{
+		URL:    rawURL,
+		Events: slices.Compact(slices.Sorted(slices.Values(events))),
+		Secret: hex.EncodeToString(secret),
+	}
+
+	query := `
+	INSERT INTO webhooks (user_id, url, events, secret)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at
+	`
+
+	if err := s.db.QueryRow(ctx, query, userID, w.URL, w.Events, w.Secret).Scan(&w.ID, &w.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &w, nil
+}
+
+func (s *Storage) GetWebhooks(ctx context.Context, userID string) ([]Webhook, error) {
+	webhooks := []Webhook{}
+
+	query := `
+	SELECT id, url, events, created_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var w Webhook
+
+		if err := rows.Scan(&w.ID, &w.URL, &w.Events, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to fetch webhook: %w", err)
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes a webhook and its delivery log.
+func (s *Storage) DeleteWebhook(ctx context.Context, id, userID string) error {
+	if !isUUID(id) {
+		return ErrWebhookNotFound
+	}
+
+	tag, err := s.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// QueueWebhookDeliveries queues msg for each subscribed webhook of its owner, once.
+func (s *Storage) QueueWebhookDeliveries(ctx context.Context, msg OutboxMessage) (int64, error) {
+	if msg.UserID == "" {
+		return 0, nil
+	}
+
+	query := `
+	INSERT INTO webhook_deliveries (webhook_id, message_id)
+	SELECT id, $1
+		FROM webhooks
+		WHERE user_id = $2 AND $3 = ANY(events)
+	ON CONFLICT (webhook_id, message_id) DO NOTHING
+	`
+
+	tag, err := s.db.Exec(ctx, query, msg.ID, msg.UserID, msg.Topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ClaimWebhookDeliveries leases up to limit due deliveries to the caller.
+func (s *Storage) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookJob, error) {
+	jobs := []WebhookJob{}
+
+	query := `
+	WITH claimed AS (
+		UPDATE webhook_deliveries
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, webhook_id, message_id, attempts
+	)
+	SELECT c.id, c.webhook_id, c.attempts, w.url, w.secret,
+		o.id, o.topic, o.key, COALESCE(o.user_id::text, ''), o.payload, o.created_at
+	FROM claimed c
+	JOIN webhooks w ON w.id = c.webhook_id
+	JOIN outbox o ON o.id = c.message_id
+	ORDER BY o.id ASC
+	`
+
+	rows, err := s.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var j WebhookJob
+
+		err := rows.Scan(
+			&j.DeliveryID, &j.WebhookID, &j.Attempts, &j.URL, &j.Secret,
+			&j.Message.ID, &j.Message.Topic, &j.Message.Key, &j.Message.UserID, &j.Message.Payload, &j.Message.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch webhook delivery: %w", err)
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// RecordWebhookAttempt logs one attempt at a delivery and when to retry it, if ever.
+func (s *Storage) RecordWebhookAttempt(ctx context.Context, deliveryID string, statusCode int, attemptErr error, retryAt time.Time) error {
+	status, lastError := DeliveryDelivered, ""
+
+	if attemptErr != nil {
+		lastError = attemptErr.Error()
+		status = DeliveryPending
+		if retryAt.IsZero() {
+			status = DeliveryFailed
+		}
+	}
+
+	query := `
+	UPDATE webhook_deliveries
+	SET attempts = attempts + 1,
+		status = $2,
+		last_status_code = NULLIF($3, 0),
+		last_error = NULLIF($4, ''),
+		next_attempt_at = CASE WHEN $2 = 'PENDING' THEN $5 ELSE next_attempt_at END,
+		delivered_at = CASE WHEN $2 = 'DELIVERED' THEN NOW() END
+	WHERE id = $1
+	`
+
+	if _, err := s.db.Exec(ctx, query, deliveryID, status, statusCode, lastError, retryAt.UTC()); err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	return nil
+}
+
+const webhookDeliveryColumns = `
+	d.id, d.webhook_id, d.message_id, o.topic, d.status, d.attempts,
+	COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''),
+	d.next_attempt_at, d.delivered_at, d.created_at
+`
+
+func scanWebhookDelivery(row pgx.Row, d *WebhookDelivery) error {
+	return row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.MessageID,
+		&d.Event,
+		&d.Status,
+		&d.Attempts,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.NextAttemptAt,
+		&d.DeliveredAt,
+		&d.CreatedAt,
+	)
+}
+
+// GetWebhookDeliveries returns a webhook's delivery log, newest first.
+func (s *Storage) GetWebhookDeliveries(ctx context.Context, webhookID, userID string, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+
+	if !isUUID(webhookID) {
+		return nil, ErrWebhookNotFound
+	}
+
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)`, webhookID, userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	query := fmt.Sprintf(`
+	SELECT %s
+		FROM webhook_deliveries d
+		JOIN outbox o ON o.id = d.message_id
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC, d.message_id DESC
+		LIMIT $2
+	`, webhookDeliveryColumns)
+
+	rows, err := s.db.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var d WebhookDelivery
+
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, fmt.Errorf("failed to fetch webhook delivery: %w", err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RedeliverWebhook queues a delivery to be sent again straight away.
+func (s *Storage) RedeliverWebhook(ctx context.Context, webhookID, deliveryID, userID string) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+
+	if !isUUID(webhookID) || !isUUID(deliveryID) {
+		return nil, ErrDeliveryNotFound
+	}
+
+	query := fmt.Sprintf(`
+	WITH redelivered AS (
+		UPDATE webhook_deliveries d
+		SET status = 'PENDING', next_attempt_at = NOW()
+		FROM webhooks w
+		WHERE d.id = $1 AND d.webhook_id = $2 AND w.id = d.webhook_id AND w.user_id = $3
+		RETURNING d.*
+	)
+	SELECT %s
+		FROM redelivered d
+		JOIN outbox o ON o.id = d.message_id
+	`, webhookDeliveryColumns)
+
+	if err := scanWebhookDelivery(s.db.QueryRow(ctx, query, deliveryID, webhookID, userID), &d); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestWebhooks(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := NewStorage(tx)
+	ctx := context.Background()
+
+	owner, err := storage.CreateUser(ctx, &User{Username: "webhook_owner", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	other, err := storage.CreateUser(ctx, &User{Username: "webhook_other", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	t.Run("Validates the URL and events", func(t *testing.T) {
+		cases := []struct {
+			url    string
+			events []string
+		}{
+			{"ftp://example.com/hook", []string{TopicFills}},
+			{"/relative", []string{TopicFills}},
+			{"https://example.com/hook", nil},
+			{"https://example.com/hook", []string{"trades"}},
+			{"https://example.com/hook", []string{"deposits"}},
+			{"http://localhost:8080/hook", []string{TopicFills}},
+			{"http://127.0.0.1/hook", []string{TopicFills}},
+			{"http://10.0.0.5/hook", []string{TopicFills}},
+			{"http://169.254.169.254/latest/meta-data", []string{TopicFills}},
+			{"http://[::1]/hook", []string{TopicFills}},
+			{"http://[::ffff:192.168.0.1]/hook", []string{TopicFills}},
+			{"http://0.0.0.0/hook", []string{TopicFills}},
+		}
+
+		for _, c := range cases {
+			if _, err := storage.CreateWebhook(ctx, owner.ID, c.url, c.events); !errors.Is(err, ErrValidation) {
+				t.Errorf("%s %v: expected ErrValidation, got %v", c.url, c.events, err)
+			}
+		}
+	})
+
+	hook, err := storage.CreateWebhook(ctx, owner.ID, "https://example.com/hook", []string{TopicCancels, TopicFills, TopicFills})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+
+	t.Run("Returns the secret only on creation", func(t *testing.T) {
+		if len(hook.Secret) != 64 {
+			t.Errorf("Expected a 32-byte hex secret, got %q", hook.Secret)
+		}
+		if len(hook.Events) != 2 {
+			t.Errorf("Expected duplicate events to be dropped, got %v", hook.Events)
+		}
+
+		hooks, err := storage.GetWebhooks(ctx, owner.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hooks) != 1 || hooks[0].Secret != "" {
+			t.Errorf("Expected one webhook without its secret, got %+v", hooks)
+		}
+	})
+
+	t.Run("Queues cancels for the owner's subscribed webhooks", func(t *testing.T) {
+		order, err := storage.CreateOrder(ctx, Order{UserID: owner.ID, Symbol: "WHS-USD", Side: "BUY", Price: 100, Quantity: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.CancelOrder(ctx, order.ID, owner.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := storage.GetPendingOutbox(ctx, "test-webhooks", 10000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cancel *OutboxMessage
+		for i := range pending {
+			if pending[i].Topic == TopicCancels && pending[i].Key == order.ID {
+				cancel = &pending[i]
+			}
+		}
+		if cancel == nil || cancel.UserID != owner.ID {
+			t.Fatalf("Expected a cancel message for the owner, got %+v", cancel)
+		}
+
+		for range 2 {
+			if _, err := storage.QueueWebhookDeliveries(ctx, *cancel); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		deliveries, err := storage.GetWebhookDeliveries(ctx, hook.ID, owner.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 1 || deliveries[0].Event != TopicCancels || deliveries[0].Status != DeliveryPending {
+			t.Errorf("Expected one pending cancel delivery, got %+v", deliveries)
+		}
+	})
+
+	t.Run("Hides webhooks from other users", func(t *testing.T) {
+		if _, err := storage.GetWebhookDeliveries(ctx, hook.ID, other.ID, 10); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+		}
+		if err := storage.DeleteWebhook(ctx, hook.ID, other.ID); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+		}
+		if err := storage.DeleteWebhook(ctx, hook.ID, owner.ID); err != nil {
+			t.Errorf("Expected the owner to delete the webhook, got %v", err)
+		}
+	})
+}
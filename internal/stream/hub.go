@@ -0,0 +1,315 @@
+package stream
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+// Channels a client can subscribe to, as "<channel>:<symbol>"; ChannelOrders is per user and private.
+const (
+	ChannelTrades  = "trades"
+	ChannelDepth   = "depth"
+	ChannelBook    = "book"
+	ChannelTicker  = "ticker"
+	ChannelKline   = "kline"
+	ChannelOrders  = "orders"
+	ChannelAuction = "auction"
+)
+
+// Event types. Trades go to the public trades channel; fills, order updates
+// and prevented self-trades go to the owner's orders channel.
+const (
+	TypeTrade     = "trade"
+	TypeFill      = "fill"
+	TypeOrder     = "order"
+	TypeSelfTrade = "self_trade"
+)
+
+// Event is one message on a stream; ID, when set, lets a client resume after it.
+type Event struct {
+	ID     string `json:"id,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Stream string `json:"stream"`
+	Data   any    `json:"data"`
+}
+
+// DepthUpdate lists the price levels whose resting quantity changed; zero empties a level.
+type DepthUpdate struct {
+	Symbol   string                 `json:"symbol"`
+	UpdateID int64                  `json:"updateId"`
+	Bids     []store.OrderBookEntry `json:"bids"`
+	Asks     []store.OrderBookEntry `json:"asks"`
+}
+
+// NewDepthUpdate builds the update for a book change from the levels the
+// store read in the transaction that numbered it.
+func NewDepthUpdate(symbol string, updateID int64, depth *store.DepthChange) DepthUpdate {
+	return DepthUpdate{Symbol: symbol, UpdateID: updateID, Bids: depth.Bids, Asks: depth.Asks}
+}
+
+// AuctionUpdate is where a symbol's auction stands; Price and Volume are indicative during AUCTION.
+type AuctionUpdate struct {
+	Symbol string             `json:"symbol"`
+	Phase  store.TradingPhase `json:"phase"`
+	EndsAt *time.Time         `json:"ends_at,omitempty"`
+	Price  float64            `json:"price"`
+	Volume int                `json:"volume"`
+}
+
+type KlineUpdate struct {
+	Symbol string `json:"symbol"`
+	store.IntervalCandle
+}
+
+func Name(channel, symbol string) string {
+	return channel + ":" + symbol
+}
+
+func KlineChannel(interval store.Interval) string {
+	return ChannelKline + "_" + string(interval)
+}
+
+// ParseName validates a stream name and splits it into channel and symbol.
+func ParseName(name string) (channel string, symbol string, ok bool) {
+	channel, symbol, found := strings.Cut(name, ":")
+	if !found || symbol == "" {
+		return "", "", false
+	}
+
+	switch channel {
+	case ChannelTrades, ChannelDepth, ChannelBook, ChannelTicker, ChannelAuction:
+		return channel, symbol, true
+	}
+
+	if interval, isKline := strings.CutPrefix(channel, ChannelKline+"_"); isKline {
+		if _, err := store.ParseInterval(interval); err == nil {
+			return channel, symbol, true
+		}
+	}
+
+	return "", "", false
+}
+
+// Subscriber is one consumer's view of the hub; Done is closed if it falls behind.
+type Subscriber struct {
+	events    chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu      sync.RWMutex
+	streams map[string]bool
+}
+
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// MaxStreams is the most streams one subscriber can be subscribed to.
+const MaxStreams = 50
+
+// ErrTooManyStreams means a subscription would take a subscriber past
+// MaxStreams.
+var ErrTooManyStreams = errors.New("too many streams")
+
+// Subscribe adds streams to the subscriber, or none of them if that would
+// take it past MaxStreams.
+func (s *Subscriber) Subscribe(streams ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := map[string]bool{}
+	for _, name := range streams {
+		if !s.streams[name] {
+			added[name] = true
+		}
+	}
+
+	if len(s.streams)+len(added) > MaxStreams {
+		return ErrTooManyStreams
+	}
+
+	for name := range added {
+		s.streams[name] = true
+	}
+
+	return nil
+}
+
+func (s *Subscriber) Unsubscribe(streams ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range streams {
+		delete(s.streams, name)
+	}
+}
+
+func (s *Subscriber) wants(stream string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.streams[stream]
+}
+
+func (s *Subscriber) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Hub fans published events out to subscribers without blocking.
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscriber]struct{}
+	buffer int
+
+	depthMu sync.Mutex
+	depth   map[string]*depthQueue
+}
+
+// depthReorderWait is how long PublishDepth holds an update that arrived
+// ahead of the one before it.
+const depthReorderWait = 100 * time.Millisecond
+
+// depthQueue holds a symbol's depth updates that arrived out of order.
+type depthQueue struct {
+	last    int64
+	pending map[int64]DepthUpdate
+	timer   *time.Timer
+	// gen numbers the timers, so a timer that fired as it was stopped can
+	// tell it is no longer the current one.
+	gen int64
+}
+
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		subs:   map[*Subscriber]struct{}{},
+		buffer: buffer,
+		depth:  map[string]*depthQueue{},
+	}
+}
+
+func (h *Hub) Subscribe() *Subscriber {
+	sub := &Subscriber{
+		events:  make(chan Event, h.buffer),
+		done:    make(chan struct{}),
+		streams: map[string]bool{},
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+func (h *Hub) Remove(sub *Subscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+
+	sub.close()
+}
+
+func (h *Hub) Publish(e Event) {
+	if h == nil {
+		return
+	}
+
+	var slow []*Subscriber
+
+	h.mu.RLock()
+	for sub := range h.subs {
+		if !sub.wants(e.Stream) {
+			continue
+		}
+
+		select {
+		case sub.events <- e:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.Remove(sub)
+	}
+}
+
+// PublishDepth publishes depth updates in UpdateID order, holding one back while there is a gap.
+func (h *Hub) PublishDepth(u DepthUpdate) {
+	if h == nil {
+		return
+	}
+
+	h.depthMu.Lock()
+	defer h.depthMu.Unlock()
+
+	q, ok := h.depth[u.Symbol]
+	if !ok {
+		q = &depthQueue{pending: map[int64]DepthUpdate{}}
+		h.depth[u.Symbol] = q
+	}
+
+	if q.last != 0 && u.UpdateID <= q.last {
+		return
+	}
+
+	if q.last != 0 && u.UpdateID > q.last+1 {
+		q.pending[u.UpdateID] = u
+		if q.timer == nil {
+			q.gen++
+			gen := q.gen
+			q.timer = time.AfterFunc(depthReorderWait, func() { h.flushDepth(u.Symbol, gen) })
+		}
+		return
+	}
+
+	h.releaseDepth(q, u)
+
+	for {
+		next, ok := q.pending[q.last+1]
+		if !ok {
+			break
+		}
+		h.releaseDepth(q, next)
+	}
+
+	if len(q.pending) == 0 && q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+}
+
+// flushDepth stops waiting for a missing update and publishes whatever a
+// symbol has held since, in order.
+func (h *Hub) flushDepth(symbol string, gen int64) {
+	h.depthMu.Lock()
+	defer h.depthMu.Unlock()
+
+	q := h.depth[symbol]
+	if q.gen != gen || q.timer == nil {
+		return
+	}
+	q.timer = nil
+
+	for _, id := range slices.Sorted(maps.Keys(q.pending)) {
+		h.releaseDepth(q, q.pending[id])
+	}
+}
+
+func (h *Hub) releaseDepth(q *depthQueue, u DepthUpdate) {
+	delete(q.pending, u.UpdateID)
+	q.last = u.UpdateID
+
+	h.Publish(Event{Stream: Name(ChannelDepth, u.Symbol), Data: u})
+}
@@ -0,0 +1,194 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub(4)
+
+	btc := hub.Subscribe()
+	btc.Subscribe(Name(ChannelTrades, "BTC-USD"))
+
+	eth := hub.Subscribe()
+	eth.Subscribe(Name(ChannelTrades, "ETH-USD"))
+
+	hub.Publish(Event{Stream: Name(ChannelTrades, "BTC-USD"), Data: 1})
+
+	select {
+	case e := <-btc.Events():
+		if e.Data != 1 {
+			t.Errorf("Expected data 1, got %v", e.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("BTC subscriber did not receive its event")
+	}
+
+	select {
+	case e := <-eth.Events():
+		t.Errorf("ETH subscriber should not receive BTC events, got %v", e)
+	default:
+	}
+
+	btc.Unsubscribe(Name(ChannelTrades, "BTC-USD"))
+	hub.Publish(Event{Stream: Name(ChannelTrades, "BTC-USD"), Data: 2})
+
+	select {
+	case e := <-btc.Events():
+		t.Errorf("Unsubscribed stream still delivered %v", e)
+	default:
+	}
+}
+
+func TestHubDropsSlowConsumer(t *testing.T) {
+	hub := NewHub(2)
+
+	slow := hub.Subscribe()
+	slow.Subscribe("trades:BTC-USD")
+
+	fast := hub.Subscribe()
+	fast.Subscribe("trades:BTC-USD")
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(Event{Stream: "trades:BTC-USD", Data: i})
+		<-fast.Events()
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("Expected the slow subscriber to be dropped")
+	}
+
+	select {
+	case <-fast.Done():
+		t.Fatal("The fast subscriber should stay connected")
+	default:
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		symbol  string
+		ok      bool
+	}{
+		{name: "trades:BTC-USD", channel: "trades", symbol: "BTC-USD", ok: true},
+		{name: "kline_1m:ETH-USD", channel: "kline_1m", symbol: "ETH-USD", ok: true},
+		{name: "auction:BTC-USD", channel: "auction", symbol: "BTC-USD", ok: true},
+		{name: "kline_2m:ETH-USD"},
+		{name: "book:"},
+		{name: "gossip:BTC-USD"},
+		{name: "trades"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel, symbol, ok := ParseName(tt.name)
+
+			if ok != tt.ok || channel != tt.channel || symbol != tt.symbol {
+				t.Errorf("ParseName(%q) = %q, %q, %v", tt.name, channel, symbol, ok)
+			}
+		})
+	}
+}
+
+func TestSubscriberMaxStreams(t *testing.T) {
+	sub := NewHub(1).Subscribe()
+
+	streams := []string{}
+	for i := range MaxStreams {
+		streams = append(streams, Name(ChannelTrades, fmt.Sprintf("S%d-USD", i)))
+	}
+
+	if err := sub.Subscribe(streams...); err != nil {
+		t.Fatalf("Expected %d streams to be allowed, got %v", MaxStreams, err)
+	}
+	if err := sub.Subscribe(streams[0]); err != nil {
+		t.Errorf("Expected a repeated stream not to count, got %v", err)
+	}
+	if err := sub.Subscribe(Name(ChannelTrades, "ONE-MORE")); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("Expected ErrTooManyStreams, got %v", err)
+	}
+
+	sub.Unsubscribe(streams[0])
+
+	if err := sub.Subscribe(Name(ChannelTrades, "ONE-MORE")); err != nil {
+		t.Errorf("Expected room after unsubscribing, got %v", err)
+	}
+}
+
+func TestHubPublishDepthInOrder(t *testing.T) {
+	hub := NewHub(8)
+
+	sub := hub.Subscribe()
+	sub.Subscribe(Name(ChannelDepth, "BTC-USD"))
+
+	next := func(wait time.Duration) int64 {
+		t.Helper()
+
+		select {
+		case e := <-sub.Events():
+			return e.Data.(DepthUpdate).UpdateID
+		case <-time.After(wait):
+			return 0
+		}
+	}
+
+	publish := func(id int64) {
+		hub.PublishDepth(DepthUpdate{Symbol: "BTC-USD", UpdateID: id})
+	}
+
+	publish(1)
+	publish(3)
+
+	if got := next(time.Second); got != 1 {
+		t.Fatalf("Expected update 1, got %d", got)
+	}
+	if got := next(depthReorderWait / 4); got != 0 {
+		t.Fatalf("Expected update 3 to wait for 2, got %d", got)
+	}
+
+	publish(2)
+
+	for _, want := range []int64{2, 3} {
+		if got := next(time.Second); got != want {
+			t.Fatalf("Expected update %d, got %d", want, got)
+		}
+	}
+
+	// The timer for the gap just filled may already have fired; it must not
+	// flush a later gap early.
+	publish(6)
+	hub.flushDepth("BTC-USD", 1)
+
+	if got := next(depthReorderWait / 4); got != 0 {
+		t.Fatalf("Expected a stale timer to be ignored, got update %d", got)
+	}
+
+	publish(4)
+	publish(5)
+
+	for _, want := range []int64{4, 5, 6} {
+		if got := next(time.Second); got != want {
+			t.Fatalf("Expected update %d, got %d", want, got)
+		}
+	}
+
+	// A gap that is never filled is given up on.
+	publish(8)
+
+	if got := next(time.Second); got != 8 {
+		t.Fatalf("Expected update 8 after the wait, got %d", got)
+	}
+
+	publish(7)
+
+	if got := next(depthReorderWait * 2); got != 0 {
+		t.Errorf("Expected the late update 7 to be dropped, got %d", got)
+	}
+}
@@ -27,20 +27,22 @@ func (t *TestTx) Rollback(ctx context.Context) error {
 	return nil
 }
 
-func SetupTestDB(t *testing.T) *TestTx {
-	t.Helper()
-
+func connString() string {
 	_ = godotenv.Load("../../.env")
 
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASSWORD"),
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB_PORT"),
 		os.Getenv("DB_NAME"),
 	)
+}
+
+func SetupTestDB(t *testing.T) *TestTx {
+	t.Helper()
 
-	pool, err := pgxpool.New(context.Background(), connStr)
+	pool, err := pgxpool.New(context.Background(), connString())
 	if err != nil {
 		t.Fatalf("Failed to connect to DB: %v", err)
 	}
@@ -60,3 +62,19 @@ func SetupTestDB(t *testing.T) *TestTx {
 
 	return &TestTx{Tx: tx}
 }
+
+// SetupTestPool connects without a wrapping transaction; tests must clean up after themselves.
+func SetupTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	pool, err := pgxpool.New(context.Background(), connString())
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+	})
+
+	return pool
+}
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+// Envelope is the body of every delivery.
+type Envelope struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Deliverer sends pending webhook deliveries, retrying failures with
+// exponential backoff until MaxAttempts is reached.
+type Deliverer struct {
+	store       *store.Storage
+	client      *http.Client
+	interval    time.Duration
+	batch       int
+	lease       time.Duration
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+type Option func(*Deliverer)
+
+// WithClient replaces the HTTP client used for deliveries.
+func WithClient(c *http.Client) Option {
+	return func(d *Deliverer) {
+		d.client = c
+	}
+}
+
+// WithRetries sets how many attempts a delivery gets and the backoff before
+// the second one, which doubles on each later attempt up to max.
+func WithRetries(attempts int, base, max time.Duration) Option {
+	return func(d *Deliverer) {
+		d.maxAttempts = attempts
+		d.baseBackoff = base
+		d.maxBackoff = max
+	}
+}
+
+func NewDeliverer(s *store.Storage, opts ...Option) *Deliverer {
+	d := &Deliverer{
+		store:       s,
+		client:      newClient(),
+		interval:    time.Second,
+		batch:       50,
+		lease:       time.Minute,
+		maxAttempts: 10,
+		baseBackoff: 30 * time.Second,
+		maxBackoff:  6 * time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// newClient dials only public addresses, and follows no redirects or proxies.
+func newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialControl}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !store.WebhookAddrAllowed(addr) {
+		return fmt.Errorf("refusing to send a webhook to %s", addr)
+	}
+
+	return nil
+}
+
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	slog.Info("Webhook Deliverer Started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Webhook Deliverer shutting down...")
+			return
+		case <-ticker.C:
+			if _, err := d.deliverDue(ctx); err != nil {
+				slog.Error("Failed to deliver webhooks", "error", err)
+			}
+		}
+	}
+}
+
+// deliverDue sends one batch of due deliveries and returns how many were
+// attempted.
+func (d *Deliverer) deliverDue(ctx context.Context) (int, error) {
+	jobs, err := d.store.ClaimWebhookDeliveries(ctx, d.batch, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		statusCode, sendErr := d.send(ctx, job)
+
+		var retryAt time.Time
+		if sendErr != nil {
+			slog.Error("Webhook delivery failed", "error", sendErr, "delivery_id", job.DeliveryID, "attempt", job.Attempts+1)
+
+			if job.Attempts+1 < d.maxAttempts {
+				retryAt = time.Now().Add(d.backoff(job.Attempts + 1))
+			}
+		}
+
+		if err := d.store.RecordWebhookAttempt(ctx, job.DeliveryID, statusCode, sendErr, retryAt); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(jobs), nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (d *Deliverer) backoff(failures int) time.Duration {
+	wait := d.baseBackoff
+	for i := 1; i < failures && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, d.maxBackoff)
+}
+
+func (d *Deliverer) send(ctx context.Context, job store.WebhookJob) (int, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        job.DeliveryID,
+		Event:     job.Message.Topic,
+		CreatedAt: job.Message.CreatedAt,
+		Data:      job.Message.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", job.WebhookID)
+	req.Header.Set("X-Webhook-Delivery", job.DeliveryID)
+	req.Header.Set("X-Webhook-Event", job.Message.Topic)
+	req.Header.Set(SignatureHeader, Sign(job.Secret, time.Now().Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
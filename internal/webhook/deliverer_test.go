@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/testutils"
+)
+
+func TestDeliverer(t *testing.T) {
+	tx := testutils.SetupTestDB(t)
+	storage := store.NewStorage(tx)
+	ctx := context.Background()
+
+	u, err := storage.CreateUser(ctx, &store.User{Username: "webhook_trader", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 10)
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+	}))
+	defer ok.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	// Webhooks cannot be registered against a local address, so they are
+	// registered against a public one and re-pointed at the test servers.
+	local := func(target string, events []string) *store.Webhook {
+		t.Helper()
+
+		w, err := storage.CreateWebhook(ctx, u.ID, "https://hooks.example.com/trades", events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Exec(ctx, `UPDATE webhooks SET url = $2 WHERE id = $1`, w.ID, target); err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+
+	good := local(ok.URL, []string{store.TopicFills})
+	bad := local(failing.URL, []string{store.TopicFills})
+	cancelsOnly := local(ok.URL, []string{store.TopicCancels})
+
+	bid, err := storage.CreateOrder(ctx, store.Order{UserID: u.ID, Symbol: "WHK-USD", Side: "BUY", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ask, err := storage.CreateOrder(ctx, store.Order{UserID: u.ID, Symbol: "WHK-USD", Side: "SELL", Price: 100, Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.CreateTrade(ctx, 100, 1, bid.ID, ask.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stand in for the outbox dispatcher, fanning out only our user's messages.
+	fanout := NewFanout(storage)
+
+	pending, err := storage.GetPendingOutbox(ctx, fanout.Name(), 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range pending {
+		if msg.UserID != u.ID {
+			continue
+		}
+		if err := fanout.Deliver(ctx, msg); err != nil {
+			t.Fatalf("Fanout failed: %v", err)
+		}
+	}
+
+	d := NewDeliverer(storage, WithRetries(2, time.Minute, time.Hour), WithClient(&http.Client{Timeout: 10 * time.Second}))
+
+	if _, err := d.deliverDue(ctx); err != nil {
+		t.Fatalf("deliverDue failed: %v", err)
+	}
+
+	t.Run("Sends each fill, signed, to subscribed webhooks", func(t *testing.T) {
+		if len(requests) != 2 {
+			t.Fatalf("Expected one request per side of the trade, got %d", len(requests))
+		}
+
+		req := <-requests
+
+		var envelope Envelope
+		if err := json.Unmarshal(req.body, &envelope); err != nil {
+			t.Fatal(err)
+		}
+		if envelope.Event != store.TopicFills || req.header.Get("X-Webhook-Id") != good.ID {
+			t.Errorf("Unexpected delivery: %+v %v", envelope, req.header)
+		}
+
+		signature := req.header.Get(SignatureHeader)
+		ts, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+		unix, _ := strconv.ParseInt(ts, 10, 64)
+
+		if signature != Sign(good.Secret, unix, req.body) {
+			t.Errorf("Signature %s does not verify", signature)
+		}
+	})
+
+	t.Run("Logs successes and schedules retries", func(t *testing.T) {
+		delivered, err := storage.GetWebhookDeliveries(ctx, good.ID, u.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(delivered) != 2 || delivered[0].Status != store.DeliveryDelivered || delivered[0].LastStatusCode != 200 {
+			t.Errorf("Expected 2 delivered entries, got %+v", delivered)
+		}
+
+		retrying, err := storage.GetWebhookDeliveries(ctx, bad.ID, u.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(retrying) != 2 || retrying[0].Status != store.DeliveryPending || retrying[0].Attempts != 1 || retrying[0].LastStatusCode != 500 {
+			t.Errorf("Expected 2 entries awaiting retry, got %+v", retrying)
+		}
+
+		skipped, err := storage.GetWebhookDeliveries(ctx, cancelsOnly.ID, u.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(skipped) != 0 {
+			t.Errorf("Expected no fills for a cancels-only webhook, got %+v", skipped)
+		}
+	})
+
+	t.Run("Fails after the last retry and can be redelivered", func(t *testing.T) {
+		retrying, err := storage.GetWebhookDeliveries(ctx, bad.ID, u.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range retrying {
+			if _, err := storage.RedeliverWebhook(ctx, bad.ID, entry.ID, u.ID); err != nil {
+				t.Fatalf("RedeliverWebhook failed: %v", err)
+			}
+		}
+
+		if _, err := d.deliverDue(ctx); err != nil {
+			t.Fatalf("deliverDue failed: %v", err)
+		}
+
+		failed, err := storage.GetWebhookDeliveries(ctx, bad.ID, u.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range failed {
+			if entry.Status != store.DeliveryFailed || entry.Attempts != 2 {
+				t.Errorf("Expected FAILED after 2 attempts, got %+v", entry)
+			}
+		}
+	})
+}
+
+func TestDefaultClient(t *testing.T) {
+	requests := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer ts.Close()
+
+	t.Run("Refuses to dial a loopback address", func(t *testing.T) {
+		resp, err := newClient().Post(ts.URL, "application/json", strings.NewReader("{}"))
+		if err == nil {
+			resp.Body.Close()
+			t.Fatal("Expected the request to be refused")
+		}
+		if requests != 0 {
+			t.Errorf("Expected nothing to reach the server, got %d requests", requests)
+		}
+	})
+
+	t.Run("Does not follow redirects", func(t *testing.T) {
+		if err := newClient().CheckRedirect(nil, nil); err != http.ErrUseLastResponse {
+			t.Errorf("Expected http.ErrUseLastResponse, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/Nevnet99/trade-engine/internal/store"
+)
+
+// Fanout is the outbox sink that queues a user's events for their webhooks.
+type Fanout struct {
+	store *store.Storage
+}
+
+func NewFanout(s *store.Storage) *Fanout {
+	return &Fanout{store: s}
+}
+
+func (f *Fanout) Name() string {
+	return "user-webhooks"
+}
+
+func (f *Fanout) Deliver(ctx context.Context, msg store.OutboxMessage) error {
+	_, err := f.store.QueueWebhookDeliveries(ctx, msg)
+	return err
+}
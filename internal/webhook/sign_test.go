@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"fills"}`)
+
+	got := Sign("secret", 1700000000, body)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if Sign("other", 1700000000, body) == got {
+		t.Error("Expected a different secret to give a different signature")
+	}
+	if !strings.HasPrefix(Sign("secret", 1700000001, body), "t=1700000001,") {
+		t.Error("Expected the timestamp to be carried in the signature")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDeliverer(nil, WithRetries(6, time.Second, 10*time.Second))
+
+	want := []time.Duration{1, 2, 4, 8, 10, 10}
+
+	for i, w := range want {
+		if got := d.backoff(i + 1); got != w*time.Second {
+			t.Errorf("After %d failures: got %v, want %v", i+1, got, w*time.Second)
+		}
+	}
+}
@@ -7,10 +7,16 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Nevnet99/trade-engine/internal/api"
 	"github.com/Nevnet99/trade-engine/internal/engine"
+	"github.com/Nevnet99/trade-engine/internal/outbox"
 	"github.com/Nevnet99/trade-engine/internal/store"
+	"github.com/Nevnet99/trade-engine/internal/stream"
+	"github.com/Nevnet99/trade-engine/internal/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -40,13 +46,53 @@ func main() {
 	defer pool.Close()
 
 	storage := store.NewStorageFromPool(pool)
-	server := api.NewServer(storage)
 
-	matchingEngine := engine.New(storage)
+	if len(os.Args) > 1 && os.Args[1] == "backfill-candles" {
+		slog.Info("Rebuilding candles from trade history...")
+
+		if err := storage.BackfillCandles(context.Background()); err != nil {
+			log.Fatal("Candle backfill failed: ", err)
+		}
+
+		slog.Info("Candle backfill complete")
+		return
+	}
+
+	if len(os.Args) > 2 && os.Args[1] == "replay" {
+		if err := replayJournal(context.Background(), storage, os.Args[2]); err != nil {
+			log.Fatal("Replay failed: ", err)
+		}
+		return
+	}
+
+	hub := stream.NewHub(256)
+
+	engineOpts := []engine.Option{engine.WithHub(hub)}
+	if n, err := strconv.Atoi(os.Getenv("MATCHING_WORKERS")); err == nil {
+		engineOpts = append(engineOpts, engine.WithWorkers(n))
+	}
+
+	matchingEngine := engine.New(storage, engineOpts...)
+	serverOpts := []api.Option{api.WithHub(hub), api.WithMatcher(matchingEngine)}
+	if origins := os.Getenv("WS_ALLOWED_ORIGINS"); origins != "" {
+		serverOpts = append(serverOpts, api.WithAllowedOrigins(strings.Split(origins, ",")...))
+	}
+
+	server := api.NewServer(storage, serverOpts...)
 
 	slog.Info("Starting Matching Engine...")
 	go matchingEngine.ProcessMatches(context.Background())
 
+	sinks := []outbox.Sink{webhook.NewFanout(storage)}
+	if url := os.Getenv("OUTBOX_WEBHOOK_URL"); url != "" {
+		sinks = append(sinks, outbox.NewWebhookSink("webhook", url))
+	}
+
+	// Sinks added to a running exchange only get what happens from then on.
+	go outbox.NewDispatcher(storage, sinks, outbox.WithStart(store.OutboxStartLatest)).Run(context.Background())
+	go webhook.NewDeliverer(storage).Run(context.Background())
+	go server.PruneIdempotencyKeys(context.Background(), time.Hour)
+
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 
@@ -56,6 +102,9 @@ func main() {
 	r.Get("/orderbook", server.HandleGetOrderBook)
 	r.Get("/trades", server.HandleGetRecentTrades)
 	r.Get("/kline", server.HandleGetKlines)
+	r.Get("/ticker", server.HandleGetTicker)
+	r.Get("/ws", server.HandleWebSocket)
+	r.Get("/stream/trades", server.HandleStreamTrades)
 
 	// Authentication
 
@@ -66,10 +115,88 @@ func main() {
 
 	r.Group(func(r chi.Router) {
 		r.Use(server.AuthMiddleware)
+		r.Use(server.IdempotencyMiddleware)
 
 		r.Post("/trade", server.CreateOrder)
+		r.Post("/orders/batch", server.HandleCreateOrders)
+		r.Delete("/orders/batch", server.HandleCancelOrders)
+		r.Patch("/orders/{id}", server.HandleAmendOrder)
+		r.Delete("/orders/{id}", server.HandleCancelOrder)
+		r.Get("/orders/client/{clientOrderID}", server.HandleGetOrderByClientID)
+		r.Delete("/orders/client/{clientOrderID}", server.HandleCancelOrderByClientID)
+		r.Post("/order-groups", server.HandleCreateOrderGroup)
+		r.Get("/order-groups/{id}", server.HandleGetOrderGroup)
+		r.Delete("/order-groups/{id}", server.HandleCancelOrderGroup)
+		r.Get("/fills", server.HandleGetFills)
+		r.Put("/account/stp", server.HandleSetSTPMode)
+		r.Get("/stream/orders", server.HandleStreamOrders)
+
+		r.Post("/webhooks", server.HandleCreateWebhook)
+		r.Get("/webhooks", server.HandleGetWebhooks)
+		r.Delete("/webhooks/{id}", server.HandleDeleteWebhook)
+		r.Get("/webhooks/{id}/deliveries", server.HandleGetWebhookDeliveries)
+		r.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", server.HandleRedeliverWebhook)
+	})
+
+	// Admin
+
+	r.Group(func(r chi.Router) {
+		r.Use(server.AuthMiddleware)
+		r.Use(server.AdminMiddleware)
+
+		r.Put("/admin/pairs/{symbol}/protection", server.HandleSetPairProtection)
+		r.Put("/admin/pairs/{symbol}/auction", server.HandleStartAuction)
+		r.Delete("/admin/pairs/{symbol}/auction", server.HandleEndAuction)
 	})
 
 	slog.Info("Starting server on :8080")
 	http.ListenAndServe(":8080", r)
 }
+
+// replayJournal re-executes this database's engine journal into targetDB on the same server.
+func replayJournal(ctx context.Context, source *store.Storage, targetDB string) error {
+	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
+		os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), targetDB,
+	)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	if err != nil {
+		return err
+	}
+
+	defer pool.Close()
+
+	replayer := engine.New(store.NewStorageFromPool(pool))
+
+	symbols, err := source.GetJournalSymbols(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Books are independent, and Seq is only commit order within one, so
+	// each symbol is replayed on its own.
+	for _, symbol := range symbols {
+		var seq int64
+		for {
+			events, err := source.GetJournal(ctx, store.JournalQuery{Symbol: symbol, AfterSeq: seq, Limit: 1000})
+			if err != nil {
+				return err
+			}
+
+			if len(events) == 0 {
+				break
+			}
+
+			if err := replayer.Replay(ctx, events); err != nil {
+				return err
+			}
+
+			seq = events[len(events)-1].Seq
+			slog.Info("Replayed journal", "symbol", symbol, "through_seq", seq)
+		}
+	}
+
+	slog.Info("Replay complete", "symbols", len(symbols))
+	return nil
+}
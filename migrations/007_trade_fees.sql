@@ -0,0 +1,8 @@
+ALTER TABLE trades
+ADD COLUMN taker_side VARCHAR(4),
+ADD COLUMN maker_fee DECIMAL(20, 8) NOT NULL DEFAULT 0,
+ADD COLUMN taker_fee DECIMAL(20, 8) NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders (user_id);
+CREATE INDEX IF NOT EXISTS idx_trades_bid_order_id ON trades (bid_order_id);
+CREATE INDEX IF NOT EXISTS idx_trades_ask_order_id ON trades (ask_order_id);
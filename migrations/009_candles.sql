@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS candles (
+    symbol TEXT NOT NULL,
+    period VARCHAR(3) NOT NULL,
+    bucket TIMESTAMPTZ NOT NULL,
+    open DECIMAL(12, 2) NOT NULL,
+    high DECIMAL(12, 2) NOT NULL,
+    low DECIMAL(12, 2) NOT NULL,
+    close DECIMAL(12, 2) NOT NULL,
+    volume BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (symbol, period, bucket)
+);
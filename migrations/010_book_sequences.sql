@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS book_sequences (
+    symbol TEXT PRIMARY KEY,
+    last_update_id BIGINT NOT NULL DEFAULT 0
+);
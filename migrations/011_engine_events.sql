@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS engine_events (
+    seq BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_engine_events_symbol_seq ON engine_events (symbol, seq);
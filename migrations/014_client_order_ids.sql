@@ -0,0 +1,17 @@
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS client_order_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS orders_user_client_order_id
+    ON orders (user_id, client_order_id)
+    WHERE client_order_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INT,
+    content_type TEXT,
+    response BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, key)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS stp_mode TEXT NOT NULL DEFAULT 'CANCEL_NEWEST';
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS stp_mode TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS display_quantity INT,
+ADD COLUMN IF NOT EXISTS visible_quantity INT;
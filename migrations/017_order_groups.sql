@@ -0,0 +1,20 @@
+ALTER TABLE book_sequences
+ADD COLUMN IF NOT EXISTS last_price DECIMAL(12, 2);
+
+CREATE TABLE IF NOT EXISTS order_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    symbol TEXT NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS order_type VARCHAR(20) NOT NULL DEFAULT 'LIMIT',
+ADD COLUMN IF NOT EXISTS stop_price DECIMAL(12, 2),
+ADD COLUMN IF NOT EXISTS group_id UUID REFERENCES order_groups(id),
+ADD COLUMN IF NOT EXISTS group_role VARCHAR(20);
+
+CREATE INDEX IF NOT EXISTS idx_orders_waiting ON orders (symbol, created_at) WHERE status = 'WAITING';
+CREATE INDEX IF NOT EXISTS idx_orders_group_id ON orders (group_id) WHERE group_id IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS trailing_offset DECIMAL(12, 2),
+ADD COLUMN IF NOT EXISTS trailing_percent DECIMAL(5, 2),
+ADD COLUMN IF NOT EXISTS trailing_watermark DECIMAL(12, 2);
@@ -0,0 +1,10 @@
+ALTER TABLE trading_pairs
+ADD COLUMN IF NOT EXISTS price_band_percent DECIMAL(5, 2),
+ADD COLUMN IF NOT EXISTS reference_price DECIMAL(12, 2),
+ADD COLUMN IF NOT EXISTS circuit_breaker_percent DECIMAL(5, 2),
+ADD COLUMN IF NOT EXISTS circuit_breaker_window_seconds INT,
+ADD COLUMN IF NOT EXISTS circuit_breaker_halt_seconds INT,
+ADD COLUMN IF NOT EXISTS halted_until TIMESTAMP;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+ALTER TABLE trading_pairs
+ADD COLUMN IF NOT EXISTS trading_phase TEXT NOT NULL DEFAULT 'CONTINUOUS',
+ADD COLUMN IF NOT EXISTS auction_price DECIMAL(12, 2);
+
+ALTER TABLE trading_pairs
+ALTER COLUMN trading_phase SET DEFAULT 'AUCTION';
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox_sinks (
+    sink TEXT PRIMARY KEY,
+    settled_through BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO outbox_sinks (sink, settled_through)
+SELECT sink, COALESCE(MIN(message_id) FILTER (WHERE delivered_at IS NULL) - 1, MAX(message_id))
+FROM outbox_deliveries
+GROUP BY sink
+ON CONFLICT (sink) DO NOTHING;
+
+ALTER TABLE outbox_deliveries
+ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_created_at ON outbox (created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_message_id ON webhook_deliveries (message_id);
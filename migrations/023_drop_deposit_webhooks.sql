@@ -0,0 +1,3 @@
+UPDATE webhooks
+SET events = array_remove(events, 'deposits')
+WHERE 'deposits' = ANY(events);
@@ -0,0 +1,6 @@
+ALTER TABLE trading_pairs
+ALTER COLUMN halted_until TYPE TIMESTAMPTZ USING halted_until AT TIME ZONE 'UTC',
+ADD COLUMN IF NOT EXISTS opening_auction_seconds INT;
+
+ALTER TABLE trading_pairs
+ALTER COLUMN opening_auction_seconds SET DEFAULT 300;
@@ -0,0 +1,8 @@
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS priority_at TIMESTAMP;
+
+UPDATE orders SET priority_at = created_at WHERE priority_at IS NULL;
+
+ALTER TABLE orders
+ALTER COLUMN priority_at SET NOT NULL,
+ALTER COLUMN priority_at SET DEFAULT NOW();